@@ -2,6 +2,7 @@ package api
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
@@ -32,6 +33,14 @@ const EnvVaultTLSServerName = "VAULT_TLS_SERVER_NAME"
 const EnvVaultWrapTTL = "VAULT_WRAP_TTL"
 const EnvVaultMaxRetries = "VAULT_MAX_RETRIES"
 const EnvVaultToken = "VAULT_TOKEN"
+const EnvVaultProxyAddr = "VAULT_PROXY_ADDR"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
+const EnvVaultUserAgent = "VAULT_USER_AGENT"
+const EnvVaultTLSMinVersion = "VAULT_TLS_MIN_VERSION"
+const EnvVaultTLSCiphers = "VAULT_TLS_CIPHERS"
+const EnvVaultCACertPEM = "VAULT_CACERT_PEM"
+const EnvVaultClientPKCS12 = "VAULT_CLIENT_PKCS12"
+const EnvVaultClientPKCS12Password = "VAULT_CLIENT_PKCS12_PASSWORD"
 
 // WrappingLookupFunc is a function that, given an HTTP verb and a path,
 // returns an optional string duration to be used for response wrapping (e.g.
@@ -40,6 +49,9 @@ const EnvVaultToken = "VAULT_TOKEN"
 // called path precisely.
 type WrappingLookupFunc func(operation, path string) string
 
+// lookupSRV is used to look up SRV records; it is replaced in tests.
+var lookupSRV = net.LookupSRV
+
 // Config is used to configure the creation of the client.
 type Config struct {
 	// Address is the address of the Vault server. This should be a complete
@@ -55,11 +67,74 @@ type Config struct {
 	redirectSetup sync.Once
 
 	// MaxRetries controls the maximum number of times to retry when a 5xx error
-	// occurs. Set to 0 or less to disable retrying. Defaults to 0.
+	// occurs. Set to 0 or less to disable retrying. Defaults to 0. Requests
+	// are retried whatever their method, including writes, unless
+	// RetryIdempotentOnly is set.
 	MaxRetries int
 
+	// RetryIdempotentOnly limits retries to GET, HEAD and LIST requests,
+	// which can safely be repeated. Writes are then sent exactly once, so a
+	// write that reached the server before failing, such as during a leader
+	// election, is never applied twice.
+	RetryIdempotentOnly bool
+
+	// RetryWaitMin, if set, is how long to wait before the first retry of a
+	// request, doubling for each retry after it up to RetryWaitMax, if set.
+	// By default the wait grows linearly, by about a second per retry.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+
 	// Timeout is for setting custom timeout parameter in the HttpClient
 	Timeout time.Duration
+
+	// RetryBudget, if set, caps the total number of retries performed across
+	// every request made by clients sharing this configuration, in addition
+	// to the per-request limit set by MaxRetries.
+	RetryBudget *RetryBudget
+
+	// RetryStatuses are the status codes, in addition to 5xx errors, that a
+	// request is retried on, within the limits set by MaxRetries and
+	// RetryBudget.
+	RetryStatuses []int
+
+	// DisableSRVLookup disables looking up the SRV record of the host of the
+	// address when it does not include a port.
+	DisableSRVLookup bool
+}
+
+// RetryBudget is a counter of retries that can be shared by many requests.
+// Once the budget has been exhausted, failing requests are no longer retried.
+// It is safe for concurrent use.
+type RetryBudget struct {
+	l         sync.Mutex
+	remaining int
+}
+
+// NewRetryBudget returns a RetryBudget allowing at most n retries in total.
+func NewRetryBudget(n int) *RetryBudget {
+	if n < 0 {
+		n = 0
+	}
+	return &RetryBudget{remaining: n}
+}
+
+// Remaining returns the number of retries left in the budget.
+func (b *RetryBudget) Remaining() int {
+	b.l.Lock()
+	defer b.l.Unlock()
+	return b.remaining
+}
+
+// take removes a single retry from the budget, reporting whether there was
+// one left.
+func (b *RetryBudget) take() bool {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
 }
 
 // TLSConfig contains the parameters needed to configure TLS on the HTTP client
@@ -73,6 +148,11 @@ type TLSConfig struct {
 	// the Vault server SSL certificate.
 	CAPath string
 
+	// CACertBytes is a PEM-encoded certificate or bundle used to verify the
+	// Vault server SSL certificate. It takes precedence over CACert and
+	// CAPath.
+	CACertBytes []byte
+
 	// ClientCert is the path to the certificate for Vault communication
 	ClientCert string
 
@@ -133,12 +213,20 @@ func (c *Config) ConfigureTLS(t *TLSConfig) error {
 	}
 
 	clientTLSConfig := c.HttpClient.Transport.(*http.Transport).TLSClientConfig
-	rootConfig := &rootcerts.Config{
-		CAFile: t.CACert,
-		CAPath: t.CAPath,
-	}
-	if err := rootcerts.ConfigureTLS(clientTLSConfig, rootConfig); err != nil {
-		return err
+	if len(t.CACertBytes) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(t.CACertBytes) {
+			return fmt.Errorf("Error loading CA certificate: no valid PEM certificates found")
+		}
+		clientTLSConfig.RootCAs = pool
+	} else {
+		rootConfig := &rootcerts.Config{
+			CAFile: t.CACert,
+			CAPath: t.CAPath,
+		}
+		if err := rootcerts.ConfigureTLS(clientTLSConfig, rootConfig); err != nil {
+			return err
+		}
 	}
 
 	clientTLSConfig.InsecureSkipVerify = t.Insecure
@@ -360,6 +448,37 @@ func (c *Client) SetHeaders(headers http.Header) {
 	c.headers = headers
 }
 
+// SetNamespace sets the namespace that future requests are made in, which is
+// sent in the X-Vault-Namespace header. An empty namespace removes the
+// header.
+func (c *Client) SetNamespace(namespace string) {
+	headers := c.copyHeaders()
+	if namespace == "" {
+		headers.Del("X-Vault-Namespace")
+	} else {
+		headers.Set("X-Vault-Namespace", namespace)
+	}
+	c.headers = headers
+}
+
+// AddHeader adds the value of the header to future requests, after any
+// values the header already has.
+func (c *Client) AddHeader(key, value string) {
+	headers := c.copyHeaders()
+	headers.Add(key, value)
+	c.headers = headers
+}
+
+// copyHeaders returns a copy of the headers, which may be shared with the
+// caller of SetHeaders and so are not changed in place.
+func (c *Client) copyHeaders() http.Header {
+	headers := make(http.Header, len(c.headers)+1)
+	for k, v := range c.headers {
+		headers[k] = append([]string(nil), v...)
+	}
+	return headers
+}
+
 // Clone creates a copy of this client.
 func (c *Client) Clone() (*Client, error) {
 	return NewClient(c.config)
@@ -372,9 +491,9 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	// if SRV records exist (see https://tools.ietf.org/html/draft-andrews-http-srv-02), lookup the SRV
 	// record and take the highest match; this is not designed for high-availability, just discovery
 	var host string = c.addr.Host
-	if c.addr.Port() == "" {
+	if c.addr.Port() == "" && !c.config.DisableSRVLookup {
 		// Internet Draft specifies that the SRV record is ignored if a port is given
-		_, addrs, err := net.LookupSRV("http", "tcp", c.addr.Hostname())
+		_, addrs, err := lookupSRV("http", "tcp", c.addr.Hostname())
 		if err == nil && len(addrs) > 0 {
 			host = fmt.Sprintf("%s:%d", addrs[0].Target, addrs[0].Port)
 		}
@@ -416,26 +535,95 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	return req
 }
 
+// backoff returns how long to wait before the given retry of a request.
+func (c *Client) backoff(retry int) time.Duration {
+	min, max := c.config.RetryWaitMin, c.config.RetryWaitMax
+	if min <= 0 {
+		return pester.LinearJitterBackoff(retry)
+	}
+
+	wait := min
+	for i := 1; i < retry && (max <= 0 || wait < max); i++ {
+		wait *= 2
+	}
+	if max > 0 && wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// retryStatus reports whether the status code is one of the RetryStatuses.
+func (c *Client) retryStatus(code int) bool {
+	for _, s := range c.config.RetryStatuses {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotent reports whether a request with the method can safely be
+// repeated.
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "LIST":
+		return true
+	}
+	return false
+}
+
 // RawRequest performs the raw request given. This request may be against
 // a Vault server not configured with this client. This is an advanced operation
 // that generally won't need to be called externally.
 func (c *Client) RawRequest(r *Request) (*Response, error) {
 	redirectCount := 0
+	attempts := 0
 START:
 	req, err := r.ToHTTP()
 	if err != nil {
 		return nil, err
 	}
 
+	maxAttempts := c.config.MaxRetries
+	if c.config.RetryIdempotentOnly && !idempotent(r.Method) {
+		maxAttempts = 1
+	}
+
 	client := pester.NewExtendedClient(c.config.HttpClient)
-	client.Backoff = pester.LinearJitterBackoff
-	client.MaxRetries = c.config.MaxRetries
+	client.Backoff = c.backoff
+	client.MaxRetries = maxAttempts
+
+	budget := c.config.RetryBudget
+	if budget != nil {
+		// Each retry is taken from the budget right before it is made, so
+		// that requests sharing the budget cannot exceed it together. The
+		// client cannot consult the budget, so the retries are made here.
+		client.MaxRetries = 1
+	}
 
 	var result *Response
 	resp, err := client.Do(req)
 	if resp != nil {
 		result = &Response{Response: resp}
 	}
+
+	// The client only retries 5xx errors itself, so the additional
+	// RetryStatuses are retried here, as are all retries with a budget
+	attempts++
+	retry := err == nil && resp.StatusCode < 500 && c.retryStatus(resp.StatusCode)
+	if budget != nil {
+		retry = retry || err != nil || resp.StatusCode >= 500
+	}
+	if retry && attempts < maxAttempts && (budget == nil || budget.take()) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		time.Sleep(c.backoff(attempts))
+		if err := r.ResetJSONBody(); err != nil {
+			return nil, err
+		}
+		goto START
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "tls: oversized") {
 			err = fmt.Errorf(
@@ -3,8 +3,11 @@ package api
 import (
 	"bytes"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -95,6 +98,65 @@ func TestClientToken(t *testing.T) {
 	}
 }
 
+func TestClientSetNamespace(t *testing.T) {
+	var namespaces []string
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		namespaces = append(namespaces, req.Header.Get("X-Vault-Namespace"))
+	}
+
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, ns := range []string{"", "team-a/", ""} {
+		client.SetNamespace(ns)
+		resp, err := client.RawRequest(client.NewRequest("GET", "/"))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		resp.Body.Close()
+	}
+	if !reflect.DeepEqual(namespaces, []string{"", "team-a/", ""}) {
+		t.Fatalf("bad: %v", namespaces)
+	}
+}
+
+func TestClientAddHeader(t *testing.T) {
+	var headers []http.Header
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		headers = append(headers, req.Header)
+	}
+
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	shared := http.Header{"X-Trace": []string{"a"}}
+	client.SetHeaders(shared)
+	client.AddHeader("X-Trace", "b")
+	client.AddHeader("X-Request-Id", "1")
+	resp, err := client.RawRequest(client.NewRequest("GET", "/"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+
+	if !reflect.DeepEqual(headers[0]["X-Trace"], []string{"a", "b"}) || headers[0].Get("X-Request-Id") != "1" {
+		t.Fatalf("bad: %v", headers[0])
+	}
+	if !reflect.DeepEqual(shared, http.Header{"X-Trace": []string{"a"}}) {
+		t.Fatalf("headers passed to SetHeaders were changed: %v", shared)
+	}
+}
+
 func TestClientRedirect(t *testing.T) {
 	primary := func(w http.ResponseWriter, req *http.Request) {
 		w.Write([]byte("test"))
@@ -213,3 +275,225 @@ func TestClientNonTransportRoundTripper(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClientRetryBudget(t *testing.T) {
+	var l sync.Mutex
+	var attempts int
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		l.Lock()
+		attempts++
+		l.Unlock()
+		w.WriteHeader(500)
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	config.MaxRetries = 3
+	config.RetryBudget = NewRetryBudget(2)
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.RawRequest(client.NewRequest("GET", "/")); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	// One attempt per request plus the two retries allowed by the budget
+	if attempts != 5 {
+		t.Fatalf("bad: expected 5 attempts, got %d", attempts)
+	}
+	if remaining := config.RetryBudget.Remaining(); remaining != 0 {
+		t.Fatalf("bad: expected an exhausted budget, got %d", remaining)
+	}
+}
+
+func TestClientRetryBudget_concurrent(t *testing.T) {
+	var l sync.Mutex
+	var attempts int
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		l.Lock()
+		attempts++
+		l.Unlock()
+		w.WriteHeader(500)
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	config.MaxRetries = 5
+	config.RetryWaitMin = 10 * time.Millisecond
+	config.RetryBudget = NewRetryBudget(3)
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.RawRequest(client.NewRequest("GET", "/"))
+		}()
+	}
+	wg.Wait()
+
+	// However the requests interleave, together they make no more retries
+	// than the budget allows
+	if attempts != 13 {
+		t.Fatalf("bad: expected 13 attempts, got %d", attempts)
+	}
+	if remaining := config.RetryBudget.Remaining(); remaining != 0 {
+		t.Fatalf("bad: expected an exhausted budget, got %d", remaining)
+	}
+}
+
+func TestClientRetryStatuses(t *testing.T) {
+	var l sync.Mutex
+	attempts := make(map[string]int)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		l.Lock()
+		attempts[req.URL.Path]++
+		l.Unlock()
+		switch req.URL.Path {
+		case "/v1/listed":
+			w.WriteHeader(520)
+		default:
+			w.WriteHeader(404)
+		}
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	config.MaxRetries = 2
+	config.RetryStatuses = []int{408, 520}
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, path := range []string{"/v1/listed", "/v1/unlisted"} {
+		if _, err := client.RawRequest(client.NewRequest("GET", path)); err == nil {
+			t.Fatalf("%s: expected error", path)
+		}
+	}
+
+	if attempts["/v1/listed"] != 2 {
+		t.Fatalf("bad: expected 2 attempts of a listed status, got %d", attempts["/v1/listed"])
+	}
+	if attempts["/v1/unlisted"] != 1 {
+		t.Fatalf("bad: expected 1 attempt of an unlisted status, got %d", attempts["/v1/unlisted"])
+	}
+}
+
+func TestClientRetryMethods(t *testing.T) {
+	var l sync.Mutex
+	attempts := make(map[string]int)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		l.Lock()
+		attempts[req.Method]++
+		l.Unlock()
+		w.WriteHeader(500)
+	}
+
+	for _, idempotentOnly := range []bool{false, true} {
+		l.Lock()
+		attempts = make(map[string]int)
+		l.Unlock()
+
+		config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+		defer ln.Close()
+		config.MaxRetries = 3
+		config.RetryWaitMin = 10 * time.Millisecond
+		config.RetryIdempotentOnly = idempotentOnly
+
+		client, err := NewClient(config)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		for _, method := range []string{"GET", "LIST", "PUT", "POST"} {
+			if _, err := client.RawRequest(client.NewRequest(method, "/")); err == nil {
+				t.Fatalf("%s: expected error", method)
+			}
+		}
+
+		// By default writes are retried like reads; with
+		// RetryIdempotentOnly they are sent exactly once
+		writes := 3
+		if idempotentOnly {
+			writes = 1
+		}
+		expected := map[string]int{"GET": 3, "LIST": 3, "PUT": writes, "POST": writes}
+		l.Lock()
+		if !reflect.DeepEqual(attempts, expected) {
+			t.Fatalf("%t: bad: %v", idempotentOnly, attempts)
+		}
+		l.Unlock()
+	}
+}
+
+func TestClientBackoff(t *testing.T) {
+	client, err := NewClient(&Config{
+		RetryWaitMin: 100 * time.Millisecond,
+		RetryWaitMax: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, wait := range expected {
+		if actual := client.backoff(i + 1); actual != wait {
+			t.Fatalf("retry %d: expected %s, got %s", i+1, wait, actual)
+		}
+	}
+}
+
+func TestClientDisableSRVLookup(t *testing.T) {
+	defer func(f func(string, string, string) (string, []*net.SRV, error)) {
+		lookupSRV = f
+	}(lookupSRV)
+
+	lookups := 0
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		lookups++
+		return "", []*net.SRV{{Target: "srv.example.com", Port: 8300}}, nil
+	}
+
+	config := DefaultConfig()
+	config.Address = "https://vault.example.com"
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := client.NewRequest("GET", "/").URL.Host; host != "srv.example.com:8300" {
+		t.Fatalf("bad: %s", host)
+	}
+
+	config = DefaultConfig()
+	config.Address = "https://vault.example.com"
+	config.DisableSRVLookup = true
+	client, err = NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := client.NewRequest("GET", "/").URL.Host; host != "vault.example.com" {
+		t.Fatalf("bad: %s", host)
+	}
+	if lookups != 1 {
+		t.Fatalf("bad: expected 1 lookup, got %d", lookups)
+	}
+}
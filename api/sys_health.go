@@ -22,6 +22,7 @@ type HealthResponse struct {
 	Initialized   bool   `json:"initialized"`
 	Sealed        bool   `json:"sealed"`
 	Standby       bool   `json:"standby"`
+	PerfStandby   bool   `json:"performance_standby"`
 	ServerTimeUTC int64  `json:"server_time_utc"`
 	Version       string `json:"version"`
 	ClusterName   string `json:"cluster_name,omitempty"`
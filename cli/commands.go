@@ -65,7 +65,9 @@ import (
 func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 	if metaPtr == nil {
 		metaPtr = &meta.Meta{
-			TokenHelper: command.DefaultTokenHelper,
+			TokenHelper:      command.DefaultTokenHelper,
+			AllowedAddresses: command.DefaultAllowedAddresses,
+			AutoLogins:       command.DefaultAutoLogins(),
 		}
 	}
 
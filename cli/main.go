@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/hashicorp/vault/meta"
 	"github.com/mitchellh/cli"
 )
 
@@ -41,9 +42,11 @@ func RunCustom(args []string, commands map[string]cli.CommandFactory) int {
 		Name:         "vault",
 		Autocomplete: true,
 		HelpFunc:     cli.FilteredHelpFunc(commandsInclude, HelpFunc),
+		HelpWriter:   meta.HelpWriter(os.Stderr, args),
 	}
 
 	exitCode, err := cli.Run()
+	meta.ExportTraces("vault "+cli.Subcommand(), exitCode)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing CLI: %s\n", err.Error())
 		return 1
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// timeNow returns the current time; it can be overwritten for tests.
+var timeNow = time.Now
+
+// secretAge is how long ago a version of a secret was created. It is
+// rendered human-readably in table output, such as "3d4h", and as a number
+// of seconds in json and yaml output.
+type secretAge time.Duration
+
+func (a secretAge) String() string {
+	d := time.Duration(a)
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd%dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	}
+	return d.Truncate(time.Second).String()
+}
+
+func (a secretAge) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(time.Duration(a)/time.Second), 10)), nil
+}
+
+// addSecretAge returns the secret with an "age" field added alongside its
+// data and metadata, computed from the creation time in the metadata, if
+// -show-age is set. Only versioned secrets have a creation time; for others
+// a warning is printed and the secret is returned as is. The secret itself
+// is not modified.
+func addSecretAge(ui cli.Ui, secret *api.Secret, opts *meta.OutputOptions) *api.Secret {
+	if secret == nil || opts == nil || !opts.ShowAge {
+		return secret
+	}
+
+	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+	created, _ := metadata["created_time"].(string)
+	if created == "" {
+		ui.Error("Warning: the secret is not versioned, so its age is not known")
+		return secret
+	}
+	t, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Warning: error parsing the creation time of the secret: %s", err))
+		return secret
+	}
+
+	age := timeNow().Sub(t)
+	if age < 0 {
+		age = 0
+	}
+
+	s := *secret
+	s.Data = make(map[string]interface{}, len(secret.Data)+1)
+	for k, v := range secret.Data {
+		s.Data[k] = v
+	}
+	s.Data["age"] = secretAge(age)
+	return &s
+}
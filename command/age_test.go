@@ -0,0 +1,78 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestOutputSecret_showAge(t *testing.T) {
+	oldNow := timeNow
+	timeNow = func() time.Time { return time.Date(2018, 1, 4, 14, 30, 0, 0, time.UTC) }
+	defer func() { timeNow = oldNow }()
+
+	versioned := func(created string) *api.Secret {
+		return &api.Secret{
+			Data: map[string]interface{}{
+				"data":     map[string]interface{}{"password": "hunter2"},
+				"metadata": map[string]interface{}{"created_time": created, "version": 3},
+			},
+		}
+	}
+	opts := &meta.OutputOptions{ShowAge: true}
+
+	cases := []struct {
+		Created string
+		Table   string
+		JSON    string
+	}{
+		{"2018-01-01T12:00:00.123456Z", `age\s+3d2h\n`, `"age":268199`},
+		{"2018-01-04T12:15:30Z", `age\s+2h14m30s\n`, `"age":8070`},
+		// Clock skew does not give a negative age
+		{"2018-01-04T15:00:00Z", `age\s+0s\n`, `"age":0`},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		if code := OutputSecret(ui, "table", versioned(tc.Created), opts); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Created, code, ui.ErrorWriter.String())
+		}
+		if out := ui.OutputWriter.String(); !regexpMatch(t, tc.Table, out) {
+			t.Fatalf("%s: bad: %s", tc.Created, out)
+		}
+
+		ui = new(cli.MockUi)
+		if code := OutputSecret(ui, "json", versioned(tc.Created), opts); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Created, code, ui.ErrorWriter.String())
+		}
+		if out := ui.OutputWriter.String(); !strings.Contains(out, tc.JSON) {
+			t.Fatalf("%s: bad: %s", tc.Created, out)
+		}
+	}
+
+	// Secrets that are not versioned get a warning and no age
+	ui := new(cli.MockUi)
+	s := &api.Secret{Data: map[string]interface{}{"password": "hunter2"}}
+	if code := OutputSecret(ui, "table", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); strings.Contains(out, "age") {
+		t.Fatalf("bad: %s", out)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Warning: the secret is not versioned") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+
+	// Without the flag nothing changes
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", versioned("2018-01-01T12:00:00Z"), nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); strings.Contains(out, "age") {
+		t.Fatalf("bad: %s", out)
+	}
+}
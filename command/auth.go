@@ -203,7 +203,7 @@ CHECK_TOKEN:
 			return 0
 		}
 		if noStore {
-			return OutputSecret(c.Ui, "table", secret)
+			return OutputSecret(c.Ui, "table", secret, nil)
 		}
 		client.SetToken(secret.WrapInfo.Token)
 		secret, err = client.Logical().Unwrap("")
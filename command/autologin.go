@@ -0,0 +1,120 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
+	credAws "github.com/hashicorp/vault/builtin/credential/aws"
+	"github.com/hashicorp/vault/meta"
+)
+
+// DefaultAutoLogins returns the login methods of -auth, which log in with
+// the credentials of the machine the command runs on.
+func DefaultAutoLogins() map[string]meta.AutoLoginFunc {
+	return map[string]meta.AutoLoginFunc{
+		"aws":        awsAutoLogin,
+		"gcp":        gcpAutoLogin,
+		"kubernetes": kubernetesAutoLogin,
+	}
+}
+
+// These can be overwritten for tests.
+var (
+	gcpMetadataEndpoint = "http://metadata.google.internal"
+	kubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// awsAutoLogin logs in to the aws backend with the IAM credentials found in
+// the environment, the shared credentials file, or the instance profile,
+// like "vault auth -method=aws".
+func awsAutoLogin(client *api.Client, config map[string]string) (*api.Secret, error) {
+	handler := &credAws.CLIHandler{}
+	return handler.Auth(client, config)
+}
+
+// gcpAutoLogin logs in to the gcp backend with an identity token of the
+// instance's service account, which is signed by Google for the role.
+func gcpAutoLogin(client *api.Client, config map[string]string) (*api.Secret, error) {
+	role := config["role"]
+	if role == "" {
+		return nil, errors.New("missing role, set it with -auth-config=role=name")
+	}
+	account := config["service_account"]
+	if account == "" {
+		account = "default"
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf(
+		"%s/computeMetadata/v1/instance/service-accounts/%s/identity?audience=%s&format=full",
+		gcpMetadataEndpoint, url.PathEscape(account), url.QueryEscape("vault/"+role)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	httpClient := cleanhttp.DefaultClient()
+	httpClient.Timeout = 5 * time.Second
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting an identity token from the metadata server: %s", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"error getting an identity token from the metadata server: unexpected status %d", resp.StatusCode)
+	}
+
+	return jwtLogin(client, config, "gcp", role, strings.TrimSpace(string(body)))
+}
+
+// kubernetesAutoLogin logs in to the kubernetes backend with the token of
+// the pod's service account.
+func kubernetesAutoLogin(client *api.Client, config map[string]string) (*api.Secret, error) {
+	role := config["role"]
+	if role == "" {
+		return nil, errors.New("missing role, set it with -auth-config=role=name")
+	}
+	path := config["token_path"]
+	if path == "" {
+		path = kubernetesTokenPath
+	}
+
+	jwt, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the service account token: %s", err)
+	}
+
+	return jwtLogin(client, config, "kubernetes", role, strings.TrimSpace(string(jwt)))
+}
+
+// jwtLogin logs in to the backend mounted at the "mount" setting, or else at
+// the name of the method, with the role and the JWT.
+func jwtLogin(client *api.Client, config map[string]string, method, role, jwt string) (*api.Secret, error) {
+	mount := config["mount"]
+	if mount == "" {
+		mount = method
+	}
+
+	secret, err := client.Logical().Write(fmt.Sprintf("auth/%s/login", mount), map[string]interface{}{
+		"role": role,
+		"jwt":  jwt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, errors.New("empty response from credential provider")
+	}
+	return secret, nil
+}
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// testLoginServer returns a server that records the path and the body of
+// the logins made to it and responds with a token.
+func testLoginServer(t *testing.T, logins map[string]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		logins[r.URL.Path] = body
+		w.Write([]byte(`{"auth":{"client_token":"login-token"}}`))
+	}))
+}
+
+func TestGCPAutoLogin(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" ||
+			r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/identity" ||
+			r.URL.Query().Get("audience") != "vault/web" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("gcp-jwt\n"))
+	}))
+	defer metadata.Close()
+	defer func(orig string) { gcpMetadataEndpoint = orig }(gcpMetadataEndpoint)
+	gcpMetadataEndpoint = metadata.URL
+
+	logins := make(map[string]map[string]interface{})
+	server := testLoginServer(t, logins)
+	defer server.Close()
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := gcpAutoLogin(client, map[string]string{"role": "web", "mount": "gce"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	login := logins["/v1/auth/gce/login"]
+	if secret.Auth.ClientToken != "login-token" || login["role"] != "web" || login["jwt"] != "gcp-jwt" {
+		t.Fatalf("bad: %#v %v", secret.Auth, logins)
+	}
+
+	if _, err := gcpAutoLogin(client, map[string]string{}); err == nil || !strings.Contains(err.Error(), "missing role") {
+		t.Fatalf("expected error, got %v", err)
+	}
+	_, err = gcpAutoLogin(client, map[string]string{"role": "web", "service_account": "other"})
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 404") {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
+
+func TestKubernetesAutoLogin(t *testing.T) {
+	f, err := ioutil.TempFile("", "vault-k8s-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("k8s-jwt\n")
+	f.Close()
+	defer func(orig string) { kubernetesTokenPath = orig }(kubernetesTokenPath)
+	kubernetesTokenPath = f.Name()
+
+	logins := make(map[string]map[string]interface{})
+	server := testLoginServer(t, logins)
+	defer server.Close()
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := kubernetesAutoLogin(client, map[string]string{"role": "web"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	login := logins["/v1/auth/kubernetes/login"]
+	if secret.Auth.ClientToken != "login-token" || login["role"] != "web" || login["jwt"] != "k8s-jwt" {
+		t.Fatalf("bad: %#v %v", secret.Auth, logins)
+	}
+
+	_, err = kubernetesAutoLogin(client, map[string]string{"role": "web", "token_path": "/nonexistent"})
+	if err == nil || !strings.Contains(err.Error(), "error reading the service account token") {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+)
+
+// avroSchema is a parsed Avro schema, as used by -avro-schema. Only the
+// parts of Avro needed to validate and encode JSON values are supported:
+// the primitive types, records, enums, arrays, maps, unions and references
+// to named types.
+type avroSchema struct {
+	// Type is the name of a primitive type, or one of "record", "enum",
+	// "array", "map" and "union".
+	Type string
+
+	// Name is the full name of a record or enum.
+	Name string
+
+	Fields  []*avroField
+	Symbols []string
+	Items   *avroSchema
+	Values  *avroSchema
+	Union   []*avroSchema
+}
+
+// avroField is a field of an Avro record.
+type avroField struct {
+	Name       string
+	Type       *avroSchema
+	Default    interface{}
+	HasDefault bool
+}
+
+var avroPrimitives = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"int":     true,
+	"long":    true,
+	"float":   true,
+	"double":  true,
+	"bytes":   true,
+	"string":  true,
+}
+
+// loadAvroSchema reads and parses the Avro schema in the given file. The
+// schema must describe a record.
+func loadAvroSchema(path string) (*avroSchema, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Avro schema: %s", err)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(contents, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing Avro schema: %s", err)
+	}
+
+	schema, err := parseAvroSchema(raw, "", make(map[string]*avroSchema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %s", err)
+	}
+	if schema.Type != "record" {
+		return nil, fmt.Errorf("invalid Avro schema: expected a record, got %s", schema.Type)
+	}
+	return schema, nil
+}
+
+// parseAvroSchema parses a schema in its JSON form. Named types are
+// registered in names so that later references to them can be resolved.
+func parseAvroSchema(raw interface{}, namespace string, names map[string]*avroSchema) (*avroSchema, error) {
+	switch raw := raw.(type) {
+	case string:
+		if avroPrimitives[raw] {
+			return &avroSchema{Type: raw}, nil
+		}
+		if s, ok := names[avroFullName(raw, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := names[raw]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", raw)
+
+	case []interface{}:
+		union := &avroSchema{Type: "union"}
+		for _, branch := range raw {
+			s, err := parseAvroSchema(branch, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			if s.Type == "union" {
+				return nil, fmt.Errorf("unions may not contain unions")
+			}
+			union.Union = append(union.Union, s)
+		}
+		return union, nil
+
+	case map[string]interface{}:
+		typ, _ := raw["type"].(string)
+		switch typ {
+		case "record", "error":
+			name, _ := raw["name"].(string)
+			if name == "" {
+				return nil, fmt.Errorf("record without a name")
+			}
+			if ns, ok := raw["namespace"].(string); ok {
+				namespace = ns
+			}
+			s := &avroSchema{Type: "record", Name: avroFullName(name, namespace)}
+			names[s.Name] = s
+			if i := strings.LastIndex(s.Name, "."); i >= 0 {
+				namespace = s.Name[:i]
+			}
+
+			fields, ok := raw["fields"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("record %s without fields", s.Name)
+			}
+			for _, f := range fields {
+				f, ok := f.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("invalid field in record %s", s.Name)
+				}
+				fieldName, _ := f["name"].(string)
+				if fieldName == "" {
+					return nil, fmt.Errorf("field without a name in record %s", s.Name)
+				}
+				fieldType, err := parseAvroSchema(f["type"], namespace, names)
+				if err != nil {
+					return nil, fmt.Errorf("field %s.%s: %s", s.Name, fieldName, err)
+				}
+				def, hasDefault := f["default"]
+				s.Fields = append(s.Fields, &avroField{
+					Name:       fieldName,
+					Type:       fieldType,
+					Default:    def,
+					HasDefault: hasDefault,
+				})
+			}
+			return s, nil
+
+		case "enum":
+			name, _ := raw["name"].(string)
+			if name == "" {
+				return nil, fmt.Errorf("enum without a name")
+			}
+			if ns, ok := raw["namespace"].(string); ok {
+				namespace = ns
+			}
+			s := &avroSchema{Type: "enum", Name: avroFullName(name, namespace)}
+			symbols, _ := raw["symbols"].([]interface{})
+			for _, sym := range symbols {
+				if sym, ok := sym.(string); ok {
+					s.Symbols = append(s.Symbols, sym)
+				}
+			}
+			names[s.Name] = s
+			return s, nil
+
+		case "array":
+			items, err := parseAvroSchema(raw["items"], namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchema{Type: "array", Items: items}, nil
+
+		case "map":
+			values, err := parseAvroSchema(raw["values"], namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchema{Type: "map", Values: values}, nil
+
+		default:
+			// A primitive type or reference written as {"type": "string"}
+			return parseAvroSchema(raw["type"], namespace, names)
+		}
+	}
+
+	return nil, fmt.Errorf("invalid schema %v", raw)
+}
+
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// typeName is the name of the schema used to tag union values.
+func (s *avroSchema) typeName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+// encode validates v against the schema and returns it in the Avro JSON
+// encoding, in which values of unions are tagged with the name of their
+// type, such as {"string": "foo"}. If strict is set, fields of records that
+// are not in the schema are an error; otherwise they are dropped.
+func (s *avroSchema) encode(v interface{}, strict bool) (interface{}, error) {
+	switch s.Type {
+	case "null":
+		if v != nil {
+			return nil, fmt.Errorf("expected null, got %v", v)
+		}
+		return nil, nil
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return nil, fmt.Errorf("expected a boolean, got %v", v)
+		}
+		return v, nil
+
+	case "int", "long":
+		f, ok := avroNumber(v)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("expected an integer, got %v", v)
+		}
+		if s.Type == "int" && (f < math.MinInt32 || f > math.MaxInt32) {
+			return nil, fmt.Errorf("%v is out of range for an int", v)
+		}
+		return v, nil
+
+	case "float", "double":
+		if _, ok := avroNumber(v); !ok {
+			return nil, fmt.Errorf("expected a number, got %v", v)
+		}
+		return v, nil
+
+	case "string", "bytes":
+		if _, ok := v.(string); !ok {
+			return nil, fmt.Errorf("expected a string, got %v", v)
+		}
+		return v, nil
+
+	case "enum":
+		sym, _ := v.(string)
+		for _, symbol := range s.Symbols {
+			if sym == symbol {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("%v is not a symbol of %s", v, s.Name)
+
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %v", v)
+		}
+		result := make([]interface{}, len(list))
+		for i, elem := range list {
+			encoded, err := s.Items.encode(elem, strict)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			result[i] = encoded
+		}
+		return result, nil
+
+	case "map":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %v", v)
+		}
+		result := make(map[string]interface{}, len(m))
+		for k, elem := range m {
+			encoded, err := s.Values.encode(elem, strict)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k, err)
+			}
+			result[k] = encoded
+		}
+		return result, nil
+
+	case "union":
+		for _, branch := range s.Union {
+			encoded, err := branch.encode(v, strict)
+			if err != nil {
+				continue
+			}
+			if branch.Type == "null" {
+				return nil, nil
+			}
+			return map[string]interface{}{branch.typeName(): encoded}, nil
+		}
+		return nil, fmt.Errorf("%v does not match any type of the union", v)
+
+	case "record":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %v", v)
+		}
+
+		result := make(map[string]interface{}, len(s.Fields))
+		known := make(map[string]bool, len(s.Fields))
+		for _, f := range s.Fields {
+			known[f.Name] = true
+			value, ok := m[f.Name]
+			if !ok {
+				if !f.HasDefault {
+					return nil, fmt.Errorf("missing field %s", f.Name)
+				}
+				// Defaults of unions are of the first type of the union
+				fieldType := f.Type
+				if fieldType.Type == "union" {
+					fieldType = fieldType.Union[0]
+				}
+				encoded, err := fieldType.encode(f.Default, strict)
+				if err != nil {
+					return nil, fmt.Errorf("%s: invalid default: %s", f.Name, err)
+				}
+				if fieldType != f.Type && fieldType.Type != "null" {
+					encoded = map[string]interface{}{fieldType.typeName(): encoded}
+				}
+				result[f.Name] = encoded
+				continue
+			}
+
+			encoded, err := f.Type.encode(value, strict)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", f.Name, err)
+			}
+			result[f.Name] = encoded
+		}
+
+		if strict {
+			var unknown []string
+			for k := range m {
+				if !known[k] {
+					unknown = append(unknown, k)
+				}
+			}
+			if len(unknown) > 0 {
+				sort.Strings(unknown)
+				return nil, fmt.Errorf("fields not in the schema of %s: %s",
+					s.Name, strings.Join(unknown, ", "))
+			}
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", s.Type)
+}
+
+// avroNumber returns the value of a JSON number.
+func avroNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
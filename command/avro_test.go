@@ -0,0 +1,166 @@
+package command
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestAvroSchema_encode(t *testing.T) {
+	schema, err := loadAvroSchema(filepath.Join(FixturePath, "credentials.avsc"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Data     map[string]interface{}
+		Strict   bool
+		Expected map[string]interface{}
+		Err      string
+	}{
+		{
+			map[string]interface{}{
+				"username": "admin",
+				"port":     json.Number("5432"),
+				"comment":  "primary",
+				"roles":    []interface{}{"read", "write"},
+				"kind":     "static",
+			},
+			true,
+			map[string]interface{}{
+				"username": "admin",
+				"port":     json.Number("5432"),
+				"comment":  map[string]interface{}{"string": "primary"},
+				"roles":    []interface{}{"read", "write"},
+				"kind":     "static",
+			},
+			"",
+		},
+		// Defaults are used for missing fields and unknown fields are dropped
+		{
+			map[string]interface{}{
+				"username": "admin",
+				"port":     json.Number("5432"),
+				"kind":     "dynamic",
+				"extra":    "dropped",
+			},
+			false,
+			map[string]interface{}{
+				"username": "admin",
+				"port":     json.Number("5432"),
+				"comment":  nil,
+				"roles":    []interface{}{},
+				"kind":     "dynamic",
+			},
+			"",
+		},
+		{
+			map[string]interface{}{
+				"username": "admin",
+				"port":     json.Number("5432"),
+				"kind":     "dynamic",
+				"extra":    "dropped",
+			},
+			true,
+			nil,
+			"fields not in the schema of com.example.Credentials: extra",
+		},
+		{
+			map[string]interface{}{"username": "admin", "kind": "static"},
+			false,
+			nil,
+			"missing field port",
+		},
+		{
+			map[string]interface{}{"username": "admin", "port": "5432", "kind": "static"},
+			false,
+			nil,
+			"port: expected an integer",
+		},
+		{
+			map[string]interface{}{"username": "admin", "port": json.Number("1.5"), "kind": "static"},
+			false,
+			nil,
+			"port: expected an integer",
+		},
+		{
+			map[string]interface{}{"username": "admin", "port": json.Number("1"), "kind": "other"},
+			false,
+			nil,
+			"kind: other is not a symbol of com.example.Kind",
+		},
+	}
+
+	for i, tc := range cases {
+		actual, err := schema.encode(tc.Data, tc.Strict)
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%d: expected error %q, got %v", i, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: bad: %#v", i, actual)
+		}
+	}
+}
+
+func TestParseAvroSchema_invalid(t *testing.T) {
+	cases := []string{
+		`"string"`,
+		`{"type": "record", "fields": []}`,
+		`{"type": "record", "name": "r", "fields": [{"name": "f", "type": "nope"}]}`,
+	}
+
+	for _, tc := range cases {
+		var raw interface{}
+		if err := json.Unmarshal([]byte(tc), &raw); err != nil {
+			t.Fatal(err)
+		}
+		s, err := parseAvroSchema(raw, "", make(map[string]*avroSchema))
+		if err == nil && s.Type == "record" {
+			t.Fatalf("expected error for %s", tc)
+		}
+	}
+}
+
+func TestJsonFormatter_avroSchema(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"username": "admin",
+			"port":     json.Number("5432"),
+			"comment":  "primary",
+			"kind":     "static",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{AvroSchema: filepath.Join(FixturePath, "credentials.avsc")}
+	if code := OutputSecret(ui, "json", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{
+		"username": "admin",
+		"port":     5432.0,
+		"comment":  map[string]interface{}{"string": "primary"},
+		"roles":    []interface{}{},
+		"kind":     "static",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
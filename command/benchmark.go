@@ -0,0 +1,142 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// benchmarkWarmup is the largest number of requests made before a benchmark
+// starts measuring. They open the connections to the server so that the
+// first measured requests do not pay for it.
+const benchmarkWarmup = 5
+
+// benchmarkResult is the outcome of a single benchmarked request.
+type benchmarkResult struct {
+	latency time.Duration
+	err     error
+}
+
+// runBenchmark calls op n times, at most concurrency at a time, after a
+// brief warm-up, and returns the outcome of each measured call along with
+// the total time taken.
+func runBenchmark(n, concurrency int, op func() error) ([]benchmarkResult, time.Duration) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	warmup := n / 10
+	if warmup > benchmarkWarmup {
+		warmup = benchmarkWarmup
+	}
+	for i := 0; i < warmup; i++ {
+		op()
+	}
+
+	results := make([]benchmarkResult, n)
+	work := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				reqStart := time.Now()
+				err := op()
+				results[i] = benchmarkResult{latency: time.Since(reqStart), err: err}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results, time.Since(start)
+}
+
+// benchmarkStats computes the statistics reported for the results of a
+// benchmark that took the given time.
+func benchmarkStats(results []benchmarkResult, elapsed time.Duration) map[string]interface{} {
+	errors := 0
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.err != nil {
+			errors++
+		}
+		latencies = append(latencies, r.latency)
+	}
+	sort.Sort(durations(latencies))
+
+	stats := map[string]interface{}{
+		"requests":       len(results),
+		"errors":         errors,
+		"error_rate":     0.0,
+		"duration_ms":    milliseconds(elapsed),
+		"throughput":     0.0,
+		"latency_p50_ms": milliseconds(percentile(latencies, 50)),
+		"latency_p95_ms": milliseconds(percentile(latencies, 95)),
+		"latency_p99_ms": milliseconds(percentile(latencies, 99)),
+	}
+	if len(results) > 0 {
+		stats["error_rate"] = float64(errors) / float64(len(results))
+	}
+	if elapsed > 0 {
+		stats["throughput"] = float64(len(results)) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// percentile returns the p-th percentile of the sorted latencies, using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// outputBenchmark benchmarks op and outputs the statistics in the given
+// format. It returns a non-zero exit code if any request failed.
+func outputBenchmark(ui cli.Ui, format string, n, concurrency int, opts *meta.OutputOptions, op func() error) int {
+	if n < 1 {
+		ui.Error("-benchmark must be at least 1")
+		return 1
+	}
+
+	results, elapsed := runBenchmark(n, concurrency, op)
+	stats := benchmarkStats(results, elapsed)
+	if code := OutputSecret(ui, format, &api.Secret{Data: stats}, opts); code != 0 {
+		return code
+	}
+
+	for _, r := range results {
+		if r.err != nil {
+			ui.Error(fmt.Sprintf("First error: %s", r.err))
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestBenchmarkStats(t *testing.T) {
+	var results []benchmarkResult
+	for i := 1; i <= 100; i++ {
+		r := benchmarkResult{latency: time.Duration(i) * time.Millisecond}
+		if i%10 == 0 {
+			r.err = errors.New("failed")
+		}
+		results = append(results, r)
+	}
+
+	stats := benchmarkStats(results, 2*time.Second)
+	expected := map[string]interface{}{
+		"requests":       100,
+		"errors":         10,
+		"error_rate":     0.1,
+		"duration_ms":    2000.0,
+		"throughput":     50.0,
+		"latency_p50_ms": 50.0,
+		"latency_p95_ms": 95.0,
+		"latency_p99_ms": 99.0,
+	}
+	for k, v := range expected {
+		if stats[k] != v {
+			t.Fatalf("%s: expected %v, got %v", k, v, stats[k])
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	if p := percentile(nil, 50); p != 0 {
+		t.Fatalf("bad: %s", p)
+	}
+
+	sorted := []time.Duration{1, 2, 3}
+	cases := map[float64]time.Duration{0: 1, 50: 2, 99: 3, 100: 3}
+	for p, expected := range cases {
+		if actual := percentile(sorted, p); actual != expected {
+			t.Fatalf("p%v: expected %s, got %s", p, expected, actual)
+		}
+	}
+}
+
+func TestRead_benchmark(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		// Fail every fourth request
+		if n%4 == 0 {
+			w.WriteHeader(500)
+			w.Write([]byte(`{"errors":["failed"]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"value":"bar"}}`))
+	}))
+	defer ts.Close()
+
+	ui := new(cli.MockUi)
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+
+	// Disable retries so that every failure is seen
+	os.Setenv("VAULT_MAX_RETRIES", "0")
+	defer os.Unsetenv("VAULT_MAX_RETRIES")
+
+	args := []string{
+		"-address", ts.URL,
+		"-benchmark", "20",
+		"-concurrency", "4",
+		"-format", "json",
+		"secret/foo",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// Two warm-up requests are made before measuring
+	if requests != 22 {
+		t.Fatalf("bad: expected 22 requests, got %d", requests)
+	}
+
+	var secret struct {
+		Data map[string]float64
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &secret); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	stats := secret.Data
+	if stats["requests"] != 20 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats["errors"] != 5 || stats["error_rate"] != 0.25 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats["throughput"] <= 0 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats["latency_p50_ms"] > stats["latency_p99_ms"] {
+		t.Fatalf("bad: %#v", stats)
+	}
+}
@@ -4,16 +4,23 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/meta"
 )
 
+// rootCapabilities are the capabilities implied by the root capability.
+var rootCapabilities = []string{"create", "read", "update", "delete", "list", "sudo"}
+
 // CapabilitiesCommand is a Command that enables a new endpoint.
 type CapabilitiesCommand struct {
 	meta.Meta
 }
 
 func (c *CapabilitiesCommand) Run(args []string) int {
-	flags := c.Meta.FlagSet("capabilities", meta.FlagSetDefault)
+	var format string
+	flags := c.Meta.FlagSet("capabilities", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -60,10 +67,52 @@ func (c *CapabilitiesCommand) Run(args []string) int {
 		return 1
 	}
 
+	if flagIsSet(flags, "format") {
+		return OutputSecret(c.Ui, format, capabilitiesSecret(path, capabilities), c.OutputOptions())
+	}
+
 	c.Ui.Output(fmt.Sprintf("Capabilities: %s", capabilities))
 	return 0
 }
 
+// capabilitiesSecret returns the capabilities on the path as a secret for
+// structured output. Besides the capabilities returned by the server, which
+// combine those granted by each of the token's policies, it gives the
+// capabilities they amount to and whether they include root, deny or sudo,
+// which each have a special meaning.
+func capabilitiesSecret(path string, capabilities []string) *api.Secret {
+	root := strutil.StrListContains(capabilities, "root")
+	deny := strutil.StrListContains(capabilities, "deny")
+
+	effective := capabilities
+	switch {
+	case deny:
+		effective = []string{}
+	case root:
+		effective = rootCapabilities
+	}
+
+	return &api.Secret{
+		Data: map[string]interface{}{
+			"path":                   path,
+			"capabilities":           interfaceList(capabilities),
+			"effective_capabilities": interfaceList(effective),
+			"root":                   root,
+			"deny":                   deny,
+			"sudo":                   strutil.StrListContains(effective, "sudo"),
+		},
+	}
+}
+
+// interfaceList converts the list to the type of lists in decoded JSON.
+func interfaceList(list []string) []interface{} {
+	result := make([]interface{}, 0, len(list))
+	for _, v := range list {
+		result = append(result, v)
+	}
+	return result
+}
+
 func (c *CapabilitiesCommand) Synopsis() string {
 	return "Fetch the capabilities of a token on a given path"
 }
@@ -82,6 +131,17 @@ Usage: vault capabilities [options] [token] path
   is invalid, this command will respond with a ["deny"].
 
 General Options:
-` + meta.GeneralOptionsUsage()
+` + meta.GeneralOptionsUsage() + `
+Capabilities Options:
+
+  -format=table           Output the capabilities in the given format, which
+                          can be table, json, or yaml, along with the
+                          capabilities they amount to and whether they include
+                          root, deny, or sudo. Root implies every capability
+                          and deny overrides all others. By default the
+                          capabilities are printed as a single line.
+
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
@@ -1,8 +1,14 @@
 package command
 
 import (
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/meta"
 	"github.com/hashicorp/vault/vault"
@@ -43,3 +49,97 @@ func TestCapabilities_Basic(t *testing.T) {
 		t.Fatalf("expected failure due to invalid token")
 	}
 }
+
+func TestCapabilities_format(t *testing.T) {
+	var capabilities string
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprintf(w, `{"capabilities":%s}`, capabilities)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Capabilities string
+		Expected     map[string]interface{}
+	}{
+		{
+			// Granted by several policies
+			`["list","read","sudo","update"]`,
+			map[string]interface{}{
+				"path":                   "secret/foo",
+				"capabilities":           []interface{}{"list", "read", "sudo", "update"},
+				"effective_capabilities": []interface{}{"list", "read", "sudo", "update"},
+				"root":                   false,
+				"deny":                   false,
+				"sudo":                   true,
+			},
+		},
+		{
+			`["root"]`,
+			map[string]interface{}{
+				"path":                   "secret/foo",
+				"capabilities":           []interface{}{"root"},
+				"effective_capabilities": []interface{}{"create", "read", "update", "delete", "list", "sudo"},
+				"root":                   true,
+				"deny":                   false,
+				"sudo":                   true,
+			},
+		},
+		{
+			`["deny"]`,
+			map[string]interface{}{
+				"path":                   "secret/foo",
+				"capabilities":           []interface{}{"deny"},
+				"effective_capabilities": []interface{}{},
+				"root":                   false,
+				"deny":                   true,
+				"sudo":                   false,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		capabilities = tc.Capabilities
+		ui := cli.NewMockUi()
+		c := &CapabilitiesCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		if code := c.Run([]string{"-address", ts.URL, "-format", "json", "secret/foo"}); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Capabilities, code, ui.ErrorWriter.String())
+		}
+		var secret api.Secret
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &secret); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(secret.Data, tc.Expected) {
+			t.Fatalf("%s: bad: %#v", tc.Capabilities, secret.Data)
+		}
+	}
+
+	// The table has a row for each field
+	capabilities = `["read","sudo"]`
+	ui := cli.NewMockUi()
+	c := &CapabilitiesCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	if code := c.Run([]string{"-address", ts.URL, "-format", "table", "-array-style", "joined", "secret/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	for _, pattern := range []string{
+		`capabilities\s+read,sudo\n`,
+		`root\s+false\n`,
+		`deny\s+false\n`,
+		`sudo\s+true\n`,
+	} {
+		if !regexpMatch(t, pattern, out) {
+			t.Fatalf("expected output to match %s:\n%s", pattern, out)
+		}
+	}
+}
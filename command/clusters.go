@@ -0,0 +1,114 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// parseAddresses splits the comma-separated value of -addresses.
+func parseAddresses(s string) []string {
+	var addresses []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
+
+// fanOut calls fn with a client for each of the addresses given by
+// -addresses, all at the same time. It returns the secret from each cluster
+// that succeeded, keyed by address, and the addresses in the order they were
+// given. The clusters that failed are reported, but do not stop the others;
+// the returned code is non-zero if any failed.
+func fanOut(m *meta.Meta, addresses []string, what string, fn func(*api.Client) (*api.Secret, error)) ([]string, map[string]*api.Secret, int) {
+	clients, err := m.Clients(addresses)
+	if err != nil {
+		m.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return nil, nil, 2
+	}
+
+	secrets := make([]*api.Secret, len(clients))
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *api.Client) {
+			defer wg.Done()
+			secrets[i], errs[i] = fn(client)
+		}(i, client)
+	}
+	wg.Wait()
+
+	ret := 0
+	var ok []string
+	results := make(map[string]*api.Secret, len(addresses))
+	for i, addr := range addresses {
+		switch {
+		case errs[i] != nil:
+			m.Ui.Error(fmt.Sprintf("Error reading %s from %s: %s", what, addr, errs[i]))
+			ret = 1
+		case secrets[i] == nil:
+			m.Ui.Error(fmt.Sprintf("No value found at %s on %s", what, addr))
+			ret = 1
+		default:
+			ok = append(ok, addr)
+			results[addr] = secrets[i]
+		}
+	}
+	return ok, results, ret
+}
+
+// outputClusterLists outputs the keys listed on each cluster. The table
+// format prints a "=== address ===" header before the keys of each cluster,
+// while the other formats key the lists by address.
+func outputClusterLists(ui cli.Ui, format string, addresses []string, secrets map[string]*api.Secret, opts *meta.OutputOptions) int {
+	if strings.ToLower(format) != "table" {
+		lists := make(map[string]interface{}, len(secrets))
+		for addr, secret := range secrets {
+			keys := secret.Data["keys"]
+			if opts != nil && (opts.SortBy != "" || opts.SortDesc) {
+				sorted, err := sortListKeys(secret, opts.SortBy, opts.SortDesc)
+				if err != nil {
+					ui.Error(err.Error())
+					return 1
+				}
+				keys = sorted
+			}
+			if keys == nil {
+				keys = []interface{}{}
+			}
+			lists[addr] = keys
+		}
+		return outputWithFormat(ui, format, nil, lists, opts)
+	}
+
+	// The lists are signed, and their final newline left out, together
+	// rather than one by one
+	var unsigned *meta.OutputOptions
+	if opts != nil {
+		o := *opts
+		o.SignOutput = ""
+		o.NoFinalNewline = false
+		unsigned = &o
+	}
+	return outputSigned(ui, opts, func(ui cli.Ui) int {
+		for _, addr := range addresses {
+			ui.Output(fmt.Sprintf("=== %s ===", addr))
+			if _, ok := secrets[addr].Data["keys"].([]interface{}); !ok {
+				ui.Output("No entries found")
+				continue
+			}
+			if code := OutputList(ui, format, secrets[addr], unsigned); code != 0 {
+				return code
+			}
+		}
+		return 0
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
@@ -18,16 +20,37 @@ const (
 	// ConfigPathEnv is the environment variable that can be used to
 	// override where the Vault configuration is.
 	ConfigPathEnv = "VAULT_CONFIG_PATH"
+
+	// ProfileEnv is the environment variable that selects the profile of
+	// the configuration to use.
+	ProfileEnv = "VAULT_PROFILE"
 )
 
 // Config is the CLI configuration for Vault that can be specified via
-// a `$HOME/.vault` file which is HCL-formatted (therefore HCL or JSON).
+// a `$HOME/.vault` file, or one of the other locations listed by
+// ConfigSearchPaths, which is HCL-formatted (therefore HCL or JSON).
 type DefaultConfig struct {
 	// TokenHelper is the executable/command that is executed for storing
 	// and retrieving the authentication token for the Vault CLI. If this
 	// is not specified, then vault's internal token store will be used, which
 	// stores the token on disk unencrypted.
 	TokenHelper string `hcl:"token_helper"`
+
+	// AllowedAddresses is a list of glob patterns that the address of the
+	// Vault server must match. If it is empty, any address may be used.
+	AllowedAddresses []string `hcl:"allowed_addresses"`
+
+	// Profiles are the named profiles of the configuration, one of which
+	// can be selected with the VAULT_PROFILE environment variable.
+	Profiles map[string]*ProfileConfig `hcl:"-"`
+}
+
+// ProfileConfig is the configuration of a single profile, given in a
+// `profile "name" { ... }` block. Settings made by the profile in use take
+// precedence over the top-level ones.
+type ProfileConfig struct {
+	// TokenHelper is the token helper executable to use with this profile.
+	TokenHelper string `hcl:"token_helper"`
 }
 
 // Config loads the configuration and returns it. If the configuration
@@ -43,11 +66,12 @@ func Config() (*DefaultConfig, error) {
 }
 
 // LoadConfig reads the configuration from the given path. If path is
-// empty, then the default path will be used, or the environment variable
-// if set.
+// empty, then the environment variable will be used if set, or else the
+// first configuration file found in the standard locations (see
+// ConfigSearchPaths), falling back to the default path.
 func LoadConfig(path string) (*DefaultConfig, error) {
 	if path == "" {
-		path = DefaultConfigPath
+		path = discoverConfigPath(ConfigSearchPaths(runtime.GOOS, os.Getenv), fileExists)
 	}
 	if v := os.Getenv(ConfigPathEnv); v != "" {
 		path = v
@@ -67,6 +91,56 @@ func LoadConfig(path string) (*DefaultConfig, error) {
 	return ParseConfig(string(contents))
 }
 
+// ConfigSearchPaths returns the locations searched for the configuration
+// file, in order of preference, for the given operating system:
+//
+//	$XDG_CONFIG_HOME/vault/cli.hcl, or %APPDATA%\vault\cli.hcl on Windows
+//	~/.config/vault/cli.hcl, except on Windows
+//	~/.vault/cli.hcl
+//	~/.vault
+//
+// The last is the original location of the configuration file. Locations
+// that cannot be determined, such as an unset XDG_CONFIG_HOME, are left out.
+func ConfigSearchPaths(goos string, getenv func(string) string) []string {
+	var paths []string
+	if goos == "windows" {
+		if v := getenv("APPDATA"); v != "" {
+			paths = append(paths, filepath.Join(v, "vault", "cli.hcl"))
+		}
+	} else if v := getenv("XDG_CONFIG_HOME"); v != "" {
+		paths = append(paths, filepath.Join(v, "vault", "cli.hcl"))
+	}
+
+	// NOTE: requires HOME env var to be set
+	if home, err := homedir.Dir(); err == nil && home != "" {
+		if goos != "windows" {
+			paths = append(paths, filepath.Join(home, ".config", "vault", "cli.hcl"))
+		}
+		paths = append(paths,
+			filepath.Join(home, ".vault", "cli.hcl"),
+			filepath.Join(home, ".vault"))
+	}
+
+	return paths
+}
+
+// discoverConfigPath returns the first of the given paths for which exists
+// returns true, or DefaultConfigPath if there is none.
+func discoverConfigPath(paths []string, exists func(string) bool) string {
+	for _, path := range paths {
+		if exists(path) {
+			return path
+		}
+	}
+	return DefaultConfigPath
+}
+
+// fileExists returns whether path exists and is a regular file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
 // ParseConfig parses the given configuration as a string.
 func ParseConfig(contents string) (*DefaultConfig, error) {
 	root, err := hcl.Parse(contents)
@@ -82,6 +156,8 @@ func ParseConfig(contents string) (*DefaultConfig, error) {
 
 	valid := []string{
 		"token_helper",
+		"allowed_addresses",
+		"profile",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
@@ -91,9 +167,41 @@ func ParseConfig(contents string) (*DefaultConfig, error) {
 	if err := hcl.DecodeObject(&c, list); err != nil {
 		return nil, err
 	}
+
+	if o := list.Filter("profile"); len(o.Items) > 0 {
+		if err := parseProfiles(&c, o); err != nil {
+			return nil, err
+		}
+	}
 	return &c, nil
 }
 
+func parseProfiles(result *DefaultConfig, list *ast.ObjectList) error {
+	profiles := make(map[string]*ProfileConfig, len(list.Items))
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("profile on line %d must have a name", item.Assign.Line)
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		valid := []string{
+			"token_helper",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("profile.%s:", name))
+		}
+
+		var p ProfileConfig
+		if err := hcl.DecodeObject(&p, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("profile.%s:", name))
+		}
+		profiles[name] = &p
+	}
+
+	result.Profiles = profiles
+	return nil
+}
+
 func checkHCLKeys(node ast.Node, valid []string) error {
 	var list *ast.ObjectList
 	switch n := node.(type) {
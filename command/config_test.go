@@ -1,10 +1,17 @@
 package command
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/vault/command/token"
+	"github.com/mitchellh/go-homedir"
 )
 
 const FixturePath = "./test-fixtures"
@@ -34,6 +41,87 @@ func TestLoadConfig_noExist(t *testing.T) {
 	}
 }
 
+func TestConfigSearchPaths(t *testing.T) {
+	home, err := homedir.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"XDG_CONFIG_HOME": "/xdg",
+		"APPDATA":         "/appdata",
+	}
+	getenv := func(k string) string { return env[k] }
+
+	cases := []struct {
+		GOOS     string
+		Getenv   func(string) string
+		Expected []string
+	}{
+		{
+			"linux",
+			getenv,
+			[]string{
+				filepath.Join("/xdg", "vault", "cli.hcl"),
+				filepath.Join(home, ".config", "vault", "cli.hcl"),
+				filepath.Join(home, ".vault", "cli.hcl"),
+				filepath.Join(home, ".vault"),
+			},
+		},
+		{
+			"linux",
+			func(string) string { return "" },
+			[]string{
+				filepath.Join(home, ".config", "vault", "cli.hcl"),
+				filepath.Join(home, ".vault", "cli.hcl"),
+				filepath.Join(home, ".vault"),
+			},
+		},
+		{
+			"windows",
+			getenv,
+			[]string{
+				filepath.Join("/appdata", "vault", "cli.hcl"),
+				filepath.Join(home, ".vault", "cli.hcl"),
+				filepath.Join(home, ".vault"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		actual := ConfigSearchPaths(tc.GOOS, tc.Getenv)
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: bad: %#v", i, actual)
+		}
+	}
+}
+
+func TestDiscoverConfigPath(t *testing.T) {
+	paths := []string{"/xdg/vault/cli.hcl", "/home/.config/vault/cli.hcl", "/home/.vault"}
+
+	cases := []struct {
+		Files    []string
+		Expected string
+	}{
+		{[]string{"/xdg/vault/cli.hcl", "/home/.vault"}, "/xdg/vault/cli.hcl"},
+		{[]string{"/home/.config/vault/cli.hcl", "/home/.vault"}, "/home/.config/vault/cli.hcl"},
+		{[]string{"/home/.vault"}, "/home/.vault"},
+		{nil, DefaultConfigPath},
+	}
+
+	for _, tc := range cases {
+		files := make(map[string]bool)
+		for _, f := range tc.Files {
+			files[f] = true
+		}
+		exists := func(path string) bool { return files[path] }
+
+		if actual := discoverConfigPath(paths, exists); actual != tc.Expected {
+			t.Fatalf("%v: expected %s, got %s", tc.Files, tc.Expected, actual)
+		}
+	}
+}
+
 func TestParseConfig_badKeys(t *testing.T) {
 	_, err := ParseConfig(`
 token_helper = "/token"
@@ -47,3 +135,117 @@ nope = "true"
 		t.Errorf("bad error: %s", err.Error())
 	}
 }
+
+func TestParseConfig_allowedAddresses(t *testing.T) {
+	config, err := ParseConfig(`
+allowed_addresses = ["https://vault-*.dev.example.com:8200", "http://127.0.0.1:*"]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"https://vault-*.dev.example.com:8200", "http://127.0.0.1:*"}
+	if !reflect.DeepEqual(config.AllowedAddresses, expected) {
+		t.Fatalf("bad: %#v", config.AllowedAddresses)
+	}
+}
+
+func TestParseConfig_profiles(t *testing.T) {
+	config, err := ParseConfig(`
+token_helper = "/default"
+
+profile "dev" {
+	token_helper = "/dev"
+}
+
+profile "prod" {
+	token_helper = "/prod"
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*ProfileConfig{
+		"dev":  &ProfileConfig{TokenHelper: "/dev"},
+		"prod": &ProfileConfig{TokenHelper: "/prod"},
+	}
+	if !reflect.DeepEqual(config.Profiles, expected) {
+		t.Fatalf("bad: %#v", config.Profiles)
+	}
+
+	_, err = ParseConfig(`
+profile "dev" {
+	nope = "true"
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "profile.dev: invalid key 'nope'") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestConfigTokenHelper_profiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake token helpers are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A fake token helper for each profile that returns the profile name
+	// as the token
+	helpers := make(map[string]string)
+	for _, name := range []string{"dev", "prod"} {
+		path := filepath.Join(dir, name)
+		script := fmt.Sprintf("#!/bin/sh\nprintf %s-token\n", name)
+		if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		helpers[name] = path
+	}
+
+	config := &DefaultConfig{
+		Profiles: map[string]*ProfileConfig{
+			"dev":     &ProfileConfig{TokenHelper: helpers["dev"]},
+			"prod":    &ProfileConfig{TokenHelper: helpers["prod"]},
+			"missing": &ProfileConfig{TokenHelper: filepath.Join(dir, "nope")},
+			"none":    &ProfileConfig{},
+		},
+	}
+
+	for _, name := range []string{"dev", "prod"} {
+		helper, err := configTokenHelper(config, name)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		token, err := helper.Get()
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if token != name+"-token" {
+			t.Fatalf("%s: bad token: %q", name, token)
+		}
+	}
+
+	// A profile without a token helper uses the top-level setting
+	helper, err := configTokenHelper(config, "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := helper.(*token.InternalTokenHelper); !ok {
+		t.Fatalf("bad: %#v", helper)
+	}
+
+	_, err = configTokenHelper(config, "missing")
+	if err == nil || !strings.Contains(err.Error(), `of profile "missing"`) {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	_, err = configTokenHelper(config, "nope")
+	if err == nil || !strings.Contains(err.Error(), `Profile "nope" is not defined`) {
+		t.Fatalf("bad error: %v", err)
+	}
+}
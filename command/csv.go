@@ -0,0 +1,161 @@
+package command
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// An output formatter for csv output of an object. The data of a secret is
+// output as "key,value" rows, with nested objects and arrays flattened into
+// dotted keys such as "a.b" and "list.0", and the keys of a list as a "key"
+// column followed by a column for each field of their metadata.
+type CsvFormatter struct {
+}
+
+func (c CsvFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	var rows [][]string
+	switch d := data.(type) {
+	case *api.Secret:
+		rows = c.flatRows(d.Data, opts)
+	case []interface{}:
+		rows = c.listRows(secret, d, opts)
+	default:
+		// Anything else is flattened based on how it is encoded
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		var decoded interface{}
+		if err := jsonutil.DecodeJSON(b, &decoded); err != nil {
+			return err
+		}
+		m, ok := decoded.(map[string]interface{})
+		if !ok {
+			return errors.New("Cannot use the csv formatter for this type")
+		}
+		rows = c.flatRows(m, opts)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	ui.Output(strings.TrimSuffix(buf.String(), "\n"))
+	return nil
+}
+
+// flatRows returns a "key,value" header and a row for each value of the
+// data, sorted by key.
+func (c CsvFormatter) flatRows(data map[string]interface{}, opts *meta.OutputOptions) [][]string {
+	flat := make(map[string]string)
+	for k, v := range data {
+		c.flatten(k, v, flat, opts)
+	}
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rows := [][]string{{"key", "value"}}
+	for _, k := range keys {
+		rows = append(rows, []string{c.text(k, opts), flat[k]})
+	}
+	return rows
+}
+
+// flatten adds the value to flat under the key, or each of its elements
+// under a dotted key if it is a non-empty object or array.
+func (c CsvFormatter) flatten(key string, v interface{}, flat map[string]string, opts *meta.OutputOptions) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			flat[key] = "{}"
+			return
+		}
+		for k, elem := range v {
+			c.flatten(key+"."+k, elem, flat, opts)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			flat[key] = "[]"
+			return
+		}
+		for i, elem := range v {
+			c.flatten(fmt.Sprintf("%s.%d", key, i), elem, flat, opts)
+		}
+	default:
+		flat[key] = c.value(v, opts)
+	}
+}
+
+// listRows returns the rows for the keys of a list, with a column for each
+// field of their metadata.
+func (c CsvFormatter) listRows(secret *api.Secret, list []interface{}, opts *meta.OutputOptions) [][]string {
+	var columns []string
+	var info map[string]interface{}
+	if secret != nil {
+		columns, _ = listRows(secret)
+		columns = columns[1:]
+		info, _ = secret.Data["key_info"].(map[string]interface{})
+	}
+
+	header := []string{"key"}
+	for _, col := range columns {
+		header = append(header, c.text(col, opts))
+	}
+	rows := [][]string{header}
+	for _, k := range list {
+		key, _ := k.(string)
+		row := []string{c.text(key, opts)}
+		fields, _ := info[key].(map[string]interface{})
+		for _, col := range columns {
+			v, ok := fields[col]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, c.value(v, opts))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// value renders a single value. Null is rendered as an empty field, and
+// objects and arrays, which only occur in list metadata, as JSON.
+func (c CsvFormatter) value(v interface{}, opts *meta.OutputOptions) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return c.text(v, opts)
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err == nil {
+			return c.text(string(b), opts)
+		}
+	}
+	return c.text(fmt.Sprintf("%v", v), opts)
+}
+
+// text escapes the control characters in s, other than tabs and newlines,
+// unless -no-sanitize is set. Quoting is left to the csv writer.
+func (c CsvFormatter) text(s string, opts *meta.OutputOptions) string {
+	if opts.NoSanitize {
+		return s
+	}
+	return sanitize(s)
+}
@@ -0,0 +1,96 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestCsvFormatter(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"plain":   "value",
+			"comma":   "a,b",
+			"quote":   `say "hi"`,
+			"newline": "line1\nline2",
+			"port":    json.Number("5432"),
+			"null":    nil,
+			"nested": map[string]interface{}{
+				"a":    map[string]interface{}{"b": true},
+				"list": []interface{}{"x", map[string]interface{}{"y": "z"}},
+			},
+			"empty": []interface{}{},
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "csv", s, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	expected := `key,value
+comma,"a,b"
+empty,[]
+nested.a.b,true
+nested.list.0,x
+nested.list.1.y,z
+newline,"line1
+line2"
+null,
+plain,value
+port,5432
+quote,"say ""hi"""
+`
+	if actual := ui.OutputWriter.String(); actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestCsvFormatter_list(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"keys": []interface{}{"foo", "bar"},
+			"key_info": map[string]interface{}{
+				"foo": map[string]interface{}{"owner": "alice, bob"},
+			},
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputList(ui, "csv", s, &meta.OutputOptions{}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	expected := "key,owner\nfoo,\"alice, bob\"\nbar,\n"
+	if actual := ui.OutputWriter.String(); actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestRead_csvField(t *testing.T) {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprint(w, `{"data":{"value":"a,b"}}`)
+	}))
+	defer ts.Close()
+
+	ui := new(cli.MockUi)
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+
+	// -field takes precedence over the format
+	args := []string{"-address", ts.URL, "-format", "csv", "-field", "value", "secret/foo"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if actual := ui.OutputWriter.String(); actual != "a,b\n" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
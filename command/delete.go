@@ -26,7 +26,11 @@ func (c *DeleteCommand) Run(args []string) int {
 		return 1
 	}
 
-	path := args[0]
+	path, err := expandPath(args[0])
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 
 	client, err := c.Client()
 	if err != nil {
@@ -61,6 +65,9 @@ Usage: vault delete [options] path
   policy for the AWS backend. Use "vault help" for more details on
   whether delete is supported for a path and what the behavior is.
 
+  The path may be a template using the date and env functions, such as
+  secret/logs/{{date "2006-01"}}; see "vault read -h".
+
 General Options:
 ` + meta.GeneralOptionsUsage()
 	return strings.TrimSpace(helpText)
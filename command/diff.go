@@ -0,0 +1,93 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// secretDiff is the key-level difference between two versions of a secret.
+type secretDiff struct {
+	Added   map[string]interface{} `json:"added"`
+	Removed map[string]interface{} `json:"removed"`
+	Changed map[string]*diffChange `json:"changed"`
+}
+
+// diffChange is the old and new value of a key that changed between two
+// versions of a secret.
+type diffChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// diffData returns the keys that were added, removed and changed going from
+// the old data to the new data.
+func diffData(old, new map[string]interface{}) *secretDiff {
+	diff := &secretDiff{
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]*diffChange),
+	}
+	for k, v := range old {
+		newV, ok := new[k]
+		switch {
+		case !ok:
+			diff.Removed[k] = v
+		case !reflect.DeepEqual(v, newV):
+			diff.Changed[k] = &diffChange{Old: v, New: newV}
+		}
+	}
+	for k, v := range new {
+		if _, ok := old[k]; !ok {
+			diff.Added[k] = v
+		}
+	}
+	return diff
+}
+
+// parseVersions parses a pair of versions given as "from,to".
+func parseVersions(s string) (int, int, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected two versions separated by a comma, got %q", s)
+	}
+
+	var versions [2]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < 1 {
+			return 0, 0, fmt.Errorf("invalid version %q", part)
+		}
+		versions[i] = v
+	}
+	return versions[0], versions[1], nil
+}
+
+// readVersion reads the data of a single version of a versioned secret.
+// Versioned backends nest the secret data under "data" alongside the version
+// "metadata"; other responses are used as they are.
+func readVersion(client *api.Client, path string, version int) (map[string]interface{}, error) {
+	secret, err := client.Logical().ReadWithData(path, map[string][]string{
+		"version": []string{strconv.Itoa(version)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading version %d of %s: %s", version, path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("Version %d of %s not found", version, path)
+	}
+
+	data := secret.Data
+	if _, ok := data["metadata"]; ok {
+		inner, ok := data["data"].(map[string]interface{})
+		if !ok {
+			// Deleted and destroyed versions have no data
+			return nil, fmt.Errorf("Version %d of %s not found", version, path)
+		}
+		data = inner
+	}
+	return data, nil
+}
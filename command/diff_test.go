@@ -0,0 +1,143 @@
+package command
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestDiffData(t *testing.T) {
+	old := map[string]interface{}{"a": "1", "b": "2", "c": "3"}
+	new := map[string]interface{}{"a": "1", "b": "two", "d": "4"}
+
+	expected := &secretDiff{
+		Added:   map[string]interface{}{"d": "4"},
+		Removed: map[string]interface{}{"c": "3"},
+		Changed: map[string]*diffChange{"b": {Old: "2", New: "two"}},
+	}
+	if actual := diffData(old, new); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	from, to, err := parseVersions("3, 5")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if from != 3 || to != 5 {
+		t.Fatalf("bad: %d, %d", from, to)
+	}
+
+	for _, s := range []string{"3", "3,5,7", "a,5", "0,1"} {
+		if _, _, err := parseVersions(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+// testVersionedServer serves the given versions of a versioned secret, in
+// the form returned by versioned backends.
+func testVersionedServer(versions map[string]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := r.URL.Query().Get("version")
+		data, ok := versions[version]
+		if !ok {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     data,
+				"metadata": map[string]interface{}{"version": version},
+			},
+		})
+	}))
+}
+
+func TestRead_diffVersions(t *testing.T) {
+	ts := testVersionedServer(map[string]map[string]interface{}{
+		"3": {"user": "admin", "password": "old", "host": "db1"},
+		"5": {"user": "admin", "password": "new", "port": "5432"},
+	})
+	defer ts.Close()
+
+	ui := new(cli.MockUi)
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+
+	args := []string{
+		"-address", ts.URL,
+		"-diff-versions", "3,5",
+		"secret/data/foo",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, pattern := range []string{
+		`(?m)^added\s+port\s+5432$`,
+		`(?m)^changed\s+password\s+old -> new$`,
+		`(?m)^removed\s+host\s+db1$`,
+	} {
+		if !regexpMatch(t, pattern, output) {
+			t.Fatalf("expected %q to match output:\n%s", pattern, output)
+		}
+	}
+	if strings.Contains(output, "user") {
+		t.Fatalf("unchanged key in output:\n%s", output)
+	}
+
+	// JSON output
+	ui = new(cli.MockUi)
+	c.Meta.Ui = ui
+	args = []string{
+		"-address", ts.URL,
+		"-diff-versions", "3,5",
+		"-format", "json",
+		"secret/data/foo",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var diff map[string]map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &diff); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]map[string]interface{}{
+		"added":   {"port": "5432"},
+		"removed": {"host": "db1"},
+		"changed": {"password": map[string]interface{}{"old": "old", "new": "new"}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("bad: %#v", diff)
+	}
+
+	// A missing version is an error
+	ui = new(cli.MockUi)
+	c.Meta.Ui = ui
+	args = []string{
+		"-address", ts.URL,
+		"-diff-versions", "3,4",
+		"secret/data/foo",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Version 4 of secret/data/foo not found") {
+		t.Fatalf("bad error:\n%s", ui.ErrorWriter.String())
+	}
+}
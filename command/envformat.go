@@ -0,0 +1,53 @@
+package command
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// An output formatter for shell output, to set environment variables from
+// the data of a secret with eval "$(vault read -format=env ...)". Each field
+// is output as an "export NAME='value'" line, with the names given by
+// secretEnv, prefixed with -env-prefix.
+type EnvFormatter struct {
+}
+
+func (e EnvFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	var s *api.Secret
+	switch d := data.(type) {
+	case *api.Secret:
+		s = d
+	case map[string]interface{}:
+		// The data section given by -only
+		s = &api.Secret{Data: d}
+	default:
+		return errors.New("the env format can only output the data of a secret")
+	}
+
+	vars := secretEnv(s, opts.EnvPrefix)
+	lines := make([]string, 0, len(vars))
+	for _, v := range vars {
+		i := strings.Index(v, "=")
+		value := v[i+1:]
+		if !opts.NoSanitize {
+			value = sanitize(value)
+		}
+		lines = append(lines, "export "+v[:i]+"="+shellQuote(value))
+	}
+	if len(lines) > 0 {
+		ui.Output(strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell. Within single quotes nothing is
+// special, including $, backquotes and backslashes, other than the single
+// quote itself, which ends the quoting, is escaped with a backslash, and
+// starts it again.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
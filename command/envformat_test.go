@@ -0,0 +1,62 @@
+package command
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestEnvFormatter(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"user":        "web",
+			"db.password": "it's a $ecret",
+			"greeting":    `say "hi" to $HOME and ` + "`id`",
+			"spaces":      "a  b",
+			"empty":       "",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{EnvPrefix: "app_"}
+	if code := OutputSecret(ui, "env", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	expected := `export APP_DB_PASSWORD='it'\''s a $ecret'
+export APP_EMPTY=''
+export APP_GREETING='say "hi" to $HOME and ` + "`id`" + `'
+export APP_SPACES='a  b'
+export APP_USER='web'
+`
+	output := ui.OutputWriter.String()
+	if output != expected {
+		t.Fatalf("bad output:\n%s", output)
+	}
+
+	// Lists cannot be output
+	ui = new(cli.MockUi)
+	list := &api.Secret{Data: map[string]interface{}{"keys": []interface{}{"foo"}}}
+	if code := OutputList(ui, "env", list, nil); code == 0 {
+		t.Fatal("expected an error")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	// The shell sets the variables to the values exactly
+	script := output + `printf '%s|%s|%s' "$APP_DB_PASSWORD" "$APP_GREETING" "$APP_SPACES"`
+	out, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	values := strings.Join([]string{s.Data["db.password"].(string), s.Data["greeting"].(string), "a  b"}, "|")
+	if string(out) != values {
+		t.Fatalf("bad: %s", out)
+	}
+}
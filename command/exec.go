@@ -0,0 +1,90 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// secretEnv returns the data of the secret as environment variables, in the
+// "NAME=value" form of os.Environ. Nested fields are flattened like the csv
+// format does, and the names are then uppercased with anything other than
+// letters, digits and underscores replaced by an underscore, so that
+// "db.password" becomes DB_PASSWORD. The prefix, if any, is added to the
+// names before they are normalized. For a versioned secret only the secret
+// itself is used, not its metadata.
+func secretEnv(secret *api.Secret, prefix string) []string {
+	data := secret.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"].(map[string]interface{}); ok {
+			data = inner
+		}
+	}
+
+	opts := &meta.OutputOptions{NoSanitize: true}
+	flat := make(map[string]string)
+	for k, v := range data {
+		CsvFormatter{}.flatten(k, v, flat, opts)
+	}
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, envName(prefix+k)+"="+flat[k])
+	}
+	return env
+}
+
+// envName turns the key of a field into the name of an environment variable.
+func envName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// execWithSecret runs the command with the data of the secret added to its
+// environment, given by secretEnv, and waits for it to exit. The command is
+// connected to the terminal, and its exit code is returned. The secret is
+// only passed in the environment, so it is never written to disk.
+func execWithSecret(ui cli.Ui, secret *api.Secret, command []string) int {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), secretEnv(secret, "")...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() > 0 {
+			return status.ExitStatus()
+		}
+		return 1
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error running %s: %s", command[0], err))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/meta"
+)
+
+// ttlKeyWords are the words that mark a key as holding a TTL or another
+// duration, such as max_ttl or lease_duration.
+var ttlKeyWords = []string{"ttl", "duration"}
+
+// ttlKey reports whether the value of the given key is a TTL that can be
+// shown as an expiry time with -ttl-as-expiry.
+func ttlKey(key string) bool {
+	words := strings.FieldsFunc(strings.ToLower(key), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if strutil.StrListContains(ttlKeyWords, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// ttlDuration returns the duration given by the value of a TTL field, either
+// a number of seconds or a duration string such as "768h". It returns false
+// for zero, which means there is no TTL, and for values that are not
+// durations.
+func ttlDuration(v interface{}) (time.Duration, bool) {
+	d, err := parseutil.ParseDurationSecond(v)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// ttlExpiry returns the time a TTL that starts now expires at.
+func ttlExpiry(d time.Duration) string {
+	return timeNow().Add(d).UTC().Format(time.RFC3339)
+}
+
+// addTTLExpiry returns the secret with a "<key>_expires_at" field added
+// alongside each of its TTL fields, if -ttl-as-expiry is set. The table
+// format shows the expiry in place of the TTL instead, so this is used for
+// the other formats. The secret itself is not modified.
+func addTTLExpiry(secret *api.Secret, opts *meta.OutputOptions) *api.Secret {
+	if secret == nil || opts == nil || !opts.TTLAsExpiry || len(secret.Data) == 0 {
+		return secret
+	}
+
+	s := *secret
+	s.Data = make(map[string]interface{}, len(secret.Data))
+	for k, v := range secret.Data {
+		s.Data[k] = v
+	}
+	for k, v := range secret.Data {
+		if !ttlKey(k) {
+			continue
+		}
+		if d, ok := ttlDuration(v); ok {
+			if _, exists := s.Data[k+"_expires_at"]; !exists {
+				s.Data[k+"_expires_at"] = ttlExpiry(d)
+			}
+		}
+	}
+	return &s
+}
@@ -0,0 +1,109 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestOutputSecret_ttlAsExpiry(t *testing.T) {
+	oldNow := timeNow
+	timeNow = func() time.Time { return time.Date(2018, 1, 4, 14, 30, 0, 0, time.UTC) }
+	defer func() { timeNow = oldNow }()
+
+	s := &api.Secret{
+		LeaseID:       "foo/bar/1",
+		LeaseDuration: 3600,
+		Data: map[string]interface{}{
+			"ttl":              json.Number("2764800"),
+			"max_ttl":          "768h",
+			"explicit_max_ttl": json.Number("0"),
+			"period":           json.Number("60"),
+			"name":             "web",
+		},
+	}
+	opts := &meta.OutputOptions{TTLAsExpiry: true}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "table", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	for _, expected := range []string{
+		`lease_duration\s+2018-01-04T15:30:00Z\n`,
+		`\nttl\s+2018-02-05T14:30:00Z\n`,
+		`\nmax_ttl\s+2018-02-05T14:30:00Z\n`,
+		`explicit_max_ttl\s+0\n`,
+		`period\s+60\n`,
+		`name\s+web\n`,
+	} {
+		if !regexpMatch(t, expected, out) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "expires_at") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "json", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"ttl":                float64(2764800),
+		"ttl_expires_at":     "2018-02-05T14:30:00Z",
+		"max_ttl":            "768h",
+		"max_ttl_expires_at": "2018-02-05T14:30:00Z",
+		"explicit_max_ttl":   float64(0),
+		"period":             float64(60),
+		"name":               "web",
+	}
+	if len(result.Data) != len(expected) {
+		t.Fatalf("bad: %v", result.Data)
+	}
+	for k, v := range expected {
+		if result.Data[k] != v {
+			t.Fatalf("%s: expected %v, got %v", k, v, result.Data[k])
+		}
+	}
+	if _, ok := s.Data["ttl_expires_at"]; ok {
+		t.Fatal("the secret was modified")
+	}
+
+	// Without the flag the TTLs are output as they are
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", s, &meta.OutputOptions{}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !regexpMatch(t, `\nttl\s+2764800\n`, out) ||
+		!regexpMatch(t, `lease_duration\s+1h0m0s\n`, out) {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestTTLKey(t *testing.T) {
+	cases := map[string]bool{
+		"ttl":            true,
+		"max_ttl":        true,
+		"lease_duration": true,
+		"token-ttl":      true,
+		"subtitle":       false,
+		"period":         false,
+	}
+	for key, expected := range cases {
+		if actual := ttlKey(key); actual != expected {
+			t.Fatalf("%s: expected %t", key, expected)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// An output formatter for flat output, which is easy to diff: a
+// "key=value" line for each value of the data, sorted by key, with nested
+// objects and arrays flattened into dotted keys such as "a.b" and "list.0".
+type FlatFormatter struct {
+}
+
+func (f FlatFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	var v interface{}
+	if s, ok := data.(*api.Secret); ok {
+		v = s.Data
+	} else {
+		// Anything else is flattened based on how it is encoded
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if err := jsonutil.DecodeJSON(b, &v); err != nil {
+			return err
+		}
+	}
+
+	flat := make(map[string]string)
+	f.flatten("", v, flat)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, f.text(k, opts)+"="+f.text(flat[k], opts))
+	}
+	if len(lines) > 0 {
+		ui.Output(strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// flatten adds the value to flat under the key, or each of its elements
+// under a dotted key if it is a non-empty object or array. Null, booleans
+// and numbers are rendered as in JSON, so that a null can be told apart
+// from an empty string.
+func (f FlatFormatter) flatten(key string, v interface{}, flat map[string]string) {
+	prefix := key
+	if prefix != "" {
+		prefix += "."
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			if key != "" {
+				flat[key] = "{}"
+			}
+			return
+		}
+		for k, elem := range v {
+			f.flatten(prefix+k, elem, flat)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			if key != "" {
+				flat[key] = "[]"
+			}
+			return
+		}
+		for i, elem := range v {
+			f.flatten(fmt.Sprintf("%s%d", prefix, i), elem, flat)
+		}
+	case nil:
+		flat[key] = "null"
+	case string:
+		flat[key] = v
+	default:
+		// Booleans, numbers, and values added for output such as the age
+		// of a secret are rendered as they are encoded
+		b, err := json.Marshal(v)
+		if err != nil {
+			flat[key] = fmt.Sprintf("%v", v)
+			return
+		}
+		var str string
+		if err := json.Unmarshal(b, &str); err == nil {
+			flat[key] = str
+			return
+		}
+		flat[key] = string(b)
+	}
+}
+
+// flatEscaper escapes the backslashes and line breaks of keys and values, so
+// that every value stays on its own line.
+var flatEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// text escapes s for a line of flat output. The other control characters
+// are escaped as well, unless -no-sanitize is set.
+func (f FlatFormatter) text(s string, opts *meta.OutputOptions) string {
+	s = flatEscaper.Replace(s)
+	if opts.NoSanitize {
+		return s
+	}
+	return sanitize(s)
+}
@@ -0,0 +1,78 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestFlatFormatter(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"plain":   "value",
+			"newline": "line1\nline2\\n",
+			"port":    json.Number("5432"),
+			"ratio":   json.Number("0.5"),
+			"enabled": true,
+			"null":    nil,
+			"empty":   "",
+			"nested": map[string]interface{}{
+				"a":    map[string]interface{}{"b": false},
+				"list": []interface{}{"x", map[string]interface{}{"y": "z"}},
+			},
+			"none":  []interface{}{},
+			"inner": map[string]interface{}{},
+			"esc":   "\x1b[31m",
+		},
+	}
+
+	expected := `empty=
+enabled=true
+esc=\x1b[31m
+inner={}
+nested.a.b=false
+nested.list.0=x
+nested.list.1.y=z
+newline=line1\nline2\\n
+none=[]
+null=null
+plain=value
+port=5432
+ratio=0.5
+`
+	// The output is the same every time
+	for i := 0; i < 5; i++ {
+		ui := new(cli.MockUi)
+		if code := OutputSecret(ui, "flat", s, nil); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != expected {
+			t.Fatalf("bad output:\n%s", output)
+		}
+	}
+
+	// Control characters other than line breaks are only escaped when
+	// sanitizing
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{NoSanitize: true}
+	s = &api.Secret{Data: map[string]interface{}{"esc": "\x1b[31m\r\n"}}
+	if code := OutputSecret(ui, "flat", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); output != "esc=\x1b[31m\\r\\n\n" {
+		t.Fatalf("bad output: %q", output)
+	}
+
+	// A list is flattened by index
+	ui = new(cli.MockUi)
+	list := &api.Secret{Data: map[string]interface{}{"keys": []interface{}{"foo", "bar/"}}}
+	if code := OutputList(ui, "flat", list, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); output != "0=foo\n1=bar/\n" {
+		t.Fatalf("bad output: %q", output)
+	}
+}
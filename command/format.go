@@ -10,67 +10,348 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/meta"
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 	"github.com/ryanuber/columnize"
 )
 
-var predictFormat complete.Predictor = complete.PredictSet("json", "yaml")
+var predictFormat complete.Predictor = complete.PredictSet("table", "json", "yaml", "csv", "env", "flat", "raw", "template", "qr")
 
-func OutputSecret(ui cli.Ui, format string, secret *api.Secret) int {
-	return outputWithFormat(ui, format, secret, secret)
+// OutputSecret outputs the given secret in the given format. The output
+// options may be nil, in which case the defaults are used.
+func OutputSecret(ui cli.Ui, format string, secret *api.Secret, opts *meta.OutputOptions) int {
+	if opts == nil {
+		opts = &meta.OutputOptions{}
+	}
+	secret = redactSensitive(secret, opts)
+	secret = addSecretAge(ui, secret, opts)
+	setSyslogKeys(ui, secretKeys(secret))
+	if strings.ToLower(format) != "table" {
+		secret = addTTLExpiry(secret, opts)
+	}
+
+	if opts.TemplateDir != "" || opts.OutputDir != "" {
+		return outputTemplates(ui, secret, opts)
+	}
+	if opts.JSONPath != "" {
+		return outputJSONPath(ui, secret, opts)
+	}
+
+	s, data := secret, interface{}(secret)
+	if opts.Only != "" {
+		section, sectionData, err := secretSection(secret, opts.Only)
+		if err != nil {
+			ui.Error(err.Error())
+			return 1
+		}
+		s, data = section, sectionData
+		if strings.ToLower(format) == "table" {
+			data = section
+		}
+	}
+	if opts.MergeSections {
+		merged, err := mergeSections(s)
+		if err != nil {
+			ui.Error(err.Error())
+			return 1
+		}
+		data = merged
+	}
+	if code := outputWithFormat(ui, format, s, data, opts); code != 0 {
+		return code
+	}
+
+	empty := len(secret.Data) == 0 && secret.Auth == nil && secret.WrapInfo == nil
+	return emptyResultCode(empty, opts)
+}
+
+// emptyResultCode returns the exit code for a result that was output
+// successfully, which is non-zero if it is empty and -fail-if-empty is set.
+func emptyResultCode(empty bool, opts *meta.OutputOptions) int {
+	if empty && opts != nil && opts.FailIfEmpty {
+		return 1
+	}
+	return 0
 }
 
-func OutputList(ui cli.Ui, format string, secret *api.Secret) int {
-	return outputWithFormat(ui, format, secret, secret.Data["keys"])
+// secretSection returns a copy of the secret holding only the given section
+// of it, for the table format, along with the section itself, for the other
+// formats.
+func secretSection(secret *api.Secret, name string) (*api.Secret, interface{}, error) {
+	switch name {
+	case "data":
+		if secret.Data == nil {
+			return nil, nil, errors.New("The response has no data")
+		}
+		return &api.Secret{Data: secret.Data}, secret.Data, nil
+	case "auth":
+		if secret.Auth == nil {
+			return nil, nil, errors.New("The response has no auth section")
+		}
+		return &api.Secret{Auth: secret.Auth}, secret.Auth, nil
+	case "wrap":
+		if secret.WrapInfo == nil {
+			return nil, nil, errors.New("The response has no wrap_info section")
+		}
+		return &api.Secret{WrapInfo: secret.WrapInfo}, secret.WrapInfo, nil
+	}
+	return nil, nil, fmt.Errorf("Invalid -only section %q, expected data, auth, or wrap", name)
 }
 
-func outputWithFormat(ui cli.Ui, format string, secret *api.Secret, data interface{}) int {
+// OutputList outputs the keys of a list response in the given format. The
+// output options may be nil, in which case the defaults are used.
+func OutputList(ui cli.Ui, format string, secret *api.Secret, opts *meta.OutputOptions) int {
+	if opts != nil && opts.JSONPath != "" {
+		return outputJSONPath(ui, secret, opts)
+	}
+
+	keys := secret.Data["keys"]
+	if opts != nil && (opts.SortBy != "" || opts.SortDesc) {
+		sorted, err := sortListKeys(secret, opts.SortBy, opts.SortDesc)
+		if err != nil {
+			ui.Error(err.Error())
+			return 1
+		}
+		keys = sorted
+	}
+
+	if code := outputWithFormat(ui, format, secret, keys, opts); code != 0 {
+		return code
+	}
+
+	list, _ := keys.([]interface{})
+	return emptyResultCode(len(list) == 0, opts)
+}
+
+// OutputSecrets outputs the results of reading several paths. The table
+// format prints each result in turn, preceded by a "=== path ===" header when
+// groupByPath is set, while the other formats key the results by path.
+func OutputSecrets(ui cli.Ui, format string, paths []string, secrets map[string]*api.Secret, groupByPath bool, opts *meta.OutputOptions) int {
+	if strings.ToLower(format) != "table" {
+		return outputWithFormat(ui, format, nil, secrets, opts)
+	}
+
+	// The results are signed, and their final newline left out, together
+	// rather than one by one
+	var unsigned *meta.OutputOptions
+	if opts != nil {
+		o := *opts
+		o.SignOutput = ""
+		o.NoFinalNewline = false
+		unsigned = &o
+	}
+	return outputSigned(ui, opts, func(ui cli.Ui) int {
+		for _, path := range paths {
+			secret, ok := secrets[path]
+			if !ok {
+				continue
+			}
+			if groupByPath {
+				ui.Output(fmt.Sprintf("=== %s ===", path))
+			}
+			if code := outputWithFormat(ui, format, secret, secret, unsigned); code != 0 {
+				return code
+			}
+		}
+		return 0
+	})
+}
+
+func outputWithFormat(ui cli.Ui, format string, secret *api.Secret, data interface{}, opts *meta.OutputOptions) int {
 	formatter, ok := Formatters[strings.ToLower(format)]
 	if !ok {
 		ui.Error(fmt.Sprintf("Invalid output format: %s", format))
 		return 1
 	}
-	if err := formatter.Output(ui, secret, data); err != nil {
-		ui.Error(fmt.Sprintf("Could not output secret: %s", err.Error()))
+	if opts == nil {
+		opts = &meta.OutputOptions{}
+	}
+	if opts.Template != "" && strings.ToLower(format) != "template" {
+		ui.Error("-template can only be used with -format=template")
 		return 1
 	}
-	return 0
+	if opts.Quiet {
+		return 0
+	}
+	return outputSigned(ui, opts, func(ui cli.Ui) int {
+		if err := formatter.Output(ui, secret, data, opts); err != nil {
+			ui.Error(fmt.Sprintf("Could not output secret: %s", err.Error()))
+			return 1
+		}
+		return 0
+	})
 }
 
 type Formatter interface {
-	Output(ui cli.Ui, secret *api.Secret, data interface{}) error
+	Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error
 }
 
 var Formatters = map[string]Formatter{
-	"json":  JsonFormatter{},
-	"table": TableFormatter{},
-	"yaml":  YamlFormatter{},
-	"yml":   YamlFormatter{},
+	"csv":      CsvFormatter{},
+	"env":      EnvFormatter{},
+	"flat":     FlatFormatter{},
+	"json":     JsonFormatter{},
+	"qr":       QRFormatter{},
+	"raw":      RawFormatter{},
+	"table":    TableFormatter{},
+	"template": TemplateFormatter{},
+	"yaml":     YamlFormatter{},
+	"yml":      YamlFormatter{},
 }
 
 // An output formatter for json output of an object
 type JsonFormatter struct {
 }
 
-func (j JsonFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}) error {
+func (j JsonFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	if opts.AvroSchema != "" {
+		schema, err := loadAvroSchema(opts.AvroSchema)
+		if err != nil {
+			return err
+		}
+		if s, ok := data.(*api.Secret); ok {
+			data = s.Data
+		}
+		data, err = schema.encode(data, opts.AvroStrict)
+		if err != nil {
+			return fmt.Errorf("output does not match the Avro schema: %s", err)
+		}
+	} else if opts.TypedJSON {
+		data = annotateTypes(data)
+	} else if _, ok := data.(*api.Secret); ok && opts.KeyOrder != nil {
+		ordered, err := withKeyOrder(data, opts.KeyOrder)
+		if err != nil {
+			return err
+		}
+		data = ordered
+	}
+
+	color, err := jsonColor(ui, opts)
+	if err != nil {
+		return err
+	}
+
+	// Output to a terminal is indented with two spaces, for reading, while
+	// anything else is compact, for other programs
+	indent := ""
+	switch {
+	case opts.JSONIndentTab:
+		indent = "\t"
+	case uiIsTerminal(ui):
+		indent = "  "
+	}
+	if opts.JSONIndent != nil {
+		if opts.JSONIndentTab {
+			return errors.New("-json-indent and -json-indent-tab cannot be used together")
+		}
+		if *opts.JSONIndent < 0 {
+			return fmt.Errorf("invalid JSON indent %d", *opts.JSONIndent)
+		}
+		indent = strings.Repeat(" ", *opts.JSONIndent)
+	}
+
 	b, err := json.Marshal(data)
-	if err == nil {
+	if err != nil {
+		return err
+	}
+	if indent != "" {
 		var out bytes.Buffer
-		json.Indent(&out, b, "", "\t")
-		ui.Output(out.String())
+		json.Indent(&out, b, "", indent)
+		b = out.Bytes()
 	}
-	return err
+	if color {
+		ui.Output(colorizeJSON(b))
+	} else {
+		ui.Output(string(b))
+	}
+	return nil
+}
+
+// typedValue is a value annotated with its JSON type, as output with
+// -typed-json.
+type typedValue struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// annotateTypes annotates the data to be output with -typed-json. For a
+// secret the values of its data are annotated, while anything else is
+// annotated as a whole.
+func annotateTypes(data interface{}) interface{} {
+	s, ok := data.(*api.Secret)
+	if !ok {
+		return annotateValue(data)
+	}
+	if s == nil || s.Data == nil {
+		return s
+	}
+
+	typed := *s
+	typed.Data = make(map[string]interface{}, len(s.Data))
+	for k, v := range s.Data {
+		typed.Data[k] = annotateValue(v)
+	}
+	return &typed
+}
+
+// annotateValue wraps the value, and recursively the values nested within
+// it, with its JSON type.
+func annotateValue(v interface{}) typedValue {
+	switch v := v.(type) {
+	case nil:
+		return typedValue{Type: "null"}
+	case string:
+		return typedValue{Type: "string", Value: v}
+	case bool:
+		return typedValue{Type: "boolean", Value: v}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return typedValue{Type: "integer", Value: v}
+		}
+		return typedValue{Type: "number", Value: v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return typedValue{Type: "integer", Value: v}
+	case float32, float64:
+		return typedValue{Type: "number", Value: v}
+	case []interface{}:
+		values := make([]typedValue, 0, len(v))
+		for _, elem := range v {
+			values = append(values, annotateValue(elem))
+		}
+		return typedValue{Type: "array", Value: values}
+	case map[string]interface{}:
+		values := make(map[string]typedValue, len(v))
+		for k, elem := range v {
+			values[k] = annotateValue(elem)
+		}
+		return typedValue{Type: "object", Value: values}
+	}
+
+	// Anything else is annotated based on how it is encoded
+	b, err := json.Marshal(v)
+	if err != nil {
+		return typedValue{Type: "unknown", Value: v}
+	}
+	var decoded interface{}
+	if err := jsonutil.DecodeJSON(b, &decoded); err != nil {
+		return typedValue{Type: "unknown", Value: v}
+	}
+	return annotateValue(decoded)
 }
 
 // An output formatter for yaml output format of an object
 type YamlFormatter struct {
 }
 
-func (y YamlFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}) error {
+func (y YamlFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
 	b, err := yaml.Marshal(data)
 	if err == nil {
 		ui.Output(strings.TrimSpace(string(b)))
@@ -82,18 +363,36 @@ func (y YamlFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}) e
 type TableFormatter struct {
 }
 
-func (t TableFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}) error {
+func (t TableFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	if opts.HumanizeBytes && opts.BytesBase != 2 && opts.BytesBase != 10 {
+		return fmt.Errorf("invalid bytes base %d, expected 2 or 10", opts.BytesBase)
+	}
+
 	// TODO: this should really use reflection like the other formatters do
 	if s, ok := data.(*api.Secret); ok {
-		return t.OutputSecret(ui, secret, s)
+		return t.OutputSecret(ui, secret, s, opts)
 	}
 	if s, ok := data.([]interface{}); ok {
-		return t.OutputList(ui, secret, s)
+		return t.OutputList(ui, secret, s, opts)
+	}
+	if d, ok := data.(*secretDiff); ok {
+		return t.OutputDiff(ui, d, opts)
+	}
+	if o, ok := data.(responseObject); ok {
+		return t.OutputSections(ui, o, opts)
 	}
 	return errors.New("Cannot use the table formatter for this type")
 }
 
-func (t TableFormatter) OutputList(ui cli.Ui, secret *api.Secret, list []interface{}) error {
+func (t TableFormatter) OutputList(ui cli.Ui, secret *api.Secret, list []interface{}, opts *meta.OutputOptions) error {
+	switch opts.ListFormat {
+	case "", "table":
+	case "plain", "tree", "columns":
+		return t.outputListKeys(ui, secret, list, opts)
+	default:
+		return fmt.Errorf("invalid list format %q", opts.ListFormat)
+	}
+
 	config := columnize.DefaultConfig()
 	config.Delim = "♨"
 	config.Glue = "\t"
@@ -102,17 +401,62 @@ func (t TableFormatter) OutputList(ui cli.Ui, secret *api.Secret, list []interfa
 	input := make([]string, 0, 5)
 
 	if len(list) > 0 {
-		input = append(input, "Keys")
-		input = append(input, "----")
+		// Keys with metadata get a column for each field of it
+		columns, _ := listRows(secret)
+		columns = columns[1:]
+		info, _ := secret.Data["key_info"].(map[string]interface{})
+		if opts.HideEmptyColumns {
+			columns = nonEmptyColumns(columns, list, info)
+		}
+
+		header := []string{"Key"}
+		for _, c := range columns {
+			header = append(header, t.formatText(c, opts))
+		}
+		if !opts.Transpose {
+			if len(columns) == 0 {
+				input = append(input, "Keys")
+				input = append(input, "----")
+			} else {
+				separator := []string{"---"}
+				for _, c := range columns {
+					separator = append(separator, strings.Repeat("-", len(c)))
+				}
+				input = append(input, strings.Join(header, config.Delim))
+				input = append(input, strings.Join(separator, config.Delim))
+			}
+		}
 
 		keys := make([]string, 0, len(list))
 		for _, k := range list {
 			keys = append(keys, k.(string))
 		}
-		sort.Strings(keys)
+		// Unless sorted with -sort-by or -sort-desc, keys are sorted by name
+		if opts.SortBy == "" && !opts.SortDesc {
+			sort.Strings(keys)
+		}
 
+		rows := make([][]string, 0, len(keys))
 		for _, k := range keys {
-			input = append(input, fmt.Sprintf("%s", k))
+			cells := []string{t.formatText(k, opts) + warningRefs(secret.Warnings, k, opts)}
+			fields, _ := info[k].(map[string]interface{})
+			for _, c := range columns {
+				v, ok := fields[c]
+				if !ok {
+					cells = append(cells, "")
+					continue
+				}
+				cells = append(cells, t.formatText(t.formatValue(c, v, opts), opts))
+			}
+			rows = append(rows, cells)
+		}
+
+		if opts.Transpose {
+			input = append(input, transposeRows(header, rows, config.Delim)...)
+		} else {
+			for _, cells := range rows {
+				input = append(input, strings.Join(cells, config.Delim))
+			}
 		}
 	}
 
@@ -121,14 +465,7 @@ func (t TableFormatter) OutputList(ui cli.Ui, secret *api.Secret, list []interfa
 	// Print the warning separately because the length of first
 	// column in the output will be increased by the length of
 	// the longest warning string making the output look bad.
-	warningsInput := make([]string, 0, 5)
-	if len(secret.Warnings) != 0 {
-		warningsInput = append(warningsInput, "")
-		warningsInput = append(warningsInput, "The following warnings were returned from the Vault server:")
-		for _, warning := range secret.Warnings {
-			warningsInput = append(warningsInput, fmt.Sprintf("* %s", warning))
-		}
-	}
+	warningsInput := t.warningLines(secret.Warnings, opts)
 
 	warningsOutputStr := columnize.Format(warningsInput, config)
 
@@ -137,13 +474,106 @@ func (t TableFormatter) OutputList(ui cli.Ui, secret *api.Secret, list []interfa
 	return nil
 }
 
-func (t TableFormatter) OutputSecret(ui cli.Ui, secret, s *api.Secret) error {
+// outputListKeys outputs just the keys of a list, without a header or their
+// metadata, in the layout given by -list-format.
+func (t TableFormatter) outputListKeys(ui cli.Ui, secret *api.Secret, list []interface{}, opts *meta.OutputOptions) error {
+	keys := make([]string, 0, len(list))
+	for _, k := range list {
+		keys = append(keys, t.formatText(k.(string), opts))
+	}
+	// Unless sorted with -sort-by or -sort-desc, keys are sorted by name
+	if opts.SortBy == "" && !opts.SortDesc {
+		sort.Strings(keys)
+	}
+
+	var lines []string
+	switch opts.ListFormat {
+	case "plain":
+		lines = keys
+	case "tree":
+		lines = listTree(keys)
+	case "columns":
+		lines = listColumns(keys, terminalWidth())
+	}
+	if len(lines) > 0 {
+		ui.Output(strings.Join(lines, "\n"))
+	}
+
+	if warnings := t.warningLines(secret.Warnings, opts); len(warnings) > 0 {
+		ui.Output(strings.Join(warnings, "\n"))
+	}
+	return nil
+}
+
+// OutputDiff outputs the difference between two versions of a secret, one
+// row per added, removed or changed key.
+func (t TableFormatter) OutputDiff(ui cli.Ui, diff *secretDiff, opts *meta.OutputOptions) error {
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+		ui.Output("No differences")
+		return nil
+	}
+
+	config := columnize.DefaultConfig()
+	config.Delim = "♨"
+	config.Glue = "\t"
+	config.Prefix = ""
+
+	header := []string{"Change", "Key", "Value"}
+	var rows [][]string
+
+	keys := make([]string, 0, len(diff.Added)+len(diff.Removed)+len(diff.Changed))
+	for k := range diff.Added {
+		keys = append(keys, k)
+	}
+	for k := range diff.Removed {
+		keys = append(keys, k)
+	}
+	for k := range diff.Changed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var change, value string
+		if v, ok := diff.Added[k]; ok {
+			change, value = "added", t.formatValue(k, v, opts)
+		} else if v, ok := diff.Removed[k]; ok {
+			change, value = "removed", t.formatValue(k, v, opts)
+		} else {
+			c := diff.Changed[k]
+			change = "changed"
+			value = fmt.Sprintf("%s -> %s",
+				t.formatValue(k, c.Old, opts), t.formatValue(k, c.New, opts))
+		}
+		rows = append(rows, []string{change, t.formatText(k, opts), t.formatText(value, opts)})
+	}
+
+	var input []string
+	if opts.Transpose {
+		input = transposeRows(header, rows, config.Delim)
+	} else {
+		input = []string{"Change♨Key♨Value", "------♨---♨-----"}
+		for _, cells := range rows {
+			input = append(input, strings.Join(cells, config.Delim))
+		}
+	}
+
+	ui.Output(columnize.Format(input, config))
+	return nil
+}
+
+func (t TableFormatter) OutputSecret(ui cli.Ui, secret, s *api.Secret, opts *meta.OutputOptions) error {
+	if _, ok := boolStyles[opts.BoolStyle]; !ok {
+		return fmt.Errorf("invalid bool style %q", opts.BoolStyle)
+	}
+
 	config := columnize.DefaultConfig()
 	config.Delim = "♨"
 	config.Glue = "\t"
 	config.Prefix = ""
 
 	input := make([]string, 0, 5)
+	var truncated []tableRow
 
 	onceHeader := &sync.Once{}
 	headerFunc := func() {
@@ -154,68 +584,98 @@ func (t TableFormatter) OutputSecret(ui cli.Ui, secret, s *api.Secret) error {
 	if s.LeaseDuration > 0 {
 		onceHeader.Do(headerFunc)
 		if s.LeaseID != "" {
-			input = append(input, fmt.Sprintf("lease_id %s %s", config.Delim, s.LeaseID))
+			input = append(input, fmt.Sprintf("lease_id %s %s", config.Delim, t.formatText(s.LeaseID, opts)))
 			input = append(input, fmt.Sprintf(
-				"lease_duration %s %s", config.Delim, (time.Second*time.Duration(s.LeaseDuration)).String()))
+				"lease_duration %s %s", config.Delim, t.formatSeconds(s.LeaseDuration, opts)))
 		} else {
 			input = append(input, fmt.Sprintf(
 				"refresh_interval %s %s", config.Delim, (time.Second*time.Duration(s.LeaseDuration)).String()))
 		}
 		if s.LeaseID != "" {
 			input = append(input, fmt.Sprintf(
-				"lease_renewable %s %s", config.Delim, strconv.FormatBool(s.Renewable)))
+				"lease_renewable %s %s", config.Delim, t.formatValue("lease_renewable", s.Renewable, opts)))
 		}
 	}
 
 	if s.Auth != nil {
 		onceHeader.Do(headerFunc)
-		input = append(input, fmt.Sprintf("token %s %s", config.Delim, s.Auth.ClientToken))
-		input = append(input, fmt.Sprintf("token_accessor %s %s", config.Delim, s.Auth.Accessor))
-		input = append(input, fmt.Sprintf("token_duration %s %s", config.Delim, (time.Second*time.Duration(s.Auth.LeaseDuration)).String()))
-		input = append(input, fmt.Sprintf("token_renewable %s %v", config.Delim, s.Auth.Renewable))
-		input = append(input, fmt.Sprintf("token_policies %s %v", config.Delim, s.Auth.Policies))
+		input = append(input, fmt.Sprintf("token %s %s", config.Delim, t.formatText(s.Auth.ClientToken, opts)))
+		input = append(input, fmt.Sprintf("token_accessor %s %s", config.Delim, t.formatText(s.Auth.Accessor, opts)))
+		input = append(input, fmt.Sprintf("token_duration %s %s", config.Delim, t.formatSeconds(s.Auth.LeaseDuration, opts)))
+		input = append(input, fmt.Sprintf("token_renewable %s %s", config.Delim, t.formatValue("token_renewable", s.Auth.Renewable, opts)))
+		input = append(input, fmt.Sprintf("token_policies %s %s", config.Delim, t.formatText(fmt.Sprintf("%v", s.Auth.Policies), opts)))
 		for k, v := range s.Auth.Metadata {
-			input = append(input, fmt.Sprintf("token_meta_%s %s %#v", k, config.Delim, v))
+			input = append(input, fmt.Sprintf("%s %s %s", t.formatText("token_meta_"+k, opts), config.Delim, t.formatText(fmt.Sprintf("%#v", v), opts)))
 		}
 	}
 
 	if s.WrapInfo != nil {
 		onceHeader.Do(headerFunc)
-		input = append(input, fmt.Sprintf("wrapping_token: %s %s", config.Delim, s.WrapInfo.Token))
+		input = append(input, fmt.Sprintf("wrapping_token: %s %s", config.Delim, t.formatText(s.WrapInfo.Token, opts)))
 		input = append(input, fmt.Sprintf("wrapping_token_ttl: %s %s", config.Delim, (time.Second*time.Duration(s.WrapInfo.TTL)).String()))
 		input = append(input, fmt.Sprintf("wrapping_token_creation_time: %s %s", config.Delim, s.WrapInfo.CreationTime.String()))
-		input = append(input, fmt.Sprintf("wrapping_token_creation_path: %s %s", config.Delim, s.WrapInfo.CreationPath))
+		input = append(input, fmt.Sprintf("wrapping_token_creation_path: %s %s", config.Delim, t.formatText(s.WrapInfo.CreationPath, opts)))
 		if s.WrapInfo.WrappedAccessor != "" {
-			input = append(input, fmt.Sprintf("wrapped_accessor: %s %s", config.Delim, s.WrapInfo.WrappedAccessor))
+			input = append(input, fmt.Sprintf("wrapped_accessor: %s %s", config.Delim, t.formatText(s.WrapInfo.WrappedAccessor, opts)))
 		}
 	}
 
 	if s.Data != nil && len(s.Data) > 0 {
 		onceHeader.Do(headerFunc)
 		keys := make([]string, 0, len(s.Data))
-		for k := range s.Data {
+		for k, v := range s.Data {
+			if opts.HideEmptyColumns && isEmptyValue(v) {
+				continue
+			}
 			keys = append(keys, k)
 		}
-		sort.Strings(keys)
+		if opts.KeyOrder != nil {
+			keys = orderedKeys(keys, opts.KeyOrder["data"])
+		} else {
+			sort.Strings(keys)
+		}
 
+		var rows []tableRow
 		for _, k := range keys {
-			input = append(input, fmt.Sprintf("%s %s %v", k, config.Delim, s.Data[k]))
+			keyRows, err := t.dataRows(k, s.Data[k], false, opts)
+			if err != nil {
+				return err
+			}
+			if len(keyRows) > 0 {
+				keyRows[0].note = warningRefs(s.Warnings, k, opts)
+			}
+			rows = append(rows, keyRows...)
+		}
+
+		for _, r := range rows {
+			value := r.value
+			if opts.MaxColWidth > 0 {
+				if short, ok := truncate(value, opts.MaxColWidth); ok {
+					value = short
+					truncated = append(truncated, r)
+				}
+			}
+			input = append(input, fmt.Sprintf("%s %s %s%s", r.key, config.Delim, value, r.note))
 		}
 	}
 
 	tableOutputStr := columnize.Format(input, config)
 
+	// Print the full values of any truncated cells below the table if
+	// requested, so they are still available.
+	if opts.ShowTruncated && len(truncated) > 0 {
+		full := make([]string, 0, len(truncated)+2)
+		full = append(full, "", "Truncated values:")
+		for _, r := range truncated {
+			full = append(full, fmt.Sprintf("%s: %s", r.key, r.value))
+		}
+		tableOutputStr += "\n" + strings.Join(full, "\n")
+	}
+
 	// Print the warning separately because the length of first
 	// column in the output will be increased by the length of
 	// the longest warning string making the output look bad.
-	warningsInput := make([]string, 0, 5)
-	if len(s.Warnings) != 0 {
-		warningsInput = append(warningsInput, "")
-		warningsInput = append(warningsInput, "The following warnings were returned from the Vault server:")
-		for _, warning := range s.Warnings {
-			warningsInput = append(warningsInput, fmt.Sprintf("* %s", warning))
-		}
-	}
+	warningsInput := t.warningLines(s.Warnings, opts)
 
 	warningsOutputStr := columnize.Format(warningsInput, config)
 
@@ -223,3 +683,371 @@ func (t TableFormatter) OutputSecret(ui cli.Ui, secret, s *api.Secret) error {
 
 	return nil
 }
+
+// nonEmptyColumns returns the metadata columns of a list that have a value
+// for at least one of the keys.
+func nonEmptyColumns(columns []string, list []interface{}, info map[string]interface{}) []string {
+	result := make([]string, 0, len(columns))
+	for _, c := range columns {
+		for _, k := range list {
+			k, _ := k.(string)
+			fields, _ := info[k].(map[string]interface{})
+			if v, ok := fields[c]; ok && !isEmptyValue(v) {
+				result = append(result, c)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// isEmptyValue reports whether v is null, an empty string, or an empty array
+// or object.
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// transposeRows returns the lines of a table in which each row is turned
+// into a block with one line per column, giving its name and value. Blocks
+// are separated by a "---" divider. This reads better than a wide table on
+// narrow terminals.
+func transposeRows(header []string, rows [][]string, delim string) []string {
+	lines := make([]string, 0, len(rows)*(len(header)+1))
+	for i, cells := range rows {
+		if i > 0 {
+			lines = append(lines, "---")
+		}
+		for j, name := range header {
+			lines = append(lines, name+delim+cells[j])
+		}
+	}
+	return lines
+}
+
+// tableRow is a single key/value row of a table of secret data.
+type tableRow struct {
+	key   string
+	value string
+
+	// note follows the value, such as the footnote markers of the
+	// warnings that mention the key.
+	note string
+}
+
+// dataRows returns the table rows for a single data field. With the indexed
+// array style every element of an array gets its own row, and objects found
+// within arrays are expanded into one row per field.
+func (t TableFormatter) dataRows(key string, v interface{}, inArray bool, opts *meta.OutputOptions) ([]tableRow, error) {
+	switch opts.ArrayStyle {
+	case "", "indexed":
+	case "joined":
+		if list, ok := v.([]interface{}); ok {
+			value, err := t.joinArray(key, list, opts)
+			if err != nil {
+				return nil, err
+			}
+			return []tableRow{t.row(key, value, opts)}, nil
+		}
+		return []tableRow{t.row(key, t.formatValue(key, v, opts), opts)}, nil
+	default:
+		return nil, fmt.Errorf("invalid array style %q", opts.ArrayStyle)
+	}
+
+	var rows []tableRow
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return []tableRow{t.row(key, "[]", opts)}, nil
+		}
+		for i, elem := range v {
+			elemRows, err := t.dataRows(fmt.Sprintf("%s[%d]", key, i), elem, true, opts)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, elemRows...)
+		}
+	case map[string]interface{}:
+		if !inArray || len(v) == 0 {
+			return []tableRow{t.row(key, t.formatValue(key, v, opts), opts)}, nil
+		}
+		fields := make([]string, 0, len(v))
+		for field := range v {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fieldRows, err := t.dataRows(key+"."+field, v[field], true, opts)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, fieldRows...)
+		}
+	default:
+		rows = append(rows, t.row(key, t.formatValue(key, v, opts), opts))
+	}
+	return rows, nil
+}
+
+// row returns a row of a table, preparing its text for display.
+func (t TableFormatter) row(key, value string, opts *meta.OutputOptions) tableRow {
+	return tableRow{
+		key:   t.formatText(key, opts),
+		value: t.formatText(value, opts),
+	}
+}
+
+// breaksLayout returns whether s contains whitespace that would break the
+// alignment of a table or be hard to tell apart from it.
+func breaksLayout(s string) bool {
+	return strings.ContainsAny(s, "\t\n\r") ||
+		strings.Contains(s, "  ") ||
+		strings.TrimSpace(s) != s
+}
+
+// formatValue renders the value of the given key for display in a table
+// cell. Null and empty values are rendered as "<null>" and "<empty>" so they
+// can be told apart, and whitespace-only strings are quoted so they remain
+// visible. With -quote-values, strings that would break the layout of the
+// table are quoted as well. Booleans are rendered in the -bool-style, and
+// TTLs as their expiry time with -ttl-as-expiry.
+func (t TableFormatter) formatValue(key string, v interface{}, opts *meta.OutputOptions) string {
+	if opts.TTLAsExpiry && ttlKey(key) {
+		if d, ok := ttlDuration(v); ok {
+			return ttlExpiry(d)
+		}
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "<null>"
+	case bool:
+		if style, ok := boolStyles[opts.BoolStyle]; ok {
+			if v {
+				return style[0]
+			}
+			return style[1]
+		}
+	case json.Number:
+		if opts.HumanizeBytes && byteSizeKey(key) {
+			if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+				return humanizeBytes(n, opts.BytesBase)
+			}
+		}
+		if opts.ThousandsSep != "" && groupableKey(key, opts.NoSepKeys) {
+			if _, err := v.Int64(); err == nil {
+				return groupThousands(v.String(), opts.ThousandsSep)
+			}
+		}
+	case int, int64, uint64:
+		if opts.HumanizeBytes && byteSizeKey(key) {
+			if n, err := strconv.ParseUint(fmt.Sprintf("%d", v), 10, 64); err == nil {
+				return humanizeBytes(n, opts.BytesBase)
+			}
+		}
+		if opts.ThousandsSep != "" && groupableKey(key, opts.NoSepKeys) {
+			return groupThousands(fmt.Sprintf("%d", v), opts.ThousandsSep)
+		}
+	case string:
+		switch {
+		case v == "":
+			return "<empty>"
+		case !opts.NoSanitize && isBinary(v):
+			return binaryPlaceholder(v, opts.BinaryPreview)
+		case strings.TrimSpace(v) == "":
+			return strconv.Quote(v)
+		case opts.QuoteValues && breaksLayout(v):
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// formatSeconds renders the duration of a lease or token, given in seconds,
+// or its expiry time with -ttl-as-expiry.
+func (t TableFormatter) formatSeconds(seconds int, opts *meta.OutputOptions) string {
+	d := time.Second * time.Duration(seconds)
+	if opts.TTLAsExpiry && d > 0 {
+		return ttlExpiry(d)
+	}
+	return d.String()
+}
+
+// boolStyles are the renderings of true and false for each -bool-style.
+var boolStyles = map[string][2]string{
+	"":           {"true", "false"},
+	"true-false": {"true", "false"},
+	"yes-no":     {"yes", "no"},
+	"on-off":     {"on", "off"},
+	"1-0":        {"1", "0"},
+}
+
+// joinArray renders the elements of an array as a single comma-separated
+// value. Elements that are themselves arrays or objects are rendered as JSON.
+func (t TableFormatter) joinArray(key string, list []interface{}, opts *meta.OutputOptions) (string, error) {
+	elems := make([]string, 0, len(list))
+	for _, elem := range list {
+		switch elem.(type) {
+		case []interface{}, map[string]interface{}:
+			b, err := json.Marshal(elem)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, string(b))
+		default:
+			elems = append(elems, t.formatValue(key, elem, opts))
+		}
+	}
+	return strings.Join(elems, ","), nil
+}
+
+// formatText prepares text received from the server for display in a table.
+func (t TableFormatter) formatText(s string, opts *meta.OutputOptions) string {
+	if opts.NoSanitize {
+		return s
+	}
+	return sanitize(s)
+}
+
+// sanitize escapes the non-printable control characters in s, other than
+// tabs and newlines, so that printing it cannot manipulate the terminal, for
+// example through ANSI escape sequences.
+func sanitize(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		if r != '\t' && r != '\n' && unicode.IsControl(r) {
+			fmt.Fprintf(&buf, "\\x%02x", r)
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// isBinary reports whether s holds binary data rather than text: it is not
+// valid UTF-8, or has NUL bytes or the replacement character that JSON
+// decoding puts in place of invalid UTF-8.
+func isBinary(s string) bool {
+	return !utf8.ValidString(s) || strings.ContainsRune(s, 0) || strings.ContainsRune(s, utf8.RuneError)
+}
+
+// binaryPlaceholder returns what table output shows in place of a binary
+// value: its size and the hex of up to preview of its first bytes.
+func binaryPlaceholder(s string, preview int) string {
+	if preview <= 0 {
+		return fmt.Sprintf("<binary: %d bytes>", len(s))
+	}
+	n := preview
+	if n > len(s) {
+		n = len(s)
+	}
+	hex := make([]string, 0, n+1)
+	for i := 0; i < n; i++ {
+		hex = append(hex, fmt.Sprintf("%02x", s[i]))
+	}
+	if n < len(s) {
+		hex = append(hex, "...")
+	}
+	return fmt.Sprintf("<binary: %d bytes, %s>", len(s), strings.Join(hex, " "))
+}
+
+// truncate shortens s to at most width characters, replacing the end with an
+// ellipsis if it does not fit. It reports whether s was shortened. Truncation
+// happens on rune boundaries so that multibyte characters are never split.
+func truncate(s string, width int) (string, bool) {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s, false
+	}
+	return string(runes[:width-1]) + "…", true
+}
+
+// identifierKeyWords are the words that mark a key as holding an identifier
+// rather than a quantity. The values of such keys are never grouped.
+var identifierKeyWords = []string{"id", "version", "serial", "port", "nonce"}
+
+// groupableKey reports whether the numeric value of the given key may be
+// grouped into thousands. Keys that look like identifiers and the keys
+// listed in noSepKeys are excluded.
+func groupableKey(key string, noSepKeys []string) bool {
+	if strutil.StrListContains(noSepKeys, key) {
+		return false
+	}
+	words := strings.FieldsFunc(strings.ToLower(key), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if strutil.StrListContains(identifierKeyWords, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// byteSizeKeyWords are the words that mark a key as holding a size in bytes,
+// such as max_lease_bytes or size.
+var byteSizeKeyWords = []string{"bytes", "size"}
+
+// byteSizeKey reports whether the numeric value of the given key is a size
+// in bytes that can be humanized with -humanize-bytes.
+func byteSizeKey(key string) bool {
+	words := strings.FieldsFunc(strings.ToLower(key), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if strutil.StrListContains(byteSizeKeyWords, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// humanizeBytes renders a size in bytes with binary prefixes, such as
+// "1.5 MiB", if base is 2, or decimal prefixes, such as "1.5 MB", if it is 10.
+func humanizeBytes(n uint64, base int) string {
+	step, units := 1024.0, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	if base == 10 {
+		step, units = 1000.0, []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	}
+	if float64(n) < step {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	size := float64(n)
+	unit := 0
+	for size >= step && unit < len(units)-1 {
+		size /= step
+		unit++
+	}
+	value := strings.TrimSuffix(strconv.FormatFloat(size, 'f', 1, 64), ".0")
+	return value + " " + units[unit]
+}
+
+// groupThousands inserts sep between every group of three digits of the
+// given integer, e.g. "1234567" becomes "1,234,567".
+func groupThousands(digits, sep string) string {
+	var sign string
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(sign)
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			buf.WriteString(sep)
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
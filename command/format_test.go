@@ -1,12 +1,19 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
 )
 
 var output string
@@ -39,7 +46,7 @@ func (m mockUi) Warn(s string) {
 
 func TestJsonFormatter(t *testing.T) {
 	ui := mockUi{t: t, SampleData: "something"}
-	if err := outputWithFormat(ui, "json", nil, ui); err != 0 {
+	if err := outputWithFormat(ui, "json", nil, ui, nil); err != 0 {
 		t.Fatal(err)
 	}
 	var newUi mockUi
@@ -55,7 +62,7 @@ func TestJsonFormatter(t *testing.T) {
 
 func TestYamlFormatter(t *testing.T) {
 	ui := mockUi{t: t, SampleData: "something"}
-	if err := outputWithFormat(ui, "yaml", nil, ui); err != 0 {
+	if err := outputWithFormat(ui, "yaml", nil, ui, nil); err != 0 {
 		t.Fatal(err)
 	}
 	var newUi mockUi
@@ -73,10 +80,1059 @@ func TestYamlFormatter(t *testing.T) {
 func TestTableFormatter(t *testing.T) {
 	ui := mockUi{t: t}
 	s := api.Secret{Data: map[string]interface{}{"k": "something"}}
-	if err := outputWithFormat(ui, "table", &s, &s); err != 0 {
+	if err := outputWithFormat(ui, "table", &s, &s, nil); err != 0 {
 		t.Fatal(err)
 	}
 	if !strings.Contains(output, "something") {
 		t.Fatal("did not find 'something'")
 	}
 }
+
+func TestOutputSecrets_groupByPath(t *testing.T) {
+	paths := []string{"secret/a", "secret/b"}
+	secrets := map[string]*api.Secret{
+		"secret/a": &api.Secret{Data: map[string]interface{}{"k": "first"}},
+		"secret/b": &api.Secret{Data: map[string]interface{}{"k": "second"}},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecrets(ui, "table", paths, secrets, true, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	headerA := strings.Index(out, "=== secret/a ===")
+	headerB := strings.Index(out, "=== secret/b ===")
+	if headerA == -1 || headerB == -1 {
+		t.Fatalf("missing headers:\n%s", out)
+	}
+	if !(headerA < strings.Index(out, "first") &&
+		strings.Index(out, "first") < headerB &&
+		headerB < strings.Index(out, "second")) {
+		t.Fatalf("results not grouped under their headers:\n%s", out)
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputSecrets(ui, "table", paths, secrets, false, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if strings.Contains(ui.OutputWriter.String(), "===") {
+		t.Fatalf("unexpected headers:\n%s", ui.OutputWriter.String())
+	}
+}
+
+func TestOutputSecrets_json(t *testing.T) {
+	paths := []string{"secret/a", "secret/b"}
+	secrets := map[string]*api.Secret{
+		"secret/a": &api.Secret{Data: map[string]interface{}{"k": "first"}},
+		"secret/b": &api.Secret{Data: map[string]interface{}{"k": "second"}},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecrets(ui, "json", paths, secrets, true, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var result map[string]*api.Secret
+	if err := jsonutil.DecodeJSON(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["secret/b"] == nil || result["secret/b"].Data["k"] != "second" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestTableFormatter_sanitize(t *testing.T) {
+	value := "before\x1b[2J\x1b]0;pwned\x07after"
+	s := api.Secret{
+		Data:     map[string]interface{}{"k": value},
+		Warnings: []string{"warning\x1b[31m"},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "table", &s, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if strings.ContainsRune(out, '\x1b') || strings.ContainsRune(out, '\x07') {
+		t.Fatalf("control characters were not escaped: %q", out)
+	}
+	if !strings.Contains(out, `before\x1b[2J\x1b]0;pwned\x07after`) {
+		t.Fatalf("bad: %q", out)
+	}
+	if !strings.Contains(out, `warning\x1b[31m`) {
+		t.Fatalf("bad: %q", out)
+	}
+
+	ui = new(cli.MockUi)
+	if code := PrintRawField(ui, &s, "k", nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if strings.ContainsRune(ui.OutputWriter.String(), '\x1b') {
+		t.Fatalf("control characters were not escaped: %q", ui.OutputWriter.String())
+	}
+
+	ui = new(cli.MockUi)
+	opts := &meta.OutputOptions{NoSanitize: true}
+	if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), value) {
+		t.Fatalf("value was modified: %q", ui.OutputWriter.String())
+	}
+}
+
+func TestTableFormatter_sanitizeSections(t *testing.T) {
+	s := api.Secret{
+		LeaseID:       "lease\x1b[2J",
+		LeaseDuration: 60,
+		Auth: &api.SecretAuth{
+			ClientToken: "token\x1b[2J",
+			Accessor:    "accessor\x1b[2J",
+			Policies:    []string{"root\x1b[31m"},
+			Metadata:    map[string]string{"key\x1b[31m": "value\x1b[2J"},
+		},
+		WrapInfo: &api.SecretWrapInfo{
+			Token:           "wrap\x1b[2J",
+			CreationPath:    "path\x1b[2J",
+			WrappedAccessor: "wrapped\x1b[2J",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "table", &s, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if strings.ContainsRune(out, '\x1b') {
+		t.Fatalf("control characters were not escaped: %q", out)
+	}
+	for _, expected := range []string{
+		`lease\x1b[2J`,
+		`token\x1b[2J`,
+		`accessor\x1b[2J`,
+		`[root\x1b[31m]`,
+		`token_meta_key\x1b[31m`,
+		`wrap\x1b[2J`,
+		`path\x1b[2J`,
+		`wrapped\x1b[2J`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in %q", expected, out)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	cases := map[string]string{
+		"plain":           "plain",
+		"tab\tnew\nline":  "tab\tnew\nline",
+		"\x1b[0m":         `\x1b[0m`,
+		"cr\r":            `cr\x0d`,
+		"c1\u009b31m":     `c1\x9b31m`,
+		"unicode ✓ value": "unicode ✓ value",
+	}
+	for in, expected := range cases {
+		if actual := sanitize(in); actual != expected {
+			t.Fatalf("%q: expected %q, got %q", in, expected, actual)
+		}
+	}
+}
+
+func TestTableFormatter_arrayStyle(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"hosts": []interface{}{"a.example.com", "b.example.com"},
+			"users": []interface{}{
+				map[string]interface{}{"name": "alice", "roles": []interface{}{"admin"}},
+				map[string]interface{}{"name": "bob"},
+			},
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "table", &s, &meta.OutputOptions{ArrayStyle: "indexed"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	for _, expected := range []string{
+		`hosts\[0\]\s+a\.example\.com`,
+		`hosts\[1\]\s+b\.example\.com`,
+		`users\[0\]\.name\s+alice`,
+		`users\[0\]\.roles\[0\]\s+admin`,
+		`users\[1\]\.name\s+bob`,
+	} {
+		if !regexpMatch(t, expected, out) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", &s, &meta.OutputOptions{ArrayStyle: "joined"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out = ui.OutputWriter.String()
+	if !regexpMatch(t, `hosts\s+a\.example\.com,b\.example\.com`, out) {
+		t.Fatalf("bad: %s", out)
+	}
+	if !strings.Contains(out, `{"name":"alice","roles":["admin"]},{"name":"bob"}`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", &s, &meta.OutputOptions{ArrayStyle: "nope"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func regexpMatch(t *testing.T, pattern, s string) bool {
+	matched, err := regexp.MatchString(pattern, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matched
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		In        string
+		Width     int
+		Out       string
+		Truncated bool
+	}{
+		{"short", 10, "short", false},
+		{"exactly", 7, "exactly", false},
+		{"truncated", 5, "trun…", true},
+		{"日本語のテキスト", 4, "日本語…", true},
+		{"ééééé", 3, "éé…", true},
+		{"abc", 1, "…", true},
+	}
+
+	for _, tc := range cases {
+		out, truncated := truncate(tc.In, tc.Width)
+		if out != tc.Out || truncated != tc.Truncated {
+			t.Fatalf("%q (%d): expected %q (%t), got %q (%t)",
+				tc.In, tc.Width, tc.Out, tc.Truncated, out, truncated)
+		}
+		if !utf8.ValidString(out) {
+			t.Fatalf("%q (%d): invalid UTF-8 in %q", tc.In, tc.Width, out)
+		}
+	}
+}
+
+func TestTableFormatter_maxColWidth(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"long":  "ünïcödé-value-that-is-long",
+			"short": "ok",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{MaxColWidth: 8}
+	if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !regexpMatch(t, `long\s+ünïcödé…\n`, out) || !regexpMatch(t, `short\s+ok\n`, out) {
+		t.Fatalf("bad: %s", out)
+	}
+	if strings.Contains(out, "Truncated values") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	ui = new(cli.MockUi)
+	opts.ShowTruncated = true
+	if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out = ui.OutputWriter.String()
+	if !strings.Contains(out, "Truncated values:\nlong: ünïcödé-value-that-is-long") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestTableFormatter_quoteValues(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"a":      "tab\there",
+			"b":      "line\nbreak",
+			"c":      "two  spaces",
+			"d":      "plain value",
+			"longer": "x",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{QuoteValues: true}
+	if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected a header and 5 rows, got:\n%s", out)
+	}
+
+	// Every value starts in the same column
+	column := strings.Index(lines[0], "Value")
+	for _, line := range lines[2:] {
+		if len(line) <= column || line[column-1] != ' ' && line[column-1] != '\t' || line[column] == ' ' {
+			t.Fatalf("misaligned row %q in output:\n%s", line, out)
+		}
+	}
+
+	for _, expected := range []string{
+		`"tab\there"`,
+		`"line\nbreak"`,
+		`"two  spaces"`,
+		"plain value",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, `"plain value"`) {
+		t.Fatalf("value was quoted unnecessarily:\n%s", out)
+	}
+}
+
+func TestTableFormatter_boolStyle(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"enabled":  true,
+			"disabled": false,
+			"string":   "true",
+			"number":   json.Number("1"),
+		},
+	}
+
+	cases := map[string][2]string{
+		"":           {"true", "false"},
+		"true-false": {"true", "false"},
+		"yes-no":     {"yes", "no"},
+		"on-off":     {"on", "off"},
+		"1-0":        {"1", "0"},
+	}
+
+	for style, expected := range cases {
+		ui := new(cli.MockUi)
+		opts := &meta.OutputOptions{BoolStyle: style}
+		if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", style, code, ui.ErrorWriter.String())
+		}
+		out := ui.OutputWriter.String()
+		for _, pattern := range []string{
+			`enabled\s+` + expected[0] + `\n`,
+			`disabled\s+` + expected[1] + `\n`,
+			// Values that are not booleans are left alone
+			`string\s+true\n`,
+			`number\s+1\n`,
+		} {
+			if !regexpMatch(t, pattern, out) {
+				t.Fatalf("%s: expected %q to match output:\n%s", style, pattern, out)
+			}
+		}
+
+		// The JSON output is not affected
+		ui = new(cli.MockUi)
+		if code := OutputSecret(ui, "json", &s, opts); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", style, code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.OutputWriter.String(), `"enabled":true`) {
+			t.Fatalf("%s: bad: %s", style, ui.OutputWriter.String())
+		}
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{BoolStyle: "nope"}
+	if code := OutputSecret(ui, "table", &s, opts); code == 0 {
+		t.Fatal("expected an error for an invalid style")
+	}
+}
+
+func TestTableFormatter_nullAndEmpty(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"null":       nil,
+			"empty":      "",
+			"whitespace": "   ",
+			"value":      "something",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "table", &s, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	for _, expected := range []string{
+		`null\s+<null>\n`,
+		`empty\s+<empty>\n`,
+		`whitespace\s+"   "\n`,
+		`value\s+something\n`,
+	} {
+		if !regexpMatch(t, expected, out) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "absent") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// The JSON output keeps the raw values
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "json", &s, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out = ui.OutputWriter.String()
+	for _, expected := range []string{`"null":null`, `"empty":""`, `"whitespace":"   "`} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+}
+
+func TestGroupThousands(t *testing.T) {
+	cases := map[string]string{
+		"0":        "0",
+		"123":      "123",
+		"1234":     "1,234",
+		"123456":   "123,456",
+		"1234567":  "1,234,567",
+		"-1234567": "-1,234,567",
+	}
+	for in, expected := range cases {
+		if actual := groupThousands(in, ","); actual != expected {
+			t.Fatalf("%q: expected %q, got %q", in, expected, actual)
+		}
+	}
+
+	if actual := groupThousands("1234567", "."); actual != "1.234.567" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestTableFormatter_thousandsSep(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"max_ttl":    json.Number("2764800"),
+			"ratio":      json.Number("1234.5"),
+			"entity_id":  json.Number("1234567"),
+			"version":    json.Number("10001"),
+			"num_uses":   json.Number("5000"),
+			"serial":     "1234567",
+			"big_number": json.Number("-9876543"),
+		},
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{
+		ThousandsSep: ",",
+		NoSepKeys:    []string{"num_uses"},
+	}
+	if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	for _, expected := range []string{
+		`max_ttl\s+2,764,800\n`,
+		`big_number\s+-9,876,543\n`,
+		`ratio\s+1234\.5\n`,
+		`entity_id\s+1234567\n`,
+		`version\s+10001\n`,
+		`num_uses\s+5000\n`,
+		`serial\s+1234567\n`,
+	} {
+		if !regexpMatch(t, expected, out) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+
+	// Structured formats are not affected
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "json", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), `"max_ttl":2764800`) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+}
+
+func TestJsonFormatter_typed(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"password": "secret",
+			"port":     json.Number("8200"),
+			"ratio":    json.Number("0.5"),
+			"enabled":  true,
+			"missing":  nil,
+			"hosts":    []interface{}{"a", json.Number("1")},
+			"nested":   map[string]interface{}{"key": "value"},
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "json", &s, &meta.OutputOptions{TypedJSON: true}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"password": map[string]interface{}{"type": "string", "value": "secret"},
+		"port":     map[string]interface{}{"type": "integer", "value": float64(8200)},
+		"ratio":    map[string]interface{}{"type": "number", "value": 0.5},
+		"enabled":  map[string]interface{}{"type": "boolean", "value": true},
+		"missing":  map[string]interface{}{"type": "null", "value": nil},
+		"hosts": map[string]interface{}{
+			"type": "array",
+			"value": []interface{}{
+				map[string]interface{}{"type": "string", "value": "a"},
+				map[string]interface{}{"type": "integer", "value": float64(1)},
+			},
+		},
+		"nested": map[string]interface{}{
+			"type": "object",
+			"value": map[string]interface{}{
+				"key": map[string]interface{}{"type": "string", "value": "value"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result.Data, expected) {
+		t.Fatalf("bad: %#v", result.Data)
+	}
+
+	// The secret itself is not modified
+	if s.Data["password"] != "secret" {
+		t.Fatalf("bad: %#v", s.Data)
+	}
+}
+
+func TestJsonFormatter_indent(t *testing.T) {
+	data := map[string]interface{}{"key": "value"}
+	indent := func(n int) *int { return &n }
+
+	cases := []struct {
+		Opts     *meta.OutputOptions
+		Expected string
+	}{
+		{&meta.OutputOptions{}, "{\"key\":\"value\"}\n"},
+		{&meta.OutputOptions{JSONIndentTab: true}, "{\n\t\"key\": \"value\"\n}\n"},
+		{&meta.OutputOptions{JSONIndent: indent(2)}, "{\n  \"key\": \"value\"\n}\n"},
+		{&meta.OutputOptions{JSONIndent: indent(4)}, "{\n    \"key\": \"value\"\n}\n"},
+		{&meta.OutputOptions{JSONIndent: indent(0)}, "{\"key\":\"value\"}\n"},
+	}
+
+	for i, tc := range cases {
+		ui := new(cli.MockUi)
+		if code := outputWithFormat(ui, "json", nil, data, tc.Opts); code != 0 {
+			t.Fatalf("%d: bad: %d\n\n%s", i, code, ui.ErrorWriter.String())
+		}
+		if actual := ui.OutputWriter.String(); actual != tc.Expected {
+			t.Fatalf("%d: bad: %q", i, actual)
+		}
+	}
+
+	for _, opts := range []*meta.OutputOptions{
+		{JSONIndent: indent(-1)},
+		{JSONIndent: indent(2), JSONIndentTab: true},
+	} {
+		ui := new(cli.MockUi)
+		if code := outputWithFormat(ui, "json", nil, data, opts); code == 0 {
+			t.Fatalf("expected an error for %#v", opts)
+		}
+	}
+}
+
+func TestOutputSecret_sections(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"role": "ci",
+		},
+		Auth: &api.SecretAuth{
+			ClientToken: "8d4a2c09",
+			Policies:    []string{"default"},
+		},
+		WrapInfo: &api.SecretWrapInfo{
+			Token: "47b21f8e",
+			TTL:   60,
+		},
+		Warnings: []string{"role is deprecated"},
+	}
+
+	// Every section is output by default
+	for _, format := range []string{"table", "json", "yaml"} {
+		ui := cli.NewMockUi()
+		if code := OutputSecret(ui, format, &s, &meta.OutputOptions{}); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", format, code, ui.ErrorWriter.String())
+		}
+		out := ui.OutputWriter.String()
+		for _, v := range []string{"ci", "8d4a2c09", "47b21f8e", "role is deprecated"} {
+			if !strings.Contains(out, v) {
+				t.Fatalf("%s: expected %q in output:\n%s", format, v, out)
+			}
+		}
+	}
+
+	cases := []struct {
+		Only     string
+		Included string
+		Excluded []string
+	}{
+		{"data", "ci", []string{"8d4a2c09", "47b21f8e", "deprecated"}},
+		{"auth", "8d4a2c09", []string{"role", "47b21f8e", "deprecated"}},
+		{"wrap", "47b21f8e", []string{"role", "8d4a2c09", "deprecated"}},
+	}
+	for _, tc := range cases {
+		for _, format := range []string{"table", "json", "yaml"} {
+			ui := cli.NewMockUi()
+			if code := OutputSecret(ui, format, &s, &meta.OutputOptions{Only: tc.Only}); code != 0 {
+				t.Fatalf("%s/%s: bad: %d\n\n%s", tc.Only, format, code, ui.ErrorWriter.String())
+			}
+			out := ui.OutputWriter.String()
+			if !strings.Contains(out, tc.Included) {
+				t.Fatalf("%s/%s: expected %q in output:\n%s", tc.Only, format, tc.Included, out)
+			}
+			for _, v := range tc.Excluded {
+				if strings.Contains(out, v) {
+					t.Fatalf("%s/%s: unexpected %q in output:\n%s", tc.Only, format, v, out)
+				}
+			}
+		}
+	}
+
+	// The JSON of a section is the section itself
+	ui := cli.NewMockUi()
+	if code := OutputSecret(ui, "json", &s, &meta.OutputOptions{Only: "data"}); code != 0 {
+		t.Fatalf("bad: %d", code)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, s.Data) {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// Missing and unknown sections are errors
+	for _, only := range []string{"auth", "bogus"} {
+		ui := cli.NewMockUi()
+		if code := OutputSecret(ui, "json", &api.Secret{Data: s.Data}, &meta.OutputOptions{Only: only}); code != 1 {
+			t.Fatalf("%s: bad: %d", only, code)
+		}
+	}
+}
+
+func TestOutputSecret_mergeSections(t *testing.T) {
+	s := api.Secret{
+		LeaseID:       "secret/foo/1",
+		LeaseDuration: 60,
+		Data: map[string]interface{}{
+			"role": "ci",
+		},
+		Auth: &api.SecretAuth{
+			ClientToken: "8d4a2c09",
+			Policies:    []string{"default"},
+		},
+		WrapInfo: &api.SecretWrapInfo{
+			Token: "47b21f8e",
+			TTL:   60,
+		},
+		Warnings: []string{"role is deprecated"},
+	}
+	opts := &meta.OutputOptions{MergeSections: true}
+
+	// The JSON and YAML are the same object
+	var objects []map[string]interface{}
+	for _, format := range []string{"json", "yaml"} {
+		ui := cli.NewMockUi()
+		if code := OutputSecret(ui, format, &s, opts); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", format, code, ui.ErrorWriter.String())
+		}
+		var o map[string]interface{}
+		if err := yaml.Unmarshal(ui.OutputWriter.Bytes(), &o); err != nil {
+			t.Fatalf("%s: err: %s", format, err)
+		}
+		objects = append(objects, o)
+	}
+	if !reflect.DeepEqual(objects[0], objects[1]) {
+		t.Fatalf("bad: %#v\n\n%#v", objects[0], objects[1])
+	}
+	o := objects[0]
+	if len(o) != 5 {
+		t.Fatalf("bad: %#v", o)
+	}
+	if o["data"].(map[string]interface{})["role"] != "ci" ||
+		o["auth"].(map[string]interface{})["client_token"] != "8d4a2c09" ||
+		o["wrap_info"].(map[string]interface{})["token"] != "47b21f8e" ||
+		o["lease"].(map[string]interface{})["lease_id"] != "secret/foo/1" ||
+		o["warnings"].([]interface{})[0] != "role is deprecated" {
+		t.Fatalf("bad: %#v", o)
+	}
+
+	// The table has a row for each of the same fields
+	ui := cli.NewMockUi()
+	if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	for _, expected := range []string{
+		`lease\.lease_id\s+secret/foo/1`,
+		`auth\.client_token\s+8d4a2c09`,
+		`auth\.policies\[0\]\s+default`,
+		`wrap_info\.token\s+47b21f8e`,
+		`data\.role\s+ci`,
+		`warnings\[0\]\s+role is deprecated`,
+	} {
+		if !regexp.MustCompile(expected).MatchString(ui.OutputWriter.String()) {
+			t.Fatalf("expected %s in output:\n%s", expected, ui.OutputWriter.String())
+		}
+	}
+
+	// With -only, just that section is merged
+	ui = cli.NewMockUi()
+	if code := OutputSecret(ui, "json", &s, &meta.OutputOptions{MergeSections: true, Only: "auth"}); code != 0 {
+		t.Fatalf("bad: %d", code)
+	}
+	o = nil
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := o["auth"]; !ok || len(o) != 1 {
+		t.Fatalf("bad: %#v", o)
+	}
+}
+
+func TestOutputSecret_redactSensitive(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"username": "admin",
+				"password": "hunter2",
+			},
+			"metadata": map[string]interface{}{
+				"version": json.Number("3"),
+				"custom_metadata": map[string]interface{}{
+					"sensitive": "password, api_key",
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		Opts     meta.OutputOptions
+		Redacted bool
+	}{
+		{meta.OutputOptions{}, false},
+		{meta.OutputOptions{RespectSensitive: true}, true},
+		{meta.OutputOptions{RespectSensitive: true, Reveal: true}, false},
+	}
+
+	for i, tc := range cases {
+		for _, format := range []string{"table", "json", "yaml"} {
+			ui := cli.NewMockUi()
+			if code := OutputSecret(ui, format, &s, &tc.Opts); code != 0 {
+				t.Fatalf("%d/%s: bad: %d\n\n%s", i, format, code, ui.ErrorWriter.String())
+			}
+			out := ui.OutputWriter.String()
+			if !strings.Contains(out, "admin") {
+				t.Fatalf("%d/%s: expected the username in output:\n%s", i, format, out)
+			}
+			if strings.Contains(out, "hunter2") == tc.Redacted || strings.Contains(out, "REDACTED") != tc.Redacted {
+				t.Fatalf("%d/%s: expected redacted %t:\n%s", i, format, tc.Redacted, out)
+			}
+		}
+
+		ui := cli.NewMockUi()
+		if code := PrintRawField(ui, &s, "data.password", &tc.Opts); code != 0 {
+			t.Fatalf("%d: bad: %d\n\n%s", i, code, ui.ErrorWriter.String())
+		}
+		if out := ui.OutputWriter.String(); (out == "REDACTED\n") != tc.Redacted {
+			t.Fatalf("%d: bad: %q", i, out)
+		}
+	}
+
+	// The secret itself is left alone
+	if s.Data["data"].(map[string]interface{})["password"] != "hunter2" {
+		t.Fatalf("bad: %#v", s.Data)
+	}
+}
+
+func TestTableFormatter_transpose(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"keys": []interface{}{"foo", "bar"},
+			"key_info": map[string]interface{}{
+				"foo": map[string]interface{}{"owner": "alice", "version": json.Number("3")},
+				"bar": map[string]interface{}{"owner": "bob"},
+			},
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputList(ui, "table", s, &meta.OutputOptions{Transpose: true}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !regexpMatch(t, `^Key\s+bar\nowner\s+bob\nversion\s*\n---\nKey\s+foo\nowner\s+alice\nversion\s+3\n`, out) {
+		t.Fatalf("bad: %q", out)
+	}
+
+	// A single record is a single block without a divider
+	s.Data["keys"] = []interface{}{"foo"}
+	ui = new(cli.MockUi)
+	if code := OutputList(ui, "table", s, &meta.OutputOptions{Transpose: true}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out = ui.OutputWriter.String()
+	if !regexpMatch(t, `^Key\s+foo\nowner\s+alice\nversion\s+3\n`, out) || strings.Contains(out, "---") {
+		t.Fatalf("bad: %q", out)
+	}
+}
+
+func TestListColumns(t *testing.T) {
+	keys := []string{"a", "bb", "ccc", "dddd", "e"}
+	cases := []struct {
+		Width    int
+		Expected []string
+	}{
+		{80, []string{"a  bb  ccc  dddd  e"}},
+		{12, []string{"a   ccc   e", "bb  dddd"}},
+		{3, []string{"a", "bb", "ccc", "dddd", "e"}},
+	}
+
+	for _, tc := range cases {
+		if actual := listColumns(keys, tc.Width); !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: bad: %#v", tc.Width, actual)
+		}
+	}
+}
+
+func TestTableFormatter_hideEmptyColumns(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"keys": []interface{}{"foo", "bar"},
+			"key_info": map[string]interface{}{
+				"foo": map[string]interface{}{"owner": "alice", "comment": "", "tags": []interface{}{}},
+				"bar": map[string]interface{}{"owner": "bob", "comment": nil},
+			},
+		},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputList(ui, "table", s, &meta.OutputOptions{HideEmptyColumns: true}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !regexpMatch(t, `^Key\s+owner\n`, out) || strings.Contains(out, "comment") || strings.Contains(out, "tags") {
+		t.Fatalf("bad: %q", out)
+	}
+
+	// Without the flag every column is shown
+	ui = new(cli.MockUi)
+	if code := OutputList(ui, "table", s, nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !regexpMatch(t, `^Key\s+comment\s+owner\s+tags\n`, out) {
+		t.Fatalf("bad: %q", out)
+	}
+
+	// Single records hide their empty fields, but json is not affected
+	secret := &api.Secret{Data: map[string]interface{}{"user": "alice", "note": "", "roles": nil}}
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", secret, &meta.OutputOptions{HideEmptyColumns: true}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !regexpMatch(t, `user\s+alice`, out) || strings.Contains(out, "note") || strings.Contains(out, "roles") {
+		t.Fatalf("bad: %q", out)
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "json", secret, &meta.OutputOptions{HideEmptyColumns: true}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"note":""`) || !strings.Contains(out, `"roles":null`) {
+		t.Fatalf("bad: %q", out)
+	}
+}
+
+func TestOutputSecret_noFinalNewline(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{"foo": "bar"},
+	}
+
+	for _, format := range []string{"table", "json", "yaml", "csv"} {
+		var with, without bytes.Buffer
+		if code := OutputSecret(&cli.BasicUi{Writer: &with}, format, s, nil); code != 0 {
+			t.Fatalf("%s: bad: %d", format, code)
+		}
+		opts := &meta.OutputOptions{NoFinalNewline: true}
+		if code := OutputSecret(&cli.BasicUi{Writer: &without}, format, s, opts); code != 0 {
+			t.Fatalf("%s: bad: %d", format, code)
+		}
+
+		if !strings.HasSuffix(with.String(), "\n") {
+			t.Fatalf("%s: expected a final newline: %q", format, with.String())
+		}
+		if without.String() != strings.TrimSuffix(with.String(), "\n") {
+			t.Fatalf("%s: bad output: %q", format, without.String())
+		}
+	}
+}
+
+func TestTableFormatter_warningsAsFootnotes(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"max_ttl": "768h",
+			"ttl":     "24h",
+			"policy":  "default",
+		},
+		Warnings: []string{
+			"ttl of 24h is greater than the mount's default",
+			"Endpoint is deprecated",
+			"ttl and max_ttl will be capped",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{WarningsAsFootnotes: true}
+	if code := OutputSecret(ui, "table", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := "Key    \tValue\n" +
+		"---    \t-----\n" +
+		"max_ttl\t768h [3]\n" +
+		"policy \tdefault\n" +
+		"ttl    \t24h [1][3]\n" +
+		"\n" +
+		"[1] ttl of 24h is greater than the mount's default\n" +
+		"[2] Endpoint is deprecated\n" +
+		"[3] ttl and max_ttl will be capped\n"
+	if output := ui.OutputWriter.String(); output != expected {
+		t.Fatalf("bad output:\n%q\n\nexpected:\n%q", output, expected)
+	}
+
+	// The json format keeps the warnings in their own block
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "json", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); strings.Contains(output, "[1]") || !strings.Contains(output, `"Endpoint is deprecated"`) {
+		t.Fatalf("bad output:\n%s", output)
+	}
+}
+
+func TestTableFormatter_humanizeBytes(t *testing.T) {
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"max_lease_bytes": json.Number("1572864"),
+			"size":            json.Number("512"),
+			"cache_size":      json.Number("1073741824"),
+			"max_ttl":         json.Number("2764800"),
+			"ratio":           json.Number("1234.5"),
+		},
+	}
+
+	cases := []struct {
+		Base     int
+		Expected []string
+	}{
+		{2, []string{
+			`max_lease_bytes\s+1\.5 MiB\n`,
+			`size\s+512 B\n`,
+			`cache_size\s+1 GiB\n`,
+			`max_ttl\s+2764800\n`,
+			`ratio\s+1234\.5\n`,
+		}},
+		{10, []string{
+			`max_lease_bytes\s+1\.6 MB\n`,
+			`size\s+512 B\n`,
+			`cache_size\s+1\.1 GB\n`,
+			`max_ttl\s+2764800\n`,
+		}},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		opts := &meta.OutputOptions{HumanizeBytes: true, BytesBase: tc.Base}
+		if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+			t.Fatalf("%d: bad: %d\n\n%s", tc.Base, code, ui.ErrorWriter.String())
+		}
+		out := ui.OutputWriter.String()
+		for _, expected := range tc.Expected {
+			if !regexpMatch(t, expected, out) {
+				t.Fatalf("%d: expected %q in output:\n%s", tc.Base, expected, out)
+			}
+		}
+	}
+
+	// Structured formats are not affected
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{HumanizeBytes: true, BytesBase: 2}
+	if code := OutputSecret(ui, "json", &s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), `"max_lease_bytes":1572864`) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+}
+
+func TestTableFormatter_binary(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	s := api.Secret{
+		Data: map[string]interface{}{
+			"image":   png,
+			"decoded": "ab\ufffdcd",
+			"text":    "héllo\tworld",
+		},
+	}
+
+	cases := []struct {
+		Preview  int
+		Expected []string
+	}{
+		{8, []string{
+			`image\s+<binary: 16 bytes, 89 50 4e 47 0d 0a 1a 0a \.\.\.>\n`,
+			`decoded\s+<binary: 7 bytes, 61 62 ef bf bd 63 64>\n`,
+			`text\s+héllo\tworld\n`,
+		}},
+		{0, []string{
+			`image\s+<binary: 16 bytes>\n`,
+		}},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		opts := &meta.OutputOptions{BinaryPreview: tc.Preview}
+		if code := OutputSecret(ui, "table", &s, opts); code != 0 {
+			t.Fatalf("%d: bad: %d\n\n%s", tc.Preview, code, ui.ErrorWriter.String())
+		}
+		out := ui.OutputWriter.String()
+		for _, expected := range tc.Expected {
+			if !regexpMatch(t, expected, out) {
+				t.Fatalf("%d: expected %q in output:\n%s", tc.Preview, expected, out)
+			}
+		}
+	}
+
+	// The json format outputs the value itself
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "json", &s, &meta.OutputOptions{BinaryPreview: 8}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var out struct {
+		Data map[string]string
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Data["decoded"] != "ab\ufffdcd" || strings.Contains(ui.OutputWriter.String(), "<binary") {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+}
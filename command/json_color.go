@@ -0,0 +1,105 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// uiIsTerminal reports whether the UI writes its output straight to a
+// terminal; it can be overwritten for tests. Output that is captured first,
+// such as to sign it, is not written to a terminal.
+var uiIsTerminal = func(ui cli.Ui) bool {
+	b, ok := ui.(*cli.BasicUi)
+	return ok && b.Writer == os.Stdout && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// The ANSI colors of the parts of colorized JSON.
+const (
+	jsonColorKey     = "\x1b[34m"
+	jsonColorString  = "\x1b[32m"
+	jsonColorNumber  = "\x1b[36m"
+	jsonColorLiteral = "\x1b[35m"
+	jsonColorReset   = "\x1b[0m"
+)
+
+// jsonColor reports whether the json format is colorized. -color and
+// -no-color take precedence over the environment, given by meta.NoColorEnv,
+// which takes precedence over coloring output written to a terminal.
+func jsonColor(ui cli.Ui, opts *meta.OutputOptions) (bool, error) {
+	switch {
+	case opts.Color && opts.NoColor:
+		return false, errors.New("-color and -no-color cannot be used together")
+	case opts.NoColor:
+		return false, nil
+	case opts.Color:
+		return true, nil
+	case meta.NoColorEnv():
+		return false, nil
+	}
+	return uiIsTerminal(ui), nil
+}
+
+// colorizeJSON adds ANSI colors to the keys, strings, numbers and literals
+// of the JSON document. The document is otherwise unchanged; strings cannot
+// hold the escape character, which JSON escapes, so the colors cannot be
+// confused with the content.
+func colorizeJSON(b []byte) string {
+	var buf bytes.Buffer
+	color := func(c string, tok []byte) {
+		buf.WriteString(c)
+		buf.Write(tok)
+		buf.WriteString(jsonColorReset)
+	}
+
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(b) && b[j] != '"' {
+				if b[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(b) {
+				j++
+			}
+
+			// A string followed by a colon is a key
+			k := j
+			for k < len(b) && (b[k] == ' ' || b[k] == '\t' || b[k] == '\n' || b[k] == '\r') {
+				k++
+			}
+			if k < len(b) && b[k] == ':' {
+				color(jsonColorKey, b[i:j])
+			} else {
+				color(jsonColorString, b[i:j])
+			}
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(b) && bytes.IndexByte([]byte("0123456789+-.eE"), b[j]) >= 0 {
+				j++
+			}
+			color(jsonColorNumber, b[i:j])
+			i = j
+		case c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(b) && b[j] >= 'a' && b[j] <= 'z' {
+				j++
+			}
+			color(jsonColorLiteral, b[i:j])
+			i = j
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func TestJsonFormatter_color(t *testing.T) {
+	oldIsTerminal := uiIsTerminal
+	defer func() { uiIsTerminal = oldIsTerminal }()
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"name":    "a \"quoted\": value",
+			"port":    8200,
+			"enabled": true,
+			"none":    nil,
+		},
+	}
+
+	two := 2
+	cases := []struct {
+		Terminal bool
+		NoColor  string
+		Opts     meta.OutputOptions
+		Color    bool
+		Indent   string
+	}{
+		{true, "", meta.OutputOptions{}, true, "  "},
+		{false, "", meta.OutputOptions{}, false, ""},
+		{true, "1", meta.OutputOptions{}, false, "  "},
+		{true, "", meta.OutputOptions{NoColor: true}, false, "  "},
+		{false, "", meta.OutputOptions{Color: true}, true, ""},
+		{true, "1", meta.OutputOptions{Color: true}, true, "  "},
+		{true, "", meta.OutputOptions{JSONIndentTab: true}, true, "\t"},
+		{false, "", meta.OutputOptions{JSONIndentTab: true}, false, "\t"},
+		{false, "", meta.OutputOptions{JSONIndent: &two}, false, "  "},
+	}
+
+	for i, tc := range cases {
+		uiIsTerminal = func(cli.Ui) bool { return tc.Terminal }
+		os.Setenv("NO_COLOR", tc.NoColor)
+
+		ui := new(cli.MockUi)
+		if code := OutputSecret(ui, "json", s, &tc.Opts); code != 0 {
+			t.Fatalf("%d: bad: %d\n\n%s", i, code, ui.ErrorWriter.String())
+		}
+		out := ui.OutputWriter.String()
+		if strings.Contains(out, "\x1b[") != tc.Color {
+			t.Fatalf("%d: bad output: %q", i, out)
+		}
+		if tc.Indent == "" {
+			// Compact output is a single line
+			if strings.Count(out, "\n") != 1 {
+				t.Fatalf("%d: expected compact output: %q", i, out)
+			}
+		} else if !strings.Contains(out, "\n"+tc.Indent+`"data"`) && !strings.Contains(out, "\n"+tc.Indent+jsonColorKey+`"data"`) {
+			t.Fatalf("%d: bad indent: %q", i, out)
+		}
+	}
+
+	// -color and -no-color are exclusive
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "json", s, &meta.OutputOptions{Color: true, NoColor: true}); code == 0 {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestColorizeJSON(t *testing.T) {
+	doc := `{
+  "key": "value with \"quotes\" and \\ : colons",
+  "numbers": [-1.5e+3, 0, 42],
+  "literals": [true, false, null],
+  "nested": {"a": "b"}
+}`
+	colored := colorizeJSON([]byte(doc))
+
+	// Removing the colors gives back the document
+	stripped := regexp.MustCompile("\x1b\\[[0-9]+m").ReplaceAllString(colored, "")
+	if stripped != doc {
+		t.Fatalf("bad: %s", stripped)
+	}
+
+	for _, expected := range []string{
+		jsonColorKey + `"key"` + jsonColorReset + ": " + jsonColorString + `"value with \"quotes\" and \\ : colons"` + jsonColorReset,
+		jsonColorNumber + "-1.5e+3" + jsonColorReset,
+		jsonColorNumber + "42" + jsonColorReset,
+		jsonColorLiteral + "null" + jsonColorReset,
+		jsonColorKey + `"a"` + jsonColorReset + ": " + jsonColorString + `"b"` + jsonColorReset,
+	} {
+		if !strings.Contains(colored, expected) {
+			t.Fatalf("expected %q in %q", expected, colored)
+		}
+	}
+}
+
+func TestRead_noColor(t *testing.T) {
+	oldIsTerminal := uiIsTerminal
+	defer func() { uiIsTerminal = oldIsTerminal }()
+	uiIsTerminal = func(cli.Ui) bool { return true }
+
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, format := range []string{"json", "table", "yaml"} {
+		ui := new(cli.MockUi)
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: token,
+				Ui:          ui,
+			},
+		}
+
+		args := []string{"-address", addr, "-no-color", "-format", format, "secret/foo"}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", format, code, ui.ErrorWriter.String())
+		}
+		if out := ui.OutputWriter.String(); strings.Contains(out, "\x1b") {
+			t.Fatalf("%s: escape codes in output: %q", format, out)
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// jsonPath is a parsed JSONPath expression, as used by -jsonpath. The
+// supported syntax is the root "$", child names as ".name" or "['name']",
+// the wildcards ".*" and "[*]", array indexes such as "[0]" and "[-1]",
+// slices such as "[1:3]", recursive descent with "..", and filters such as
+// "[?(@.active)]" or "[?(@.age >= 18)]" comparing to a string, number,
+// boolean or null.
+type jsonPath []jsonPathStep
+
+type jsonPathStepKind int
+
+const (
+	jsonPathName jsonPathStepKind = iota
+	jsonPathWildcard
+	jsonPathIndex
+	jsonPathSlice
+	jsonPathFilter
+)
+
+// jsonPathStep selects the values of one part of a JSONPath expression from
+// the values selected by the steps before it.
+type jsonPathStep struct {
+	kind jsonPathStepKind
+
+	// recursive applies the step to every value below, and including, the
+	// current one, for "..".
+	recursive bool
+
+	name       string
+	index      int
+	start, end *int
+	filter     *jsonPathFilterExpr
+}
+
+// jsonPathFilterExpr keeps the values for which the path relative to "@"
+// matches something and, if op is set, a matched value compares to value.
+type jsonPathFilterExpr struct {
+	path  jsonPath
+	op    string
+	value interface{}
+}
+
+// parseJSONPath parses a JSONPath expression, which must start with "$".
+func parseJSONPath(expr string) (jsonPath, error) {
+	p := &jsonPathParser{s: strings.TrimSpace(expr)}
+	if !strings.HasPrefix(p.s, "$") {
+		return nil, fmt.Errorf("expression must start with $")
+	}
+	p.i = 1
+
+	path, err := p.steps()
+	if err != nil {
+		return nil, err
+	}
+	if p.i != len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.i])
+	}
+	return path, nil
+}
+
+type jsonPathParser struct {
+	s string
+	i int
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %s", p.i+1, fmt.Sprintf(format, args...))
+}
+
+// steps parses steps until the end of the expression, or anything that
+// cannot start a step, such as the operator of a filter.
+func (p *jsonPathParser) steps() (jsonPath, error) {
+	var path jsonPath
+	for p.i < len(p.s) {
+		var step jsonPathStep
+		var err error
+		switch p.s[p.i] {
+		case '.':
+			p.i++
+			recursive := p.i < len(p.s) && p.s[p.i] == '.'
+			if recursive {
+				p.i++
+			}
+			switch {
+			case p.i < len(p.s) && p.s[p.i] == '*':
+				p.i++
+				step = jsonPathStep{kind: jsonPathWildcard}
+			case recursive && p.i < len(p.s) && p.s[p.i] == '[':
+				step, err = p.bracket()
+			default:
+				step, err = p.name()
+			}
+			step.recursive = recursive
+		case '[':
+			step, err = p.bracket()
+		default:
+			return path, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, step)
+	}
+	return path, nil
+}
+
+// name parses an unquoted child name.
+func (p *jsonPathParser) name() (jsonPathStep, error) {
+	start := p.i
+	for p.i < len(p.s) && !strings.ContainsRune(".[]() =!<>", rune(p.s[p.i])) {
+		p.i++
+	}
+	if p.i == start {
+		return jsonPathStep{}, p.errorf("expected a name")
+	}
+	return jsonPathStep{kind: jsonPathName, name: p.s[start:p.i]}, nil
+}
+
+// bracket parses a step in brackets: a quoted name, a wildcard, an index, a
+// slice or a filter.
+func (p *jsonPathParser) bracket() (jsonPathStep, error) {
+	p.i++
+	p.skipSpace()
+	if p.i >= len(p.s) {
+		return jsonPathStep{}, p.errorf("unterminated [")
+	}
+
+	var step jsonPathStep
+	switch c := p.s[p.i]; {
+	case c == '*':
+		p.i++
+		step = jsonPathStep{kind: jsonPathWildcard}
+	case c == '\'' || c == '"':
+		name, err := p.quoted()
+		if err != nil {
+			return step, err
+		}
+		step = jsonPathStep{kind: jsonPathName, name: name}
+	case c == '?':
+		filter, err := p.filter()
+		if err != nil {
+			return step, err
+		}
+		step = jsonPathStep{kind: jsonPathFilter, filter: filter}
+	default:
+		end := strings.IndexByte(p.s[p.i:], ']')
+		if end == -1 {
+			return step, p.errorf("unterminated [")
+		}
+		text := strings.TrimSpace(p.s[p.i : p.i+end])
+		if parts := strings.Split(text, ":"); len(parts) == 2 {
+			step = jsonPathStep{kind: jsonPathSlice}
+			for i, part := range parts {
+				if part = strings.TrimSpace(part); part == "" {
+					continue
+				}
+				n, err := strconv.Atoi(part)
+				if err != nil {
+					return step, p.errorf("invalid slice %q", text)
+				}
+				if i == 0 {
+					step.start = &n
+				} else {
+					step.end = &n
+				}
+			}
+		} else {
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return step, p.errorf("invalid index %q", text)
+			}
+			step = jsonPathStep{kind: jsonPathIndex, index: n}
+		}
+		p.i += end
+	}
+
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != ']' {
+		return step, p.errorf("expected ]")
+	}
+	p.i++
+	return step, nil
+}
+
+// filter parses a filter such as "?(@.age >= 18)".
+func (p *jsonPathParser) filter() (*jsonPathFilterExpr, error) {
+	p.i++
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != '(' {
+		return nil, p.errorf("expected ( after ?")
+	}
+	p.i++
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != '@' {
+		return nil, p.errorf("expected @ in filter")
+	}
+	p.i++
+
+	path, err := p.steps()
+	if err != nil {
+		return nil, err
+	}
+	filter := &jsonPathFilterExpr{path: path}
+
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.s[p.i:], op) {
+			p.i += len(op)
+			filter.op = op
+			break
+		}
+	}
+	if filter.op != "" {
+		p.skipSpace()
+		if filter.value, err = p.literal(); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+	}
+
+	if p.i >= len(p.s) || p.s[p.i] != ')' {
+		return nil, p.errorf("expected ) to end the filter")
+	}
+	p.i++
+	return filter, nil
+}
+
+// literal parses the value a filter compares to.
+func (p *jsonPathParser) literal() (interface{}, error) {
+	if p.i < len(p.s) && (p.s[p.i] == '\'' || p.s[p.i] == '"') {
+		return p.quoted()
+	}
+
+	start := p.i
+	for p.i < len(p.s) && !strings.ContainsRune(" )", rune(p.s[p.i])) {
+		p.i++
+	}
+	text := p.s[start:p.i]
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		p.i = start
+		return nil, p.errorf("invalid value %q", text)
+	}
+	return n, nil
+}
+
+// quoted parses a string in single or double quotes, in which a backslash
+// escapes the next character.
+func (p *jsonPathParser) quoted() (string, error) {
+	quote := p.s[p.i]
+	p.i++
+	var b bytes.Buffer
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.i < len(p.s):
+			b.WriteByte(p.s[p.i])
+			p.i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for p.i < len(p.s) && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+// eval returns the values the path selects from v, in document order with
+// the keys of objects sorted.
+func (path jsonPath) eval(v interface{}) []interface{} {
+	nodes := []interface{}{v}
+	for _, step := range path {
+		var next []interface{}
+		for _, n := range nodes {
+			if step.recursive {
+				for _, d := range jsonPathDescendants(n) {
+					next = append(next, step.match(d)...)
+				}
+				continue
+			}
+			next = append(next, step.match(n)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// match returns the values the step selects from v.
+func (step jsonPathStep) match(v interface{}) []interface{} {
+	switch step.kind {
+	case jsonPathName:
+		if m, ok := v.(map[string]interface{}); ok {
+			if child, ok := m[step.name]; ok {
+				return []interface{}{child}
+			}
+		}
+	case jsonPathWildcard:
+		return jsonPathChildren(v)
+	case jsonPathIndex:
+		if a, ok := v.([]interface{}); ok {
+			i := step.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				return []interface{}{a[i]}
+			}
+		}
+	case jsonPathSlice:
+		if a, ok := v.([]interface{}); ok {
+			start, end := 0, len(a)
+			if step.start != nil {
+				start = jsonPathClamp(*step.start, len(a))
+			}
+			if step.end != nil {
+				end = jsonPathClamp(*step.end, len(a))
+			}
+			if start < end {
+				return a[start:end]
+			}
+		}
+	case jsonPathFilter:
+		var matched []interface{}
+		for _, child := range jsonPathChildren(v) {
+			if step.filter.test(child) {
+				matched = append(matched, child)
+			}
+		}
+		return matched
+	}
+	return nil
+}
+
+// test reports whether the filter keeps v.
+func (f *jsonPathFilterExpr) test(v interface{}) bool {
+	for _, m := range f.path.eval(v) {
+		if f.op == "" || jsonPathCompare(m, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonPathCompare compares a value to the literal of a filter. Numbers and
+// strings can be ordered; other values can only be compared for equality.
+func jsonPathCompare(v interface{}, op string, literal interface{}) bool {
+	if n, ok := avroNumber(v); ok {
+		l, ok := literal.(float64)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return n == l
+		case "!=":
+			return n != l
+		case "<":
+			return n < l
+		case "<=":
+			return n <= l
+		case ">":
+			return n > l
+		case ">=":
+			return n >= l
+		}
+	}
+	if s, ok := v.(string); ok {
+		l, ok := literal.(string)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return s == l
+		case "!=":
+			return s != l
+		case "<":
+			return s < l
+		case "<=":
+			return s <= l
+		case ">":
+			return s > l
+		case ">=":
+			return s >= l
+		}
+	}
+
+	switch op {
+	case "==":
+		return v == literal
+	case "!=":
+		return v != literal
+	}
+	return false
+}
+
+// jsonPathChildren returns the elements of an array, or the values of an
+// object sorted by key.
+func jsonPathChildren(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		children := make([]interface{}, len(keys))
+		for i, k := range keys {
+			children[i] = v[k]
+		}
+		return children
+	}
+	return nil
+}
+
+// jsonPathDescendants returns v and every value below it.
+func jsonPathDescendants(v interface{}) []interface{} {
+	values := []interface{}{v}
+	for _, child := range jsonPathChildren(v) {
+		values = append(values, jsonPathDescendants(child)...)
+	}
+	return values
+}
+
+// jsonPathClamp resolves a slice bound, which counts from the end of the
+// array if negative.
+func jsonPathClamp(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// outputJSONPath outputs the values that the expression given by -jsonpath
+// selects from the response, one per line. Strings are output as they are
+// and other values as JSON. Selecting nothing is an error unless
+// -jsonpath-allow-empty is set.
+func outputJSONPath(ui cli.Ui, secret *api.Secret, opts *meta.OutputOptions) int {
+	path, err := parseJSONPath(opts.JSONPath)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Invalid -jsonpath: %s", err))
+		return 1
+	}
+
+	// The expression applies to the response as it is encoded
+	b, err := json.Marshal(secret)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not output secret: %s", err))
+		return 1
+	}
+	var response interface{}
+	if err := jsonutil.DecodeJSON(b, &response); err != nil {
+		ui.Error(fmt.Sprintf("Could not output secret: %s", err))
+		return 1
+	}
+
+	values := path.eval(response)
+	if len(values) == 0 {
+		if opts.JSONPathAllowEmpty {
+			return 0
+		}
+		ui.Error(fmt.Sprintf("No values matched %s", opts.JSONPath))
+		return 1
+	}
+	if opts.FieldSingle && len(values) > 1 {
+		ui.Error(fmt.Sprintf("%s is ambiguous: it matched %d values", opts.JSONPath, len(values)))
+		return 1
+	}
+	if opts.Quiet {
+		return 0
+	}
+
+	lines := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			b, err := json.Marshal(v)
+			if err != nil {
+				ui.Error(fmt.Sprintf("Could not output secret: %s", err))
+				return 1
+			}
+			s = string(b)
+		}
+		if !opts.NoSanitize {
+			s = sanitize(s)
+		}
+		lines = append(lines, s)
+	}
+	return outputSigned(ui, opts, func(ui cli.Ui) int {
+		ui.Output(strings.Join(lines, "\n"))
+		return 0
+	})
+}
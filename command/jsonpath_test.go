@@ -0,0 +1,114 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestOutputSecret_jsonPath(t *testing.T) {
+	s := &api.Secret{
+		LeaseID: "lease",
+		Data: map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"name": "alice", "active": true, "age": json.Number("34")},
+				map[string]interface{}{"name": "bob", "active": false, "age": json.Number("17")},
+				map[string]interface{}{"name": "carol", "age": json.Number("52")},
+			},
+			"owner": map[string]interface{}{"name": "dave"},
+		},
+	}
+
+	cases := []struct {
+		Expr   string
+		Output string
+	}{
+		{"$.lease_id", "lease"},
+		{"$.data.users[0].name", "alice"},
+		{"$.data['users'][-1].name", "carol"},
+		{"$.data.users[?(@.active)].name", "alice\nbob"},
+		{"$.data.users[?(@.active == true)].name", "alice"},
+		{"$.data.users[?(@.age >= 18)].name", "alice\ncarol"},
+		{"$.data.users[?(@.name != 'bob')].age", "34\n52"},
+		{"$.data.users[1:].name", "bob\ncarol"},
+		{"$.data.users[*].name", "alice\nbob\ncarol"},
+		{"$..name", "dave\nalice\nbob\ncarol"},
+		{"$.data.owner", `{"name":"dave"}`},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		opts := &meta.OutputOptions{JSONPath: tc.Expr}
+		if code := OutputSecret(ui, "table", s, opts); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Expr, code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != tc.Output+"\n" {
+			t.Fatalf("%s: bad output: %q", tc.Expr, output)
+		}
+	}
+
+	errors := []struct {
+		Expr       string
+		AllowEmpty bool
+		Code       int
+		Error      string
+	}{
+		{"$.data.missing", false, 1, "No values matched $.data.missing"},
+		{"$.data.missing", true, 0, ""},
+		{"data.users", false, 1, "Invalid -jsonpath: expression must start with $"},
+		{"$.data.users[?(@.age >> 1)]", false, 1, "Invalid -jsonpath: at position"},
+		{"$.data.users[0", false, 1, "Invalid -jsonpath: at position"},
+	}
+	for _, tc := range errors {
+		ui := cli.NewMockUi()
+		opts := &meta.OutputOptions{JSONPath: tc.Expr, JSONPathAllowEmpty: tc.AllowEmpty}
+		if code := OutputSecret(ui, "table", s, opts); code != tc.Code {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Expr, code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+			t.Fatalf("%s: expected error %q, got: %s", tc.Expr, tc.Error, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != "" {
+			t.Fatalf("%s: bad output: %q", tc.Expr, output)
+		}
+	}
+}
+
+func TestOutputSecret_jsonPathSingle(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"name": "alice"},
+				map[string]interface{}{"name": "bob"},
+			},
+		},
+	}
+
+	cases := []struct {
+		Expr   string
+		Code   int
+		Output string
+		Error  string
+	}{
+		{"$.data.missing", 1, "", "No values matched $.data.missing"},
+		{"$.data.users[0].name", 0, "alice\n", ""},
+		{"$.data.users[*].name", 1, "", "$.data.users[*].name is ambiguous: it matched 2 values"},
+	}
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		opts := &meta.OutputOptions{JSONPath: tc.Expr, FieldSingle: true}
+		if code := OutputSecret(ui, "table", s, opts); code != tc.Code {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Expr, code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+			t.Fatalf("%s: expected error %q, got: %s", tc.Expr, tc.Error, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != tc.Output {
+			t.Fatalf("%s: bad output: %q", tc.Expr, output)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// jsonSchema is a parsed JSON Schema, as used by -response-schema. Only the
+// commonly used validation keywords are supported: type, enum, properties,
+// required, additionalProperties, items, minItems, maxItems, minLength,
+// maxLength, pattern, minimum and maximum. Other keywords are ignored.
+type jsonSchema struct {
+	Types []string
+	Enum  []interface{}
+
+	Properties           map[string]*jsonSchema
+	Required             []string
+	AdditionalProperties *jsonSchema
+	NoAdditional         bool
+
+	Items              *jsonSchema
+	MinItems, MaxItems *int
+
+	MinLength, MaxLength *int
+	Pattern              *regexp.Regexp
+
+	Minimum, Maximum *float64
+}
+
+var jsonSchemaTypes = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"integer": true,
+	"number":  true,
+	"string":  true,
+	"array":   true,
+	"object":  true,
+}
+
+// loadJSONSchema reads and parses the JSON Schema in the given file.
+func loadJSONSchema(path string) (*jsonSchema, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading JSON schema: %s", err)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(contents, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing JSON schema: %s", err)
+	}
+
+	schema, err := parseJSONSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %s", err)
+	}
+	return schema, nil
+}
+
+// parseJSONSchema parses a schema in its JSON form.
+func parseJSONSchema(raw interface{}) (*jsonSchema, error) {
+	if b, ok := raw.(bool); ok {
+		// true accepts everything and false accepts nothing
+		if b {
+			return &jsonSchema{}, nil
+		}
+		return &jsonSchema{Enum: []interface{}{}}, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %v", raw)
+	}
+
+	s := &jsonSchema{}
+	switch typ := m["type"].(type) {
+	case nil:
+	case string:
+		s.Types = []string{typ}
+	case []interface{}:
+		for _, t := range typ {
+			t, _ := t.(string)
+			s.Types = append(s.Types, t)
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %v", typ)
+	}
+	for _, t := range s.Types {
+		if !jsonSchemaTypes[t] {
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+	}
+
+	if enum, ok := m["enum"]; ok {
+		values, ok := enum.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("enum must be an array")
+		}
+		s.Enum = values
+	}
+
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		s.Properties = make(map[string]*jsonSchema, len(props))
+		for name, prop := range props {
+			p, err := parseJSONSchema(prop)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %s", name, err)
+			}
+			s.Properties[name] = p
+		}
+	}
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	switch additional := m["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.NoAdditional = !additional
+	default:
+		p, err := parseJSONSchema(additional)
+		if err != nil {
+			return nil, fmt.Errorf("additionalProperties: %s", err)
+		}
+		s.AdditionalProperties = p
+	}
+
+	if items, ok := m["items"]; ok {
+		p, err := parseJSONSchema(items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %s", err)
+		}
+		s.Items = p
+	}
+
+	var err error
+	for key, dst := range map[string]**int{
+		"minItems":  &s.MinItems,
+		"maxItems":  &s.MaxItems,
+		"minLength": &s.MinLength,
+		"maxLength": &s.MaxLength,
+	} {
+		if *dst, err = jsonSchemaInt(m, key); err != nil {
+			return nil, err
+		}
+	}
+	for key, dst := range map[string]**float64{
+		"minimum": &s.Minimum,
+		"maximum": &s.Maximum,
+	} {
+		if v, ok := m[key]; ok {
+			f, ok := avroNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a number", key)
+			}
+			*dst = &f
+		}
+	}
+
+	if pattern, ok := m["pattern"]; ok {
+		p, _ := pattern.(string)
+		if s.Pattern, err = regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %s", err)
+		}
+	}
+
+	return s, nil
+}
+
+// jsonSchemaInt returns the value of a keyword that takes a non-negative
+// integer, or nil if it is not set.
+func jsonSchemaInt(m map[string]interface{}, key string) (*int, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := avroNumber(v)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	i := int(f)
+	return &i, nil
+}
+
+// validate checks v against the schema and returns an error for each part of
+// v that does not match. Errors are prefixed with the dotted path of the
+// offending field, as used by -field, such as "a.b" or "list.0".
+func (s *jsonSchema) validate(v interface{}, path string) []string {
+	errorf := func(format string, args ...interface{}) []string {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		return []string{msg}
+	}
+
+	typ := jsonSchemaType(v)
+	if len(s.Types) > 0 {
+		ok := false
+		for _, t := range s.Types {
+			if t == typ || (t == "number" && typ == "integer") {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errorf("expected %s, got %s", joinTypes(s.Types), typ)
+		}
+	}
+
+	if s.Enum != nil {
+		ok := false
+		for _, e := range s.Enum {
+			if jsonSchemaEqual(v, e) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errorf("%v is not one of the allowed values", v)
+		}
+	}
+
+	var errs []string
+	switch v := v.(type) {
+	case string:
+		n := len([]rune(v))
+		if s.MinLength != nil && n < *s.MinLength {
+			errs = append(errs, errorf("shorter than %d characters", *s.MinLength)...)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs = append(errs, errorf("longer than %d characters", *s.MaxLength)...)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			errs = append(errs, errorf("does not match the pattern %q", s.Pattern.String())...)
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs = append(errs, errorf("fewer than %d items", *s.MinItems)...)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs = append(errs, errorf("more than %d items", *s.MaxItems)...)
+		}
+		if s.Items != nil {
+			for i, elem := range v {
+				errs = append(errs, s.Items.validate(elem, joinFieldPath(path, fmt.Sprint(i)))...)
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required field", joinFieldPath(path, name)))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fieldPath := joinFieldPath(path, k)
+			if prop, ok := s.Properties[k]; ok {
+				errs = append(errs, prop.validate(v[k], fieldPath)...)
+			} else if s.NoAdditional {
+				errs = append(errs, fmt.Sprintf("%s: field not allowed by the schema", fieldPath))
+			} else if s.AdditionalProperties != nil {
+				errs = append(errs, s.AdditionalProperties.validate(v[k], fieldPath)...)
+			}
+		}
+
+	default:
+		if f, ok := avroNumber(v); ok {
+			if s.Minimum != nil && f < *s.Minimum {
+				errs = append(errs, errorf("%v is less than the minimum of %v", v, *s.Minimum)...)
+			}
+			if s.Maximum != nil && f > *s.Maximum {
+				errs = append(errs, errorf("%v is greater than the maximum of %v", v, *s.Maximum)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// jsonSchemaType returns the JSON Schema type of a value.
+func jsonSchemaType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		if f, ok := avroNumber(v); ok {
+			if f == math.Trunc(f) {
+				return "integer"
+			}
+			return "number"
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonSchemaEqual compares two JSON values, treating numbers of different
+// representations as equal.
+func jsonSchemaEqual(a, b interface{}) bool {
+	if fa, ok := avroNumber(a); ok {
+		fb, ok := avroNumber(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	result := ""
+	for i, t := range types {
+		switch {
+		case i == 0:
+		case i == len(types)-1:
+			result += " or "
+		default:
+			result += ", "
+		}
+		result += t
+	}
+	return result
+}
+
+func joinFieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
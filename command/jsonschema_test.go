@@ -0,0 +1,60 @@
+package command
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema_validate(t *testing.T) {
+	schema, err := loadJSONSchema(filepath.Join(FixturePath, "credentials.schema.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Data     map[string]interface{}
+		Expected []string
+	}{
+		{
+			map[string]interface{}{
+				"username": "admin",
+				"port":     json.Number("5432"),
+				"roles":    []interface{}{"read", "write"},
+				"options":  map[string]interface{}{"sslmode": "require"},
+			},
+			nil,
+		},
+		{
+			map[string]interface{}{
+				"username": "",
+				"port":     json.Number("70000"),
+				"roles":    []interface{}{"read", "admin"},
+				"options":  map[string]interface{}{"timeout": json.Number("5")},
+				"extra":    true,
+			},
+			[]string{
+				"extra: field not allowed by the schema",
+				"options.timeout: expected string, got integer",
+				"port: 70000 is greater than the maximum of 65535",
+				"roles.1: admin is not one of the allowed values",
+				"username: shorter than 1 characters",
+			},
+		},
+		{
+			map[string]interface{}{"port": "5432"},
+			[]string{
+				"username: missing required field",
+				"port: expected integer, got string",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		errs := schema.validate(tc.Data, "")
+		if !reflect.DeepEqual(errs, tc.Expected) {
+			t.Fatalf("%d: expected %#v, got %#v", i, tc.Expected, errs)
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package command
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 // ListCommand is a Command that lists data from the Vault.
@@ -16,11 +23,20 @@ type ListCommand struct {
 
 func (c *ListCommand) Run(args []string) int {
 	var format string
+	var benchmark, concurrency int
+	var recursive bool
+	var addresses string
+	var checkpointFile string
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
-	flags = c.Meta.FlagSet("list", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
+	flags = c.Meta.FlagSet("list", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
+	flags.IntVar(&benchmark, "benchmark", 0, "")
+	flags.IntVar(&concurrency, "concurrency", 1, "")
+	flags.BoolVar(&recursive, "recursive", false, "")
+	flags.StringVar(&addresses, "addresses", "", "")
+	flags.StringVar(&checkpointFile, "checkpoint-file", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -33,7 +49,11 @@ func (c *ListCommand) Run(args []string) int {
 		return 1
 	}
 
-	path := args[0]
+	path, err := expandPath(args[0])
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 	if path[0] == '/' {
 		path = path[1:]
 	}
@@ -42,6 +62,28 @@ func (c *ListCommand) Run(args []string) int {
 		path = path + "/"
 	}
 
+	if checkpointFile != "" && !recursive {
+		c.Ui.Error("-checkpoint-file can only be used with -recursive")
+		return 1
+	}
+
+	clusters := parseAddresses(addresses)
+	if len(clusters) > 0 && (recursive || benchmark != 0 || strings.ToLower(format) == "jsonl") {
+		c.Ui.Error("-addresses cannot be used with -recursive, -benchmark or the jsonl format")
+		return 1
+	}
+
+	done, err := startSyslog(&c.Meta, path)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	defer done()
+
+	if len(clusters) > 0 {
+		return c.listClusters(clusters, format, path)
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -49,6 +91,17 @@ func (c *ListCommand) Run(args []string) int {
 		return 2
 	}
 
+	if benchmark != 0 {
+		return outputBenchmark(c.Ui, format, benchmark, concurrency, c.OutputOptions(), func() error {
+			_, err := client.Logical().List(path)
+			return err
+		})
+	}
+
+	if recursive {
+		return c.listRecursive(client, format, path, checkpointFile)
+	}
+
 	secret, err = client.Logical().List(path)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -61,15 +114,222 @@ func (c *ListCommand) Run(args []string) int {
 		return 1
 	}
 	if secret.WrapInfo != nil && secret.WrapInfo.TTL != 0 {
-		return OutputSecret(c.Ui, format, secret)
+		return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 	}
 
 	if secret.Data["keys"] == nil {
+		return c.noEntries()
+	}
+
+	if strings.ToLower(format) == "jsonl" {
+		keys, _ := secret.Data["keys"].([]interface{})
+		if opts := c.OutputOptions(); opts.SortBy != "" || opts.SortDesc {
+			keys, err = sortListKeys(secret, opts.SortBy, opts.SortDesc)
+			if err != nil {
+				c.Ui.Error(err.Error())
+				return 1
+			}
+		}
+		for _, k := range keys {
+			if err := outputJSONLine(c.Ui, k, c.OutputOptions()); err != nil {
+				c.Ui.Error(err.Error())
+				return 1
+			}
+		}
+		return emptyResultCode(len(keys) == 0, c.OutputOptions())
+	}
+
+	return OutputList(c.Ui, format, secret, c.OutputOptions())
+}
+
+// listClusters lists the path on each of the clusters given by -addresses at
+// the same time and outputs the keys together, keyed by address. A failure
+// to list on one cluster does not prevent the others from being output, but
+// results in a non-zero exit code.
+func (c *ListCommand) listClusters(addresses []string, format, path string) int {
+	listed, secrets, ret := fanOut(&c.Meta, addresses, path, func(client *api.Client) (*api.Secret, error) {
+		secret, err := client.Logical().List(path)
+		if err == nil && secret == nil {
+			// An empty list is a result rather than an error
+			secret = &api.Secret{}
+		}
+		return secret, err
+	})
+	if ret == 2 {
+		return ret
+	}
+
+	if len(listed) > 0 {
+		if code := outputClusterLists(c.Ui, format, listed, secrets, c.OutputOptions()); code != 0 {
+			return code
+		}
+	}
+	return ret
+}
+
+// errListInterrupted is returned by walkList when it is interrupted.
+var errListInterrupted = errors.New("interrupted")
+
+// listRecursive lists the path and every path below it. With the jsonl
+// format each path is output as soon as it is found, so a long walk gives
+// incremental results; the other formats output all the paths at the end.
+// An interrupt stops the walk, leaving the paths output so far intact.
+//
+// With a checkpoint file, the progress of the walk is saved to it every so
+// often and when the walk stops, and a later walk of the same path resumes
+// from it, skipping the directories that were finished. The paths found
+// before are included in the output, except with the jsonl format, where
+// they were output already. The file is removed once the walk finishes.
+func (c *ListCommand) listRecursive(client *api.Client, format, path, checkpointFile string) int {
+	var cp *listCheckpoint
+	if checkpointFile != "" {
+		var err error
+		cp, err = loadListCheckpoint(checkpointFile, path)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+	}
+
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			close(stopCh)
+		case <-doneCh:
+		}
+	}()
+
+	stream := strings.ToLower(format) == "jsonl"
+	var keys []interface{}
+	if cp != nil && !stream {
+		for _, k := range cp.Keys {
+			keys = append(keys, k)
+		}
+	}
+	err := walkList(client, path, stopCh, cp, func(p string) error {
+		keys = append(keys, p)
+		if stream {
+			return outputJSONLine(c.Ui, p, c.OutputOptions())
+		}
+		return nil
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listing %s: %s", path, err))
+		if err := cp.save(); err != nil {
+			c.Ui.Error(err.Error())
+		} else if cp != nil {
+			c.Ui.Error(fmt.Sprintf("Progress saved to %s; run the command again to resume", checkpointFile))
+		}
+		return 1
+	}
+	if err := cp.remove(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if stream {
+		return emptyResultCode(len(keys) == 0, c.OutputOptions())
+	}
+
+	if len(keys) == 0 {
+		return c.noEntries()
+	}
+	secret := &api.Secret{Data: map[string]interface{}{"keys": keys}}
+	return OutputList(c.Ui, format, secret, c.OutputOptions())
+}
+
+// walkList lists the given path and, depth first, every path below it,
+// calling fn with the full path of each key as it is found. Directories are
+// passed to fn, with their trailing slash, before their contents. The
+// progress is recorded in the checkpoint, if any: directories it records as
+// finished are skipped, and keys it records are not passed to fn again.
+func walkList(client *api.Client, path string, stopCh <-chan struct{}, cp *listCheckpoint, fn func(string) error) error {
+	select {
+	case <-stopCh:
+		return errListInterrupted
+	default:
+	}
+
+	if cp.isCompleted(path) {
+		return nil
+	}
+
+	secret, err := client.Logical().List(path)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Data["keys"] == nil {
+		return cp.complete(path)
+	}
+	list, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected list response from %s", path)
+	}
+
+	keys := make([]string, 0, len(list))
+	for _, k := range list {
+		if s, ok := k.(string); ok {
+			keys = append(keys, s)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !cp.hasKey(path + k) {
+			if err := fn(path + k); err != nil {
+				return err
+			}
+			cp.addKey(path + k)
+		}
+		if strings.HasSuffix(k, "/") {
+			if err := walkList(client, path+k, stopCh, cp, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return cp.complete(path)
+}
+
+// noEntries reports that the list is empty.
+func (c *ListCommand) noEntries() int {
+	opts := c.OutputOptions()
+	if !opts.Quiet {
 		c.Ui.Error("No entries found")
-		return 0
 	}
+	return emptyResultCode(true, opts)
+}
+
+// outputJSONLine outputs v as a single line of JSON.
+func outputJSONLine(ui cli.Ui, v interface{}, opts *meta.OutputOptions) error {
+	if opts.Quiet {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ui.Output(string(b))
+	return nil
+}
+
+func (c *ListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
 
-	return OutputList(c.Ui, format, secret)
+func (c *ListCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-format":          complete.PredictOr(predictFormat, complete.PredictSet("jsonl")),
+		"-recursive":       complete.PredictNothing,
+		"-checkpoint-file": complete.PredictFiles("*"),
+		"-addresses":       complete.PredictAnything,
+		"-benchmark":       complete.PredictNothing,
+		"-concurrency":     complete.PredictNothing,
+	}
 }
 
 func (c *ListCommand) Synopsis() string {
@@ -86,12 +346,46 @@ Usage: vault list [options] path
   Retrieve a listing of available data. The data returned, if any, is backend-
   and endpoint-specific.
 
+  The path may be a template using the date and env functions, such as
+  secret/{{env "APP"}}/; see "vault read -h".
+
 General Options:
 ` + meta.GeneralOptionsUsage() + `
 Read Options:
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-`
+                          delimited table. This can also be json, yaml, csv,
+                          or jsonl, which outputs each key as a line of JSON.
+
+  -recursive              List the path and every path below it, outputting
+                          the full path of each key. With the jsonl format,
+                          each path is output as soon as it is found.
+
+  -checkpoint-file=path   With -recursive, save the progress of the list to
+                          this file every few seconds and when interrupted.
+                          Running the same command again resumes the list
+                          from the file, without listing the directories
+                          that were finished again. The file is removed once
+                          the list finishes.
+
+  -addresses=addr,...     List the path on each of these Vault servers at the
+                          same time, rather than on the one given by
+                          -address, and output the keys together. The table
+                          format prints a "=== address ===" header before the
+                          keys of each server, while the json and yaml
+                          formats key the lists by address. A failure to list
+                          on one server is reported without stopping the
+                          others.
+
+  -benchmark=n            Rather than outputting the result, list the path n
+                          times and output the throughput, the latency
+                          percentiles and the error rate. A few requests are
+                          made beforehand to warm up and are not measured.
+
+  -concurrency=1          The number of requests made at the same time with
+                          -benchmark.
+
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkpointInterval is how often the progress of a recursive list is saved
+// to the -checkpoint-file; it can be overwritten for tests.
+var checkpointInterval = 5 * time.Second
+
+// listCheckpoint records the progress of a recursive list, so that an
+// interrupted list can be resumed without listing the directories it had
+// already finished again. A nil checkpoint records nothing.
+type listCheckpoint struct {
+	// Path is the path being listed.
+	Path string `json:"path"`
+
+	// Keys are the full paths found so far, in the order they were found.
+	Keys []string `json:"keys"`
+
+	// Completed are the directories whose every key, at any depth, has
+	// been found.
+	Completed []string `json:"completed"`
+
+	file      string
+	seen      map[string]bool
+	completed map[string]bool
+	lastSave  time.Time
+}
+
+// loadListCheckpoint returns the checkpoint of the recursive list of path
+// saved in file, or a new one if the file does not exist.
+func loadListCheckpoint(file, path string) (*listCheckpoint, error) {
+	cp := &listCheckpoint{
+		Path:      path,
+		file:      file,
+		seen:      make(map[string]bool),
+		completed: make(map[string]bool),
+		lastSave:  time.Now(),
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %s", err)
+	}
+	if err := json.Unmarshal(contents, cp); err != nil {
+		return nil, fmt.Errorf("error parsing checkpoint %s: %s", file, err)
+	}
+	if cp.Path != path {
+		return nil, fmt.Errorf("checkpoint %s is of a list of %s, not %s", file, cp.Path, path)
+	}
+
+	for _, k := range cp.Keys {
+		cp.seen[k] = true
+	}
+	for _, dir := range cp.Completed {
+		cp.completed[dir] = true
+	}
+	return cp, nil
+}
+
+// isCompleted reports whether the directory was finished before.
+func (cp *listCheckpoint) isCompleted(dir string) bool {
+	return cp != nil && cp.completed[dir]
+}
+
+// hasKey reports whether the key was found before.
+func (cp *listCheckpoint) hasKey(key string) bool {
+	return cp != nil && cp.seen[key]
+}
+
+// addKey records that the key was found.
+func (cp *listCheckpoint) addKey(key string) {
+	if cp == nil {
+		return
+	}
+	cp.seen[key] = true
+	cp.Keys = append(cp.Keys, key)
+}
+
+// complete records that the directory is finished, saving the checkpoint if
+// it has not been saved for a while. The directories below it no longer need
+// to be recorded.
+func (cp *listCheckpoint) complete(dir string) error {
+	if cp == nil {
+		return nil
+	}
+
+	completed := cp.Completed[:0]
+	for _, d := range cp.Completed {
+		if strings.HasPrefix(d, dir) {
+			delete(cp.completed, d)
+			continue
+		}
+		completed = append(completed, d)
+	}
+	cp.Completed = append(completed, dir)
+	cp.completed[dir] = true
+
+	if time.Since(cp.lastSave) >= checkpointInterval {
+		return cp.save()
+	}
+	return nil
+}
+
+// save writes the checkpoint to its file. The file is replaced as a whole,
+// so an interruption while saving leaves the previous checkpoint intact.
+func (cp *listCheckpoint) save() error {
+	if cp == nil {
+		return nil
+	}
+
+	contents, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(cp.file), filepath.Base(cp.file)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error saving checkpoint: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving checkpoint: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving checkpoint: %s", err)
+	}
+	if err := os.Rename(tmp.Name(), cp.file); err != nil {
+		return fmt.Errorf("error saving checkpoint: %s", err)
+	}
+
+	cp.lastSave = time.Now()
+	return nil
+}
+
+// remove deletes the checkpoint file once the list has finished.
+func (cp *listCheckpoint) remove() error {
+	if cp == nil {
+		return nil
+	}
+	if err := os.Remove(cp.file); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing checkpoint: %s", err)
+	}
+	return nil
+}
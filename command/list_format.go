@@ -0,0 +1,117 @@
+package command
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ListFormats are the ways the keys of a list can be displayed in table
+// output with -list-format: "table" is the default table with a header,
+// "plain" gives one key per line, "tree" indents the keys by their "/"
+// separated hierarchy, and "columns" fills the width of the terminal like ls.
+var ListFormats = []string{"table", "plain", "tree", "columns"}
+
+// terminalWidth returns the width of the terminal the output is written to;
+// it can be overwritten for tests.
+var terminalWidth = func() int {
+	if w, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
+
+// listTree returns the lines of the keys drawn as a tree, where each "/"
+// separated part of a key is indented below its parent. Directories keep
+// their trailing slash.
+func listTree(keys []string) []string {
+	type node struct {
+		name     string
+		children []*node
+		index    map[string]*node
+	}
+	root := &node{index: make(map[string]*node)}
+
+	for _, k := range keys {
+		n := root
+		for len(k) > 0 {
+			part := k
+			if i := strings.Index(k, "/"); i != -1 {
+				part = k[:i+1]
+			}
+			k = k[len(part):]
+
+			child, ok := n.index[part]
+			if !ok {
+				child = &node{name: part, index: make(map[string]*node)}
+				n.index[part] = child
+				n.children = append(n.children, child)
+			}
+			n = child
+		}
+	}
+
+	var lines []string
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		for _, c := range n.children {
+			lines = append(lines, strings.Repeat("  ", depth)+c.name)
+			walk(c, depth+1)
+		}
+	}
+	walk(root, 0)
+	return lines
+}
+
+// listColumns returns the lines of the keys laid out in as many columns as
+// fit in the given width, filled top to bottom like ls.
+func listColumns(keys []string, width int) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+	const gap = 2
+
+	var rows int
+	var widths []int
+	for rows = 1; rows < len(keys); rows++ {
+		cols := (len(keys) + rows - 1) / rows
+		widths = make([]int, cols)
+		total := gap * (cols - 1)
+		for i, k := range keys {
+			if w := utf8.RuneCountInString(k); w > widths[i/rows] {
+				widths[i/rows] = w
+			}
+		}
+		for _, w := range widths {
+			total += w
+		}
+		if total <= width {
+			break
+		}
+	}
+	if rows == len(keys) {
+		widths = []int{0}
+	}
+
+	lines := make([]string, rows)
+	for r := 0; r < rows; r++ {
+		var line string
+		for c := range widths {
+			i := c*rows + r
+			if i >= len(keys) {
+				break
+			}
+			if c > 0 {
+				line += strings.Repeat(" ", gap)
+			}
+			line += keys[i]
+			if pad := widths[c] - utf8.RuneCountInString(keys[i]); pad > 0 && (c+1)*rows+r < len(keys) {
+				line += strings.Repeat(" ", pad)
+			}
+		}
+		lines[r] = line
+	}
+	return lines
+}
@@ -1,7 +1,16 @@
 package command
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/vault/http"
@@ -69,3 +78,407 @@ func TestList(t *testing.T) {
 		t.Fatalf("err: expected %#v, got %#v", exp, secret.Data)
 	}
 }
+
+func TestList_recursive(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	for _, path := range []string{"secret/a", "secret/b/c", "secret/b/d/e"} {
+		data := map[string]interface{}{"value": "bar"}
+		if _, err := client.Logical().Write(path, data); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	ui := new(cli.MockUi)
+	c := &ListCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+
+	args := []string{
+		"-address", addr,
+		"-recursive",
+		"-format", "json",
+		"secret",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var keys []string
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &keys); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"secret/a", "secret/b/", "secret/b/c", "secret/b/d/", "secret/b/d/e"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestList_recursiveStream(t *testing.T) {
+	ui := cli.NewMockUi()
+
+	// Record how many paths had been output when each list request is made
+	var l sync.Mutex
+	emitted := make(map[string]int)
+	responses := map[string]string{
+		"/v1/secret":   `{"data":{"keys":["a","b/"]}}`,
+		"/v1/secret/b": `{"data":{"keys":["c"]}}`,
+	}
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		l.Lock()
+		emitted[r.URL.Path] = strings.Count(ui.OutputWriter.String(), "\n")
+		l.Unlock()
+
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	defer ts.Close()
+
+	c := &ListCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+
+	args := []string{
+		"-address", ts.URL,
+		"-recursive",
+		"-format", "jsonl",
+		"secret",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := "\"secret/a\"\n\"secret/b/\"\n\"secret/b/c\"\n"
+	if output := ui.OutputWriter.String(); output != expected {
+		t.Fatalf("bad output:\n%s", output)
+	}
+
+	// The paths found in secret/ were output before secret/b/ was listed
+	if emitted["/v1/secret/b"] != 2 {
+		t.Fatalf("bad: %#v", emitted)
+	}
+}
+
+func TestList_failIfEmpty(t *testing.T) {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/empty":
+			fmt.Fprint(w, `{"data":{"keys":[]}}`)
+		case "/v1/secret/missing":
+			fmt.Fprint(w, `{"data":{}}`)
+		default:
+			fmt.Fprint(w, `{"data":{"keys":["foo"]}}`)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Path   string
+		Args   []string
+		Code   int
+		Output bool
+	}{
+		{"secret/", nil, 0, true},
+		{"secret/", []string{"-fail-if-empty"}, 0, true},
+		{"secret/", []string{"-fail-if-empty", "-format", "jsonl"}, 0, true},
+		{"secret/empty", nil, 0, true},
+		{"secret/empty", []string{"-fail-if-empty"}, 1, true},
+		{"secret/empty", []string{"-fail-if-empty", "-format", "jsonl"}, 1, false},
+		{"secret/empty", []string{"-fail-if-empty", "-quiet"}, 1, false},
+		{"secret/missing", nil, 0, false},
+		{"secret/missing", []string{"-fail-if-empty"}, 1, false},
+		{"secret/", []string{"-fail-if-empty", "-quiet"}, 0, false},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &ListCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := append([]string{"-address", ts.URL, "-format", "json"}, tc.Args...)
+		if code := c.Run(append(args, tc.Path)); code != tc.Code {
+			t.Fatalf("%s %v: bad: %d\n\n%s", tc.Path, tc.Args, code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); (output != "") != tc.Output {
+			t.Fatalf("%s %v: bad output:\n%s", tc.Path, tc.Args, output)
+		}
+	}
+}
+
+func TestList_sortBy(t *testing.T) {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprint(w, `{"data":{
+			"keys":["b","c","a"],
+			"key_info":{
+				"a":{"name":"zeta","size":10,"creation_time":"2017-03-01T00:00:00Z"},
+				"b":{"name":"alpha","size":9,"creation_time":"2017-01-15T12:00:00+02:00"},
+				"c":{"name":"mu","size":100,"creation_time":"2017-01-15T11:00:00Z"}
+			}
+		}}`)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Args     []string
+		Expected []interface{}
+	}{
+		{nil, []interface{}{"b", "c", "a"}},
+		{[]string{"-sort-by", "key"}, []interface{}{"a", "b", "c"}},
+		{[]string{"-sort-desc"}, []interface{}{"c", "b", "a"}},
+		{[]string{"-sort-by", "name"}, []interface{}{"b", "c", "a"}},
+		{[]string{"-sort-by", "size"}, []interface{}{"b", "a", "c"}},
+		{[]string{"-sort-by", "size", "-sort-desc"}, []interface{}{"c", "a", "b"}},
+		{[]string{"-sort-by", "creation_time"}, []interface{}{"b", "c", "a"}},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &ListCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := append([]string{"-address", ts.URL, "-format", "json"}, tc.Args...)
+		if code := c.Run(append(args, "secret/")); code != 0 {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.Args, code, ui.ErrorWriter.String())
+		}
+		var keys []interface{}
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &keys); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(keys, tc.Expected) {
+			t.Fatalf("%v: bad: %v", tc.Args, keys)
+		}
+	}
+
+	// The table has a column for each metadata field, and is sorted the same
+	ui := cli.NewMockUi()
+	c := &ListCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	if code := c.Run([]string{"-address", ts.URL, "-sort-by", "size", "secret/"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("bad: %q", lines)
+	}
+	for i, pattern := range []string{
+		`^Key\s+creation_time\s+name\s+size$`,
+		`^b\s+2017-01-15T12:00:00\+02:00\s+alpha\s+9$`,
+		`^a\s+2017-03-01T00:00:00Z\s+zeta\s+10$`,
+		`^c\s+2017-01-15T11:00:00Z\s+mu\s+100$`,
+	} {
+		line := lines[i]
+		if i > 0 {
+			line = lines[i+1]
+		}
+		if !regexpMatch(t, pattern, line) {
+			t.Fatalf("expected %q to match %s", line, pattern)
+		}
+	}
+
+	// Unknown columns are an error
+	ui = cli.NewMockUi()
+	c = &ListCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	if code := c.Run([]string{"-address", ts.URL, "-sort-by", "owner", "secret/"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), `Unknown column "owner" to sort by`) {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestList_listFormat(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	for _, path := range []string{"secret/app/db", "secret/app/web/tls", "secret/ops"} {
+		data := map[string]interface{}{"value": "bar"}
+		if _, err := client.Logical().Write(path, data); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	oldWidth := terminalWidth
+	terminalWidth = func() int { return 40 }
+	defer func() { terminalWidth = oldWidth }()
+
+	cases := []struct {
+		Format   string
+		Expected string
+	}{
+		{"plain", "secret/app/\nsecret/app/db\nsecret/app/web/\nsecret/app/web/tls\nsecret/ops\n"},
+		{"tree", "secret/\n  app/\n    db\n    web/\n      tls\n  ops\n"},
+		{"columns", "secret/app/      secret/app/web/tls\nsecret/app/db    secret/ops\nsecret/app/web/\n"},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		c := &ListCommand{
+			Meta: meta.Meta{
+				ClientToken: token,
+				Ui:          ui,
+			},
+		}
+
+		args := []string{"-address", addr, "-recursive", "-list-format", tc.Format, "secret"}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Format, code, ui.ErrorWriter.String())
+		}
+		if actual := ui.OutputWriter.String(); actual != tc.Expected {
+			t.Fatalf("%s: bad:\n%s", tc.Format, actual)
+		}
+	}
+}
+
+func TestList_addresses(t *testing.T) {
+	stub := func(keys string) *httptest.Server {
+		return httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			if keys == "" {
+				w.WriteHeader(500)
+				fmt.Fprint(w, `{"errors":["internal error"]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"keys":%s}}`, keys)
+		}))
+	}
+	a, b, broken := stub(`["x","y"]`), stub(`["z"]`), stub("")
+	defer a.Close()
+	defer b.Close()
+	defer broken.Close()
+
+	ui := new(cli.MockUi)
+	c := &ListCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	args := []string{"-addresses", strings.Join([]string{a.URL, b.URL, broken.URL}, ","), "-format", "json", "secret/"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var results map[string][]string
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &results); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string][]string{a.URL: {"x", "y"}, b.URL: {"z"}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("bad: %#v", results)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Error reading secret/ from "+broken.URL) {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestList_checkpoint(t *testing.T) {
+	tree := map[string]string{
+		"secret/":     `["a","b/","c/"]`,
+		"secret/b/":   `["d","e/"]`,
+		"secret/b/e/": `["f"]`,
+		"secret/c/":   `["g"]`,
+	}
+	var l sync.Mutex
+	var listed []string
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), "/") + "/"
+		l.Lock()
+		listed = append(listed, path)
+		l.Unlock()
+		fmt.Fprintf(w, `{"data":{"keys":%s}}`, tree[path])
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "vault-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "list.json")
+
+	client := testClient(t, ts.URL, "foo")
+	cp, err := loadListCheckpoint(file, "secret/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Interrupt the walk as it is about to list secret/c/
+	stopCh := make(chan struct{})
+	var found []string
+	err = walkList(client, "secret/", stopCh, cp, func(p string) error {
+		found = append(found, p)
+		if p == "secret/c/" {
+			close(stopCh)
+		}
+		return nil
+	})
+	if err != errListInterrupted {
+		t.Fatalf("bad: %v", err)
+	}
+	if err := cp.save(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"secret/a", "secret/b/", "secret/b/d", "secret/b/e/", "secret/b/e/f", "secret/c/"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("bad: %#v", found)
+	}
+
+	// Resuming only lists the directories that were not finished
+	listed = nil
+	ui := new(cli.MockUi)
+	c := &ListCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	args := []string{"-address", ts.URL, "-recursive", "-checkpoint-file", file, "-format", "json", "secret"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var keys []string
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &keys); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(keys, append(expected, "secret/c/g")) {
+		t.Fatalf("bad: %#v", keys)
+	}
+	if !reflect.DeepEqual(listed, []string{"secret/", "secret/c/"}) {
+		t.Fatalf("bad: %#v", listed)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected the checkpoint to be removed, got %v", err)
+	}
+}
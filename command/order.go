@@ -0,0 +1,163 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// keyPathSep separates the keys and array indexes of the path of an object
+// in the key order returned by jsonKeyOrder. It cannot be confused with the
+// dots that keys often contain.
+const keyPathSep = "\x00"
+
+// keyPath returns the path of the value under key within the value at path.
+func keyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + keyPathSep + key
+}
+
+// jsonKeyOrder returns the order the keys of every object in the JSON
+// document appear in, for -preserve-order. The orders are given by the path
+// of the object: the keys and array indexes leading to it, joined by
+// keyPathSep, with the document itself at "".
+func jsonKeyOrder(body []byte) (map[string][]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	order := make(map[string][]string)
+	if err := jsonValueKeyOrder(dec, "", order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// jsonValueKeyOrder reads the next value from the decoder, adding the key
+// order of the objects within it.
+func jsonValueKeyOrder(dec *json.Decoder, path string, order map[string][]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		keys := []string{}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			keys = append(keys, key)
+			if err := jsonValueKeyOrder(dec, keyPath(path, key), order); err != nil {
+				return err
+			}
+		}
+		order[path] = keys
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := jsonValueKeyOrder(dec, keyPath(path, strconv.Itoa(i)), order); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	// The closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+// orderedKeys returns the keys of an object in the given order. Keys that
+// the order does not have, such as ones added for output, follow in
+// alphabetical order.
+func orderedKeys(keys []string, order []string) []string {
+	present := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		present[k] = true
+	}
+
+	result := make([]string, 0, len(keys))
+	for _, k := range order {
+		if present[k] {
+			result = append(result, k)
+			delete(present, k)
+		}
+	}
+	rest := make([]string, 0, len(present))
+	for k := range present {
+		rest = append(rest, k)
+	}
+	sort.Strings(rest)
+	return append(result, rest...)
+}
+
+// orderedObject is a JSON object whose keys are encoded in a given order,
+// rather than the alphabetical order of a map.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// withKeyOrder returns the data to be output in JSON with the objects in it
+// replaced by ones whose keys are encoded in the given order, found by
+// jsonKeyOrder.
+func withKeyOrder(data interface{}, order map[string][]string) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := jsonutil.DecodeJSON(b, &v); err != nil {
+		return nil, err
+	}
+	return applyKeyOrder(v, "", order), nil
+}
+
+func applyKeyOrder(v interface{}, path string, order map[string][]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		values := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			keys = append(keys, k)
+			values[k] = applyKeyOrder(elem, keyPath(path, k), order)
+		}
+		return orderedObject{keys: orderedKeys(keys, order[path]), values: values}
+	case []interface{}:
+		elems := make([]interface{}, len(v))
+		for i, elem := range v {
+			elems[i] = applyKeyOrder(elem, keyPath(path, strconv.Itoa(i)), order)
+		}
+		return elems
+	}
+	return v
+}
@@ -0,0 +1,52 @@
+package command
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONKeyOrder(t *testing.T) {
+	body := `{"b":1,"a":{"y":[{"q":1,"p":2}],"x":null},"c.d":[[{"z":1,"w":2}]]}`
+	order, err := jsonKeyOrder([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"":  {"b", "a", "c.d"},
+		"a": {"y", "x"},
+		strings.Join([]string{"a", "y", "0"}, keyPathSep):   {"q", "p"},
+		strings.Join([]string{"c.d", "0", "0"}, keyPathSep): {"z", "w"},
+	}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("bad: %q", order)
+	}
+
+	// Encoding the decoded document with the order reproduces it
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(applyKeyOrder(v, "", order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Fatalf("bad: %s", b)
+	}
+
+	if _, err := jsonKeyOrder([]byte(`{"a":`)); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
+
+func TestOrderedKeys(t *testing.T) {
+	keys := []string{"age", "b", "a", "c"}
+	actual := orderedKeys(keys, []string{"c", "gone", "a", "b", "a"})
+	expected := []string{"c", "a", "b", "age"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %v", actual)
+	}
+}
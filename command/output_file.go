@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// outputFile is how the files that output is written to are created: their
+// mode, and their owner and group, which are -1 to leave them unchanged.
+type outputFile struct {
+	mode os.FileMode
+	uid  int
+	gid  int
+}
+
+// parseOutputFile parses -file-mode, -file-owner and -file-group. The
+// owner and group are names or numeric IDs. By default the files are only
+// readable by the user, as they hold secrets.
+func parseOutputFile(mode, owner, group string) (*outputFile, error) {
+	f := &outputFile{mode: 0600, uid: -1, gid: -1}
+
+	if mode != "" {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || m > 0777 {
+			return nil, fmt.Errorf("invalid -file-mode %q: expected octal permissions such as 0640", mode)
+		}
+		f.mode = os.FileMode(m)
+	}
+
+	if owner != "" {
+		u, err := user.Lookup(owner)
+		if err != nil {
+			u, err = user.LookupId(owner)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid -file-owner %q: no such user", owner)
+		}
+		if f.uid, err = strconv.Atoi(u.Uid); err != nil {
+			return nil, fmt.Errorf("invalid -file-owner %q: user ID %q is not numeric", owner, u.Uid)
+		}
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			g, err = user.LookupGroupId(group)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid -file-group %q: no such group", group)
+		}
+		if f.gid, err = strconv.Atoi(g.Gid); err != nil {
+			return nil, fmt.Errorf("invalid -file-group %q: group ID %q is not numeric", group, g.Gid)
+		}
+	}
+
+	return f, nil
+}
+
+// write writes the data to the file at path atomically: it is written to a
+// temporary file in the same directory, which is given its mode and owner
+// before being renamed into place, so that the file is never seen partly
+// written or with the wrong permissions.
+func (f *outputFile) write(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), f.mode); err != nil {
+		return err
+	}
+	if f.uid != -1 || f.gid != -1 {
+		if err := os.Chown(tmp.Name(), f.uid, f.gid); err != nil {
+			if os.IsPermission(err) {
+				return fmt.Errorf(
+					"changing the owner or group of %s is not permitted; "+
+						"-file-owner and -file-group usually need root", path)
+			}
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// pathNow returns the time that the date function of a path template
+// formats; it can be overwritten for tests.
+var pathNow = time.Now
+
+// pathTemplateFuncs are the functions of path templates. date formats the
+// current time in UTC with the layout of the Go time package, and env returns
+// the value of an environment variable, which must be set.
+var pathTemplateFuncs = template.FuncMap{
+	"date": func(layout string) string {
+		return pathNow().UTC().Format(layout)
+	},
+	"env": func(name string) (string, error) {
+		v := os.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	},
+}
+
+// expandPath expands the template in a path argument, such as
+// secret/logs/{{date "2006-01-02"}} or secret/{{env "APP"}}/config, so
+// that it is done before any request is made. Paths without "{{" are
+// returned unchanged.
+func expandPath(path string) (string, error) {
+	if !strings.Contains(path, "{{") {
+		return path, nil
+	}
+
+	tmpl, err := template.New("path").Funcs(pathTemplateFuncs).Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path template %q: %s", path, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		// Drop the position in the template before the error of the function
+		msg := err.Error()
+		if i := strings.LastIndex(msg, "error calling "); i != -1 {
+			msg = msg[i:]
+		}
+		return "", fmt.Errorf("error expanding path %q: %s", path, msg)
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("path %q expands to an empty path", path)
+	}
+	return buf.String(), nil
+}
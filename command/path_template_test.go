@@ -0,0 +1,87 @@
+package command
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestExpandPath(t *testing.T) {
+	defer func(orig func() time.Time) { pathNow = orig }(pathNow)
+	pathNow = func() time.Time {
+		return time.Date(2017, 8, 31, 23, 30, 0, 0, time.FixedZone("PDT", -7*60*60))
+	}
+	defer os.Setenv("VAULT_TEST_APP", os.Getenv("VAULT_TEST_APP"))
+	os.Setenv("VAULT_TEST_APP", "billing")
+	os.Unsetenv("VAULT_TEST_UNSET")
+
+	cases := []struct {
+		path     string
+		expected string
+		err      string
+	}{
+		{"secret/foo", "secret/foo", ""},
+		{`secret/logs/{{date "2006-01-02"}}`, "secret/logs/2017-09-01", ""},
+		{`secret/{{env "VAULT_TEST_APP"}}/config`, "secret/billing/config", ""},
+		{`secret/{{env "VAULT_TEST_UNSET"}}/config`, "", "environment variable VAULT_TEST_UNSET is not set"},
+		{`secret/{{hostname}}`, "", `function "hostname" not defined`},
+		{`secret/{{date}}`, "", "wrong number of args"},
+		{`{{"" | printf}}`, "", "expands to an empty path"},
+	}
+	for _, tc := range cases {
+		actual, err := expandPath(tc.path)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("%s: expected error %q, got %v", tc.path, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.path, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("%s: bad: %q", tc.path, actual)
+		}
+	}
+}
+
+func TestRead_pathTemplate(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+
+	defer os.Setenv("VAULT_TEST_APP", os.Getenv("VAULT_TEST_APP"))
+	os.Setenv("VAULT_TEST_APP", "billing")
+	os.Unsetenv("VAULT_TEST_UNSET")
+
+	ui := cli.NewMockUi()
+	c := &ReadCommand{Meta: meta.Meta{ClientToken: "foo", Ui: ui}}
+	args := []string{"-address", server.URL, `secret/{{env "VAULT_TEST_APP"}}/config`}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if len(paths) != 1 || paths[0] != "/v1/secret/billing/config" {
+		t.Fatalf("bad: %v", paths)
+	}
+
+	// A path that fails to expand fails before any request is made
+	paths = nil
+	ui = cli.NewMockUi()
+	c = &ReadCommand{Meta: meta.Meta{ClientToken: "foo", Ui: ui}}
+	args = []string{"-address", server.URL, "secret/foo", `secret/{{env "VAULT_TEST_UNSET"}}`}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if len(paths) != 0 || !strings.Contains(ui.ErrorWriter.String(), "VAULT_TEST_UNSET is not set") {
+		t.Fatalf("bad: %v %s", paths, ui.ErrorWriter.String())
+	}
+}
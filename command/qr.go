@@ -0,0 +1,99 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	// qrQuietZone is the number of light modules around a QR code, which
+	// scanners need to find it.
+	qrQuietZone = 4
+
+	// qrMaxModules is the widest QR code that is rendered, so that it fits
+	// in an 80 column terminal with its quiet zone.
+	qrMaxModules = 80 - 2*qrQuietZone
+)
+
+// An output formatter that renders a value as a QR code in the terminal, for
+// transferring short secrets such as a TOTP seed to a phone. The value is
+// the field given by -field, or else the only field of the secret.
+type QRFormatter struct {
+}
+
+func (q QRFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	s, ok := data.(*api.Secret)
+	if !ok || len(s.Data) != 1 {
+		return errors.New("the qr format needs -field to select a single value")
+	}
+	var value interface{}
+	for _, v := range s.Data {
+		value = v
+	}
+	return outputQR(ui, value)
+}
+
+// outputQR outputs the value as a QR code.
+func outputQR(ui cli.Ui, value interface{}) error {
+	code, err := renderQR(fmt.Sprintf("%v", value))
+	if err != nil {
+		return err
+	}
+	ui.Output(code)
+	return nil
+}
+
+// renderQR returns the lines of the QR code of the value drawn with block
+// characters, two rows of modules to a line. Light modules are drawn and
+// dark ones left blank, so the code scans on the usual dark terminal.
+func renderQR(value string) (string, error) {
+	// The encoder's own error for a value that is too long includes the
+	// value, so it is not passed on
+	code, err := qr.Encode(value, qr.M, qr.Auto)
+	if err == nil && code.Bounds().Dx() > qrMaxModules {
+		err = errors.New("too long")
+	}
+	if err != nil {
+		return "", fmt.Errorf(
+			"the value of %d bytes is too long to output as a QR code that fits in "+
+				"the terminal; QR codes of up to about 330 bytes are supported", len(value))
+	}
+
+	size := code.Bounds().Dx()
+	light := func(x, y int) bool {
+		x, y = x-qrQuietZone, y-qrQuietZone
+		if x < 0 || y < 0 || x >= size || y >= size {
+			return true
+		}
+		return code.At(x, y) != color.Black
+	}
+
+	var buf bytes.Buffer
+	total := size + 2*qrQuietZone
+	for y := 0; y < total; y += 2 {
+		if y > 0 {
+			buf.WriteByte('\n')
+		}
+		for x := 0; x < total; x++ {
+			top, bottom := light(x, y), y+1 < total && light(x, y+1)
+			switch {
+			case top && bottom:
+				buf.WriteString("█")
+			case top:
+				buf.WriteString("▀")
+			case bottom:
+				buf.WriteString("▄")
+			default:
+				buf.WriteString(" ")
+			}
+		}
+	}
+	return buf.String(), nil
+}
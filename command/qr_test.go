@@ -0,0 +1,68 @@
+package command
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestQRFormatter(t *testing.T) {
+	const value = "otpauth://totp/Vault:alice?secret=JBSWY3DPEHPK3PXP"
+	s := &api.Secret{
+		Data: map[string]interface{}{"url": value},
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "qr", s, &meta.OutputOptions{}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// Turn the blocks back into modules and compare them to the code
+	lines := strings.Split(strings.TrimSuffix(ui.OutputWriter.String(), "\n"), "\n")
+	var rows [][]bool
+	for _, line := range lines {
+		var top, bottom []bool
+		for _, r := range line {
+			top = append(top, r == '█' || r == '▀')
+			bottom = append(bottom, r == '█' || r == '▄')
+		}
+		rows = append(rows, top, bottom)
+	}
+
+	expected, err := qr.Encode(value, qr.M, qr.Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := expected.Bounds().Dx()
+	if len(lines) != (size+2*qrQuietZone+1)/2 {
+		t.Fatalf("bad: expected %d modules, got %d lines", size, len(lines))
+	}
+	for y := -qrQuietZone; y < size+qrQuietZone; y++ {
+		for x := -qrQuietZone; x < size+qrQuietZone; x++ {
+			light := x < 0 || y < 0 || x >= size || y >= size || expected.At(x, y) != color.Black
+			row := rows[y+qrQuietZone]
+			if len(row) != size+2*qrQuietZone || row[x+qrQuietZone] != light {
+				t.Fatalf("bad module at %d,%d:\n%s", x, y, ui.OutputWriter.String())
+			}
+		}
+	}
+
+	// Several fields need -field, and long values do not fit
+	s.Data["other"] = "x"
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "qr", s, &meta.OutputOptions{}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "needs -field") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+
+	if _, err := renderQR(strings.Repeat("x", 400)); err == nil || !strings.Contains(err.Error(), "400 bytes") {
+		t.Fatalf("expected size error, got %v", err)
+	}
+}
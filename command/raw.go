@@ -0,0 +1,83 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// An output formatter for raw output. The read command writes the body of
+// the response exactly as the server sent it; the other commands no longer
+// have the body once it has been parsed, so the formatter writes the data as
+// compact JSON. In both cases nothing is sanitized and no trailing newline is
+// added.
+type RawFormatter struct {
+}
+
+func (r RawFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	outputRaw(ui, string(b))
+	return nil
+}
+
+// isRawFormat reports whether the format is the raw format.
+func isRawFormat(format string) bool {
+	return strings.ToLower(format) == "raw"
+}
+
+// rawOutputUi is a cli.Ui wrapping another that can write without a
+// trailing newline through it.
+type rawOutputUi interface {
+	cli.Ui
+	OutputRaw(s string)
+}
+
+// outputRaw writes s without a trailing newline.
+func outputRaw(ui cli.Ui, s string) {
+	if r, ok := ui.(rawOutputUi); ok {
+		r.OutputRaw(s)
+		return
+	}
+
+	// c.Ui.Output() prints a CR character which in this case is
+	// not desired. Since Vault CLI currently only uses BasicUi,
+	// its writer is used here to directly print the message. If
+	// mitchellh/cli exposes method to print without CR, this check
+	// needs to be removed.
+	if b, ok := ui.(*cli.BasicUi); ok && b.Writer != nil {
+		fmt.Fprint(b.Writer, s)
+	} else {
+		ui.Output(s)
+	}
+}
+
+// readRaw reads the path and returns the body of the response unparsed. It
+// returns false if there is no value at the path. When the response is
+// wrapped, the body is the wrap info rather than the secret.
+func readRaw(client *api.Client, path string) (string, bool, error) {
+	r := client.NewRequest("GET", "/v1/"+path)
+	resp, err := client.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, err
+	}
+	return string(body), true, nil
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/flag-slice"
 	"github.com/hashicorp/vault/meta"
 	"github.com/posener/complete"
 )
@@ -17,28 +18,128 @@ type ReadCommand struct {
 
 func (c *ReadCommand) Run(args []string) int {
 	var format string
-	var field string
+	var benchmark, concurrency int
+	var fields []string
+	var fieldDefault string
+	var groupByPath bool
+	var diffVersions string
+	var requireFields []string
+	var responseSchema string
+	var addresses string
+	var execute bool
+	var preserveOrder bool
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
-	flags = c.Meta.FlagSet("read", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
-	flags.StringVar(&field, "field", "", "")
+	flags = c.Meta.FlagSet("read", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
+	flags.IntVar(&benchmark, "benchmark", 0, "")
+	flags.IntVar(&concurrency, "concurrency", 1, "")
+	flags.Var((*sliceflag.StringFlag)(&fields), "field", "")
+	flags.StringVar(&fieldDefault, "field-default", "", "")
+	flags.BoolVar(&groupByPath, "group-by-path", true, "")
+	flags.StringVar(&diffVersions, "diff-versions", "", "")
+	flags.Var((*sliceflag.StringFlag)(&requireFields), "require-field", "")
+	flags.StringVar(&responseSchema, "response-schema", "", "")
+	flags.StringVar(&addresses, "addresses", "", "")
+	flags.BoolVar(&execute, "exec", false, "")
+	flags.BoolVar(&preserveOrder, "preserve-order", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
 	args = flags.Args()
-	if len(args) != 1 || len(args[0]) == 0 {
-		c.Ui.Error("read expects one argument")
+	if len(args) < 1 {
+		c.Ui.Error("read expects at least one argument")
 		flags.Usage()
 		return 1
 	}
 
-	path := args[0]
-	if path[0] == '/' {
-		path = path[1:]
+	// With -exec, everything after the path is the command to run
+	var command []string
+	if execute {
+		args, command = args[:1], args[1:]
+		if len(command) > 0 && command[0] == "--" {
+			command = command[1:]
+		}
+		if len(command) == 0 {
+			c.Ui.Error("-exec expects the command to run after the path")
+			flags.Usage()
+			return 1
+		}
+		if len(fields) > 0 || len(requireFields) > 0 || benchmark != 0 || diffVersions != "" || addresses != "" {
+			c.Ui.Error("-exec cannot be used with -field, -require-field, -benchmark, -diff-versions or -addresses")
+			return 1
+		}
+	}
+
+	for i, arg := range args {
+		if len(arg) == 0 {
+			c.Ui.Error("read expects non-empty path arguments")
+			flags.Usage()
+			return 1
+		}
+		path, err := expandPath(arg)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		args[i] = path
+	}
+
+	if len(args) > 1 && (len(fields) > 0 || len(requireFields) > 0 || preserveOrder) {
+		c.Ui.Error("-field, -require-field and -preserve-order cannot be used when reading multiple paths")
+		return 1
+	}
+
+	if benchmark != 0 && (len(args) > 1 || len(fields) > 0 || diffVersions != "") {
+		c.Ui.Error("-benchmark cannot be used with multiple paths, -field or -diff-versions")
+		return 1
+	}
+
+	var fromVersion, toVersion int
+	if diffVersions != "" {
+		if len(args) > 1 || len(fields) > 0 {
+			c.Ui.Error("-diff-versions cannot be used with multiple paths or -field")
+			return 1
+		}
+		fromVersion, toVersion, err = parseVersions(diffVersions)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid -diff-versions: %s", err))
+			return 1
+		}
+	}
+
+	var schema *jsonSchema
+	if responseSchema != "" {
+		if benchmark != 0 || diffVersions != "" {
+			c.Ui.Error("-response-schema cannot be used with -benchmark or -diff-versions")
+			return 1
+		}
+		schema, err = loadJSONSchema(responseSchema)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+	}
+
+	clusters := parseAddresses(addresses)
+	if len(clusters) > 0 && (len(args) > 1 || len(fields) > 0 || len(requireFields) > 0 ||
+		benchmark != 0 || diffVersions != "") {
+		c.Ui.Error("-addresses cannot be used with multiple paths, -field, -require-field, -benchmark or -diff-versions")
+		return 1
+	}
+
+	done, err := startSyslog(&c.Meta, strings.Join(args, ","))
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	defer done()
+
+	if len(clusters) > 0 {
+		return c.readClusters(clusters, format, args[0], schema)
 	}
 
 	client, err := c.Client()
@@ -48,7 +149,35 @@ func (c *ReadCommand) Run(args []string) int {
 		return 2
 	}
 
-	secret, err = client.Logical().Read(path)
+	if len(args) > 1 {
+		return c.readMultiple(client, format, args, groupByPath, schema)
+	}
+
+	path := args[0]
+	if path[0] == '/' {
+		path = path[1:]
+	}
+
+	if diffVersions != "" {
+		return c.readDiff(client, format, path, fromVersion, toVersion)
+	}
+
+	if benchmark != 0 {
+		return outputBenchmark(c.Ui, format, benchmark, concurrency, c.OutputOptions(), func() error {
+			_, err := client.Logical().Read(path)
+			return err
+		})
+	}
+
+	if isRawFormat(format) && len(fields) == 0 && schema == nil && len(requireFields) == 0 && !execute {
+		return c.readRaw(client, path)
+	}
+
+	if preserveOrder {
+		secret, err = c.readOrdered(client, path)
+	} else {
+		secret, err = client.Logical().Read(path)
+	}
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error reading %s: %s", path, err))
@@ -60,12 +189,226 @@ func (c *ReadCommand) Run(args []string) int {
 		return 1
 	}
 
-	// Handle single field output
-	if field != "" {
-		return PrintRawField(c.Ui, secret, field)
+	if schema != nil && !c.validateResponse(schema, path, secret) {
+		return 1
+	}
+
+	if execute {
+		return execWithSecret(c.Ui, secret, command)
+	}
+
+	if len(requireFields) > 0 {
+		return c.requireFields(secret, requireFields)
+	}
+
+	// Handle field output
+	if len(fields) > 0 {
+		opts := c.OutputOptions()
+		if strings.ToLower(format) == "qr" {
+			if len(fields) > 1 {
+				c.Ui.Error("The qr format can only output a single -field")
+				return 1
+			}
+			return c.outputQRField(secret, fields[0])
+		}
+		if isRawFormat(format) {
+			// The raw format outputs the value exactly as it is stored
+			unsanitized := *opts
+			unsanitized.NoSanitize = true
+			opts = &unsanitized
+		}
+		if flagIsSet(flags, "field-default") {
+			return PrintRawFieldsDefault(c.Ui, secret, fields, fieldDefault, opts)
+		}
+		return PrintRawFields(c.Ui, secret, fields, opts)
+	}
+
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
+}
+
+// requireFields checks that the secret has each of the given fields, without
+// outputting it.
+func (c *ReadCommand) requireFields(secret *api.Secret, fields []string) int {
+	var missing []string
+	for _, field := range fields {
+		if _, ok := lookupField(secret.Data, field); !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		c.Ui.Error(fmt.Sprintf(
+			"Required fields not present in secret: %s", strings.Join(missing, ", ")))
+		return 1
+	}
+	return 0
+}
+
+// validateResponse checks the data of the secret against the schema given by
+// -response-schema, reporting each field that does not match.
+func (c *ReadCommand) validateResponse(schema *jsonSchema, path string, secret *api.Secret) bool {
+	errs := schema.validate(secret.Data, "")
+	if len(errs) == 0 {
+		return true
+	}
+	c.Ui.Error(fmt.Sprintf(
+		"Response from %s does not match the schema:\n  %s", path, strings.Join(errs, "\n  ")))
+	return false
+}
+
+// outputQRField outputs the value of a single field of the secret as a QR
+// code, for -format=qr.
+func (c *ReadCommand) outputQRField(secret *api.Secret, field string) int {
+	value, ok := lookupField(secret.Data, field)
+	if !ok {
+		c.Ui.Error(fieldNotPresent(secret.Data, field))
+		return 1
+	}
+	if c.OutputOptions().Quiet {
+		return 0
+	}
+	if err := outputQR(c.Ui, value); err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not output secret: %s", err))
+		return 1
+	}
+	return 0
+}
+
+// readOrdered reads the path like Logical().Read, and records the order of
+// the keys in the body of the response for -preserve-order.
+func (c *ReadCommand) readOrdered(client *api.Client, path string) (*api.Secret, error) {
+	body, ok, err := readRaw(client, path)
+	if err != nil || !ok {
+		return nil, err
+	}
+	secret, err := api.ParseSecret(strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	order, err := jsonKeyOrder([]byte(body))
+	if err != nil {
+		return nil, err
+	}
+	c.OutputOptions().KeyOrder = order
+	return secret, nil
+}
+
+// readRaw reads the path and writes the body of the response to the output
+// exactly as the server sent it, for -format=raw.
+func (c *ReadCommand) readRaw(client *api.Client, path string) int {
+	body, ok, err := readRaw(client, path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error reading %s: %s", path, err))
+		return 1
+	}
+	if !ok {
+		c.Ui.Error(fmt.Sprintf(
+			"No value found at %s", path))
+		return 1
+	}
+	if c.OutputOptions().Quiet {
+		return 0
+	}
+
+	outputRaw(c.Ui, body)
+	return 0
+}
+
+// readMultiple reads each of the given paths and outputs the results
+// together. A failure to read one path does not prevent the others from
+// being read, but results in a non-zero exit code.
+func (c *ReadCommand) readMultiple(client *api.Client, format string, paths []string, groupByPath bool, schema *jsonSchema) int {
+	ret := 0
+	read := make([]string, 0, len(paths))
+	secrets := make(map[string]*api.Secret, len(paths))
+	for _, path := range paths {
+		if path[0] == '/' {
+			path = path[1:]
+		}
+
+		secret, err := client.Logical().Read(path)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf(
+				"Error reading %s: %s", path, err))
+			ret = 1
+			continue
+		}
+		if secret == nil {
+			c.Ui.Error(fmt.Sprintf(
+				"No value found at %s", path))
+			ret = 1
+			continue
+		}
+		if schema != nil && !c.validateResponse(schema, path, secret) {
+			ret = 1
+			continue
+		}
+
+		read = append(read, path)
+		secrets[path] = secret
+	}
+
+	if len(read) > 0 {
+		if code := OutputSecrets(c.Ui, format, read, secrets, groupByPath, c.OutputOptions()); code != 0 {
+			return code
+		}
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return ret
+}
+
+// readClusters reads the path from each of the clusters given by -addresses
+// at the same time and outputs the results together, keyed by address. A
+// failure to read from one cluster does not prevent the others from being
+// output, but results in a non-zero exit code.
+func (c *ReadCommand) readClusters(addresses []string, format, path string, schema *jsonSchema) int {
+	if path[0] == '/' {
+		path = path[1:]
+	}
+
+	read, secrets, ret := fanOut(&c.Meta, addresses, path, func(client *api.Client) (*api.Secret, error) {
+		return client.Logical().Read(path)
+	})
+	if ret == 2 {
+		return ret
+	}
+
+	if schema != nil {
+		valid := read[:0]
+		for _, addr := range read {
+			if !c.validateResponse(schema, fmt.Sprintf("%s on %s", path, addr), secrets[addr]) {
+				delete(secrets, addr)
+				ret = 1
+				continue
+			}
+			valid = append(valid, addr)
+		}
+		read = valid
+	}
+
+	if len(read) > 0 {
+		if code := OutputSecrets(c.Ui, format, read, secrets, true, c.OutputOptions()); code != 0 {
+			return code
+		}
+	}
+	return ret
+}
+
+// readDiff reads two versions of a versioned secret and outputs the keys
+// that differ between them.
+func (c *ReadCommand) readDiff(client *api.Client, format, path string, from, to int) int {
+	old, err := readVersion(client, path, from)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	new, err := readVersion(client, path, to)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	return outputWithFormat(c.Ui, format, nil, diffData(old, new), c.OutputOptions())
 }
 
 func (c *ReadCommand) Synopsis() string {
@@ -74,7 +417,8 @@ func (c *ReadCommand) Synopsis() string {
 
 func (c *ReadCommand) Help() string {
 	helpText := `
-Usage: vault read [options] path
+Usage: vault read [options] path [path...]
+       vault read -exec [options] path [--] command [args...]
 
   Read data from Vault.
 
@@ -83,17 +427,97 @@ Usage: vault read [options] path
   materialized backends. Please reference the documentation for the
   backends in use to determine key structure.
 
+  If more than one path is given, each path is read in turn and the results
+  are output together.
+
+  A path may be a template, which is expanded before anything is read:
+  {{date "2006-01-02"}} is the current date in UTC in the layout of the Go
+  time package, and {{env "APP"}} the value of an environment variable,
+  which must be set. For example, secret/logs/{{date "2006-01-02"}}.
+
+  With -exec, the secret is not output. Instead the command is run with each
+  field of the secret in its environment, and the exit code of the command
+  is returned. Nested fields are flattened, and the names uppercased with
+  anything other than letters, digits, and underscores replaced by an
+  underscore, so "db.password" becomes DB_PASSWORD. For a versioned secret
+  only its data is used. The secret is never written to disk.
+
 General Options:
 ` + meta.GeneralOptionsUsage() + `
 Read Options:
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+                          delimited table. This can also be json, yaml, csv,
+                          or raw. The raw format writes the body of the
+                          response exactly as the server sent it, without a
+                          trailing newline; with -field it writes the value
+                          of the field unsanitized. A response wrapped with
+                          -wrap-ttl is written as the wrap info JSON as-is.
+                          The qr format renders the value of -field, or the
+                          only field of the secret, as a QR code.
 
   -field=field            If included, the raw value of the specified field
-                          will be output raw to stdout.
+                          will be output raw to stdout. Nested fields can be
+                          given as a dotted path, such as "a.b" or "list.0".
+                          This can be specified multiple times to output
+                          several fields on one line, separated by tabs in
+                          the order given. This cannot be used when reading
+                          multiple paths.
+
+  -require-field=field    Rather than outputting the secret, check that it has
+                          the given field and exit with a non-zero code if it
+                          does not. This can be specified multiple times to
+                          require several fields, and nested fields can be
+                          given as a dotted path like -field, such as "a.b".
+
+  -response-schema=file   Validate the data of the secret against the JSON
+                          Schema in the given file and exit with a non-zero
+                          code, listing the offending fields, if it does not
+                          match. When reading multiple paths, secrets that do
+                          not match are not output.
+
+  -preserve-order         Output the fields of the secret in the order the
+                          server sent them, rather than sorted by name. This
+                          applies to the table format and to every object in
+                          the json format. This cannot be used when reading
+                          multiple paths.
+
+  -field-default=value    The value to output when the field given by -field
+                          is not present in the secret. By default a missing
+                          field is an error.
+
+  -group-by-path=true     When reading multiple paths with the table format,
+                          print a "=== path ===" header before each result.
+                          The json and yaml formats always key the results
+                          by path.
+
+  -diff-versions=from,to  Read two versions of a versioned secret and output
+                          the keys that were added, removed or changed
+                          between them, for example -diff-versions=3,5.
+                          The json and yaml formats output an object with
+                          "added", "removed" and "changed" fields.
+
+  -addresses=addr,...     Read the path from each of these Vault servers at
+                          the same time, rather than from the one given by
+                          -address, and output the results together. The
+                          table format prints a "=== address ===" header
+                          before each result, while the json and yaml formats
+                          key the results by address. A failure to read from
+                          one server is reported without stopping the others.
+
+  -exec                   Run the command given after the path with the secret
+                          in its environment, rather than outputting it.
+
+  -benchmark=n            Rather than outputting the result, read the path n
+                          times and output the throughput, the latency
+                          percentiles and the error rate. A few requests are
+                          made beforehand to warm up and are not measured.
+
+  -concurrency=1          The number of requests made at the same time with
+                          -benchmark.
 
-`
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
 
@@ -103,7 +527,17 @@ func (c *ReadCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *ReadCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-format": predictFormat,
-		"-field":  complete.PredictNothing,
+		"-format":          predictFormat,
+		"-field":           complete.PredictNothing,
+		"-field-default":   complete.PredictNothing,
+		"-preserve-order":  complete.PredictNothing,
+		"-require-field":   complete.PredictNothing,
+		"-response-schema": complete.PredictFiles("*.json"),
+		"-addresses":       complete.PredictAnything,
+		"-group-by-path":   complete.PredictNothing,
+		"-diff-versions":   complete.PredictNothing,
+		"-benchmark":       complete.PredictNothing,
+		"-concurrency":     complete.PredictNothing,
+		"-exec":            complete.PredictNothing,
 	}
 }
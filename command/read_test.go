@@ -1,6 +1,16 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/http"
@@ -135,3 +145,547 @@ func TestRead_field_notFound(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestRead_fieldDefault(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	data := map[string]interface{}{"value": "bar"}
+	if _, err := client.Logical().Write("secret/foo", data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Field    string
+		Expected string
+	}{
+		{"value", "bar\n"},
+		{"nope", "fallback\n"},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: token,
+				Ui:          ui,
+			},
+		}
+
+		args := []string{
+			"-address", addr,
+			"-field", tc.Field,
+			"-field-default", "fallback",
+			"secret/foo",
+		}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+		}
+
+		output := ui.OutputWriter.String()
+		if output != tc.Expected {
+			t.Fatalf("%s: unexpected output:\n%s", tc.Field, output)
+		}
+	}
+}
+
+func TestRead_fields(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	data := map[string]interface{}{
+		"user":     "web",
+		"password": "hunter2",
+		"options":  map[string]interface{}{"host": "db.example.com"},
+	}
+	if _, err := client.Logical().Write("secret/foo", data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Args   []string
+		Code   int
+		Output string
+		Error  string
+	}{
+		// A single field has no trailing newline
+		{[]string{"-field", "user"}, 0, "web", ""},
+		{[]string{"-field", "user", "-field", "password"}, 0, "web\thunter2\n", ""},
+		{[]string{"-field", "options.host", "-field", "password", "-field", "user"}, 0, "db.example.com\thunter2\tweb\n", ""},
+		{[]string{"-field", "user", "-field", "nope", "-field", "password"}, 1, "", "Field nope not present in secret"},
+		{[]string{"-field", "user", "-field", "nope", "-field-default", "-"}, 0, "web\t-\n", ""},
+		{[]string{"-field", "user", "-field", "password", "-format", "qr"}, 1, "", "single -field"},
+	}
+
+	for _, tc := range cases {
+		var out, errOut bytes.Buffer
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: token,
+				Ui:          &cli.BasicUi{Writer: &out, ErrorWriter: &errOut},
+			},
+		}
+
+		args := append([]string{"-address", addr}, tc.Args...)
+		if code := c.Run(append(args, "secret/foo")); code != tc.Code {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.Args, code, errOut.String())
+		}
+		if out.String() != tc.Output {
+			t.Fatalf("%v: unexpected output: %q", tc.Args, out.String())
+		}
+		if !strings.Contains(errOut.String(), tc.Error) {
+			t.Fatalf("%v: expected error %q, got: %s", tc.Args, tc.Error, errOut.String())
+		}
+	}
+}
+
+func TestRead_preserveOrder(t *testing.T) {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprint(w, `{"request_id":"1","lease_id":"","renewable":false,"lease_duration":0,`+
+			`"data":{"zone":"eu","name":"web","options":{"timeout":"5s","retries":3},`+
+			`"hosts":[{"port":8200,"host":"a"}]},"wrap_info":null,"warnings":null,"auth":null}`)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Args     []string
+		Expected []string
+	}{
+		{
+			[]string{"-preserve-order"},
+			[]string{"zone", "name", "options", "hosts"},
+		},
+		{
+			nil,
+			[]string{"hosts", "name", "options", "zone"},
+		},
+		{
+			[]string{"-preserve-order", "-format", "json"},
+			[]string{`"request_id"`, `"lease_id"`, `"data"`, `"zone"`, `"name"`, `"timeout"`, `"retries"`, `"port"`, `"host"`, `"warnings"`},
+		},
+		{
+			[]string{"-format", "json"},
+			[]string{`"request_id"`, `"lease_id"`, `"data"`, `"hosts"`, `"host"`, `"port"`, `"name"`, `"retries"`, `"timeout"`, `"zone"`},
+		},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := append([]string{"-address", ts.URL}, tc.Args...)
+		if code := c.Run(append(args, "secret/foo")); code != 0 {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.Args, code, ui.ErrorWriter.String())
+		}
+
+		output := ui.OutputWriter.String()
+		last := -1
+		for _, s := range tc.Expected {
+			i := strings.Index(output, s)
+			if i <= last {
+				t.Fatalf("%v: expected %s after the previous key in output:\n%s", tc.Args, s, output)
+			}
+			last = i
+		}
+	}
+
+	ui := new(cli.MockUi)
+	c := &ReadCommand{Meta: meta.Meta{ClientToken: "foo", Ui: ui}}
+	if code := c.Run([]string{"-address", ts.URL, "-preserve-order", "secret/foo", "secret/bar"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestRead_requireField(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	data := map[string]interface{}{
+		"username": "admin",
+		"password": "secret",
+		"options":  map[string]interface{}{"port": "5432"},
+	}
+	if _, err := client.Logical().Write("secret/foo", data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Fields []string
+		Code   int
+		Error  string
+	}{
+		{[]string{"password"}, 0, ""},
+		{[]string{"username", "password", "options.port"}, 0, ""},
+		{[]string{"token"}, 1, "Required fields not present in secret: token"},
+		{[]string{"password", "options.host", "token"}, 1, "Required fields not present in secret: options.host, token"},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: token,
+				Ui:          ui,
+			},
+		}
+
+		args := []string{"-address", addr}
+		for _, f := range tc.Fields {
+			args = append(args, "-require-field", f)
+		}
+		args = append(args, "secret/foo")
+
+		if code := c.Run(args); code != tc.Code {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.Fields, code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+			t.Fatalf("%v: bad error: %s", tc.Fields, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != "" {
+			t.Fatalf("%v: unexpected output:\n%s", tc.Fields, output)
+		}
+	}
+}
+
+func TestRead_multiple(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	for _, path := range []string{"secret/a", "secret/b"} {
+		data := map[string]interface{}{"value": path + "-value"}
+		if _, err := client.Logical().Write(path, data); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	ui := new(cli.MockUi)
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+
+	args := []string{
+		"-address", addr,
+		"secret/a",
+		"secret/b",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{"=== secret/a ===", "secret/a-value", "=== secret/b ===", "secret/b-value"} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, output)
+		}
+	}
+
+	// A missing path is reported but does not hide the other results
+	ui = new(cli.MockUi)
+	c.Meta.Ui = ui
+	args = []string{
+		"-address", addr,
+		"secret/a",
+		"secret/nope",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "secret/a-value") {
+		t.Fatalf("bad output:\n%s", ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "No value found at secret/nope") {
+		t.Fatalf("bad error:\n%s", ui.ErrorWriter.String())
+	}
+}
+
+func TestRead_failIfEmpty(t *testing.T) {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/empty":
+			fmt.Fprint(w, `{"data":{}}`)
+		default:
+			fmt.Fprint(w, `{"data":{"value":"bar"}}`)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Path   string
+		Args   []string
+		Code   int
+		Output bool
+	}{
+		{"secret/foo", nil, 0, true},
+		{"secret/foo", []string{"-fail-if-empty"}, 0, true},
+		{"secret/empty", nil, 0, true},
+		{"secret/empty", []string{"-fail-if-empty"}, 1, true},
+		{"secret/empty", []string{"-fail-if-empty", "-quiet"}, 1, false},
+		{"secret/foo", []string{"-fail-if-empty", "-quiet"}, 0, false},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := append([]string{"-address", ts.URL, "-format", "json"}, tc.Args...)
+		if code := c.Run(append(args, tc.Path)); code != tc.Code {
+			t.Fatalf("%s %v: bad: %d\n\n%s", tc.Path, tc.Args, code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); (output != "") != tc.Output {
+			t.Fatalf("%s %v: bad output:\n%s", tc.Path, tc.Args, output)
+		}
+	}
+}
+
+func TestRead_responseSchema(t *testing.T) {
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/bad":
+			fmt.Fprint(w, `{"data":{"username":"admin","port":"5432","extra":true}}`)
+		default:
+			fmt.Fprint(w, `{"data":{"username":"admin","port":5432}}`)
+		}
+	}))
+	defer ts.Close()
+
+	schema := filepath.Join(FixturePath, "credentials.schema.json")
+	cases := []struct {
+		Paths  []string
+		Code   int
+		Output bool
+		Errors []string
+	}{
+		{[]string{"secret/good"}, 0, true, nil},
+		{[]string{"secret/bad"}, 1, false, []string{
+			"Response from secret/bad does not match the schema",
+			"extra: field not allowed by the schema",
+			"port: expected integer, got string",
+		}},
+		{[]string{"secret/good", "secret/bad"}, 1, true, []string{
+			"Response from secret/bad does not match the schema",
+		}},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := append([]string{"-address", ts.URL, "-response-schema", schema}, tc.Paths...)
+		if code := c.Run(args); code != tc.Code {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.Paths, code, ui.ErrorWriter.String())
+		}
+		for _, e := range tc.Errors {
+			if !strings.Contains(ui.ErrorWriter.String(), e) {
+				t.Fatalf("%v: expected error %q, got: %s", tc.Paths, e, ui.ErrorWriter.String())
+			}
+		}
+		if output := ui.OutputWriter.String(); strings.Contains(output, "admin") != tc.Output {
+			t.Fatalf("%v: bad output:\n%s", tc.Paths, output)
+		}
+	}
+}
+
+func TestRead_addresses(t *testing.T) {
+	stub := func(value string) *httptest.Server {
+		return httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			if value == "" {
+				w.WriteHeader(500)
+				fmt.Fprint(w, `{"errors":["internal error"]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"value":%q}}`, value)
+		}))
+	}
+	a, b, broken := stub("a"), stub("b"), stub("")
+	defer a.Close()
+	defer b.Close()
+	defer broken.Close()
+
+	ui := new(cli.MockUi)
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	args := []string{"-addresses", a.URL + "," + b.URL, "-format", "json", "secret/foo"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var results map[string]struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &results); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(results) != 2 || results[a.URL].Data["value"] != "a" || results[b.URL].Data["value"] != "b" {
+		t.Fatalf("bad: %#v", results)
+	}
+
+	// A failing cluster is reported without stopping the others
+	ui = new(cli.MockUi)
+	c = &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+	args = []string{"-addresses", a.URL + "," + broken.URL, "secret/foo"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "=== "+a.URL+" ===") || !regexpMatch(t, `value\s+a`, output) ||
+		strings.Contains(output, broken.URL) {
+		t.Fatalf("bad: %s", output)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Error reading secret/foo from "+broken.URL) {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestRead_raw(t *testing.T) {
+	body := `{"data":{"cert":"-----BEGIN CERTIFICATE-----\nMIIB\u001b\n-----END CERTIFICATE-----\n"},  "lease_id":""}`
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Args   []string
+		Output string
+	}{
+		{[]string{"-format", "raw", "secret/foo"}, body},
+		{[]string{"-format", "raw", "-field", "cert", "secret/foo"}, "-----BEGIN CERTIFICATE-----\nMIIB\x1b\n-----END CERTIFICATE-----\n"},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &ReadCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := append([]string{"-address", ts.URL}, tc.Args...)
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.Args, code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != tc.Output+"\n" {
+			t.Fatalf("%v: bad output: %q", tc.Args, output)
+		}
+	}
+}
+
+func TestRead_exec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprint(w, `{"data":{"data":{"db.password":"hunter2","user-name":"admin","ports":[5432]},"metadata":{"version":1}}}`)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "vault-exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "env")
+
+	ui := cli.NewMockUi()
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: "foo",
+			Ui:          ui,
+		},
+	}
+
+	script := `echo "$DB_PASSWORD $USER_NAME $PORTS_0 ${VERSION:-none}" > "$0"; exit 3`
+	args := []string{"-address", ts.URL, "-exec", "secret/foo", "--", "sh", "-c", script, out}
+	if code := c.Run(args); code != 3 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); output != "" {
+		t.Fatalf("bad output: %q", output)
+	}
+
+	actual, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(actual) != "hunter2 admin 5432 none\n" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestRead_formatEnv(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+
+	defer os.Setenv("VAULT_FORMAT", os.Getenv("VAULT_FORMAT"))
+
+	// Without an override, the table format read declares is used
+	os.Unsetenv("VAULT_FORMAT")
+	ui := cli.NewMockUi()
+	c := &ReadCommand{Meta: meta.Meta{ClientToken: "foo", Ui: ui}}
+	if code := c.Run([]string{"-address", server.URL, "secret/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !regexpMatch(t, `foo\s+bar`, ui.OutputWriter.String()) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	// VAULT_FORMAT overrides it
+	os.Setenv("VAULT_FORMAT", "json")
+	ui = cli.NewMockUi()
+	c = &ReadCommand{Meta: meta.Meta{ClientToken: "foo", Ui: ui}}
+	if code := c.Run([]string{"-address", server.URL, "secret/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &out); err != nil {
+		t.Fatalf("expected json output: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+
+	// -format overrides the environment
+	ui = cli.NewMockUi()
+	c = &ReadCommand{Meta: meta.Meta{ClientToken: "foo", Ui: ui}}
+	if code := c.Run([]string{"-address", server.URL, "-format", "table", "secret/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !regexpMatch(t, `foo\s+bar`, ui.OutputWriter.String()) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+}
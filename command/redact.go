@@ -0,0 +1,55 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+)
+
+const (
+	// sensitiveMetadataKey is the key of the custom metadata of a versioned
+	// secret that lists its sensitive fields, comma-separated.
+	sensitiveMetadataKey = "sensitive"
+
+	// redactedValue is output in place of the value of a sensitive field.
+	redactedValue = "REDACTED"
+)
+
+// redactSensitive returns the secret with the fields that its metadata marks
+// as sensitive replaced by REDACTED, if -respect-sensitive-metadata is set
+// and -reveal is not. Only versioned secrets, which nest their data under
+// "data" alongside the version "metadata", can mark fields as sensitive.
+// The secret itself is not modified.
+func redactSensitive(secret *api.Secret, opts *meta.OutputOptions) *api.Secret {
+	if secret == nil || opts == nil || !opts.RespectSensitive || opts.Reveal {
+		return secret
+	}
+
+	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+	custom, _ := metadata["custom_metadata"].(map[string]interface{})
+	sensitive, _ := custom[sensitiveMetadataKey].(string)
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if sensitive == "" || !ok {
+		return secret
+	}
+
+	redacted := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		redacted[k] = v
+	}
+	for _, field := range strings.Split(sensitive, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := redacted[field]; ok {
+			redacted[field] = redactedValue
+		}
+	}
+
+	s := *secret
+	s.Data = make(map[string]interface{}, len(secret.Data))
+	for k, v := range secret.Data {
+		s.Data[k] = v
+	}
+	s.Data["data"] = redacted
+	return &s
+}
@@ -332,7 +332,7 @@ func (c *RekeyCommand) rekeyRetrieveStored(client *api.Client, recovery bool) in
 		Data: structs.New(storedKeys).Map(),
 	}
 
-	return OutputSecret(c.Ui, "table", secret)
+	return OutputSecret(c.Ui, "table", secret, nil)
 }
 
 func (c *RekeyCommand) rekeyDeleteStored(client *api.Client, recovery bool) int {
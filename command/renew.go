@@ -15,8 +15,8 @@ type RenewCommand struct {
 
 func (c *RenewCommand) Run(args []string) int {
 	var format string
-	flags := c.Meta.FlagSet("renew", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
+	flags := c.Meta.FlagSet("renew", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -57,7 +57,7 @@ func (c *RenewCommand) Run(args []string) int {
 		return 1
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 }
 
 func (c *RenewCommand) Synopsis() string {
@@ -84,7 +84,11 @@ General Options:
 Renew Options:
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-`
+                          delimited table. This can also be json, yaml, csv,
+                          or raw, which outputs the data as compact JSON
+                          without a trailing newline.
+
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
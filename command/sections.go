@@ -0,0 +1,127 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+// sectionOrder is the order the sections of a response are listed in by the
+// table format, which is the order it lists them in without
+// -merge-sections.
+var sectionOrder = []string{"lease", "auth", "wrap_info", "data", "warnings"}
+
+// responseObject is a response as a single object with a key for each of
+// its sections, output with -merge-sections. Every format outputs the same
+// object, so that no section is left out by one of them.
+type responseObject map[string]interface{}
+
+// mergeSections returns the response as a responseObject. Only the sections
+// the response has are included: "lease", holding the lease_id,
+// lease_duration and renewable fields, along with "auth", "wrap_info",
+// "data" and "warnings".
+func mergeSections(secret *api.Secret) (responseObject, error) {
+	o := make(responseObject)
+	if secret.LeaseID != "" || secret.LeaseDuration > 0 {
+		o["lease"] = map[string]interface{}{
+			"lease_id":       secret.LeaseID,
+			"lease_duration": secret.LeaseDuration,
+			"renewable":      secret.Renewable,
+		}
+	}
+	if secret.Auth != nil {
+		auth, err := sectionValue(secret.Auth)
+		if err != nil {
+			return nil, err
+		}
+		o["auth"] = auth
+	}
+	if secret.WrapInfo != nil {
+		wrapInfo, err := sectionValue(secret.WrapInfo)
+		if err != nil {
+			return nil, err
+		}
+		o["wrap_info"] = wrapInfo
+	}
+	if secret.Data != nil {
+		o["data"] = secret.Data
+	}
+	if len(secret.Warnings) > 0 {
+		warnings := make([]interface{}, 0, len(secret.Warnings))
+		for _, w := range secret.Warnings {
+			warnings = append(warnings, w)
+		}
+		o["warnings"] = warnings
+	}
+	return o, nil
+}
+
+// sectionValue returns the section as it is encoded, so that its fields have
+// the same names in every format.
+func sectionValue(section interface{}) (interface{}, error) {
+	b, err := json.Marshal(section)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := jsonutil.DecodeJSON(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// OutputSections outputs the response merged by -merge-sections as a
+// table, with a row for each field of each section keyed by the section and
+// the field, such as "auth.client_token".
+func (t TableFormatter) OutputSections(ui cli.Ui, o responseObject, opts *meta.OutputOptions) error {
+	var rows []tableRow
+	for _, section := range sectionOrder {
+		v, ok := o[section]
+		if !ok {
+			continue
+		}
+
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			sectionRows, err := t.dataRows(section, v, false, opts)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, sectionRows...)
+			continue
+		}
+
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fieldRows, err := t.dataRows(section+"."+k, fields[k], false, opts)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, fieldRows...)
+		}
+	}
+
+	config := columnize.DefaultConfig()
+	config.Delim = "♨"
+	config.Glue = "\t"
+	config.Prefix = ""
+
+	input := make([]string, 0, len(rows)+2)
+	input = append(input, fmt.Sprintf("Key %s Value", config.Delim))
+	input = append(input, fmt.Sprintf("--- %s -----", config.Delim))
+	for _, r := range rows {
+		input = append(input, fmt.Sprintf("%s %s %s", r.key, config.Delim, r.value))
+	}
+	ui.Output(columnize.Format(input, config))
+	return nil
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// outputSigner returns an armored detached signature of data made with the
+// given key; it can be overwritten for tests.
+var outputSigner = gpgSign
+
+// gpgSign signs data using gpg, which asks the local gpg-agent for the key.
+func gpgSign(key string, data []byte) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--batch", "--armor", "--detach-sign", "--local-user", key)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	sig, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+	return sig, nil
+}
+
+// bufferUi is a cli.Ui that holds back the output, so that it can be signed
+// before any of it is shown. Errors are passed through.
+type bufferUi struct {
+	cli.Ui
+
+	buf bytes.Buffer
+}
+
+func (u *bufferUi) Output(s string) {
+	u.buf.WriteString(s)
+	u.buf.WriteString("\n")
+}
+
+// outputSigned runs output, which writes the formatted result to the given
+// Ui, and signs what it writes with the key given by -sign-output. The
+// signature is written to the -signature-file, or else output after the
+// result. If the result cannot be signed, nothing is output. With
+// -no-final-newline, the trailing newline of everything output is left out.
+func outputSigned(ui cli.Ui, opts *meta.OutputOptions, output func(ui cli.Ui) int) int {
+	if opts == nil || opts.Quiet || (opts.SignOutput == "" && !opts.NoFinalNewline) {
+		return output(ui)
+	}
+
+	buffered := &bufferUi{Ui: ui}
+	code := output(buffered)
+	out := strings.TrimSuffix(buffered.buf.String(), "\n")
+
+	if opts.SignOutput != "" {
+		if code != 0 {
+			return code
+		}
+		sig, err := outputSigner(opts.SignOutput, buffered.buf.Bytes())
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error signing output: %s", err))
+			return 1
+		}
+		if opts.SignatureFile != "" {
+			if err := ioutil.WriteFile(opts.SignatureFile, sig, 0644); err != nil {
+				ui.Error(fmt.Sprintf("Error writing signature: %s", err))
+				return 1
+			}
+		} else {
+			out += "\n" + strings.TrimSpace(string(sig))
+		}
+	}
+
+	switch {
+	case buffered.buf.Len() == 0 && opts.SignOutput == "":
+	case opts.NoFinalNewline:
+		outputRaw(ui, out)
+	default:
+		ui.Output(out)
+	}
+	return code
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestOutputSecret_signOutput(t *testing.T) {
+	var signed []string
+	var signErr error
+	oldSigner := outputSigner
+	outputSigner = func(key string, data []byte) ([]byte, error) {
+		if signErr != nil {
+			return nil, signErr
+		}
+		signed = append(signed, string(data))
+		return []byte("-----BEGIN PGP SIGNATURE-----\nsigned by " + key + "\n-----END PGP SIGNATURE-----\n"), nil
+	}
+	defer func() { outputSigner = oldSigner }()
+
+	s := &api.Secret{Data: map[string]interface{}{"foo": "bar"}}
+
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{SignOutput: "ops@example.com"}
+	if code := OutputSecret(ui, "json", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if len(signed) != 1 || !strings.HasPrefix(out, signed[0]) || !strings.Contains(signed[0], `"foo":"bar"`) {
+		t.Fatalf("bad: signed %q, output %q", signed, out)
+	}
+	if rest := strings.TrimPrefix(out, signed[0]); !strings.HasPrefix(rest, "-----BEGIN PGP SIGNATURE-----\nsigned by ops@example.com\n") {
+		t.Fatalf("bad: %q", out)
+	}
+
+	// The signature can be written to a file instead
+	dir, err := ioutil.TempDir("", "vault-sign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sigFile := filepath.Join(dir, "output.asc")
+
+	signed = nil
+	ui = new(cli.MockUi)
+	opts.SignatureFile = sigFile
+	if code := OutputSecrets(ui, "table", []string{"a", "b"}, map[string]*api.Secret{"a": s, "b": s}, true, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	out = ui.OutputWriter.String()
+	if len(signed) != 1 || out != signed[0] || !strings.Contains(out, "=== b ===") {
+		t.Fatalf("bad: signed %q, output %q", signed, out)
+	}
+	sig, err := ioutil.ReadFile(sigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(sig), "signed by ops@example.com") {
+		t.Fatalf("bad: %q", sig)
+	}
+
+	// Nothing is output if signing fails
+	signErr = errors.New("no secret key")
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "json", s, opts); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if out := ui.OutputWriter.String(); out != "" {
+		t.Fatalf("bad: %q", out)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Error signing output: no secret key") {
+		t.Fatalf("bad: %q", ui.ErrorWriter.String())
+	}
+}
@@ -0,0 +1,136 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/strutil"
+)
+
+// listRows returns the columns and rows of a list response, one row per key
+// in the order the server returned them. The "key" column holds the key and
+// the other columns, sorted by name, the metadata the response has for the
+// key in "key_info", if any.
+func listRows(secret *api.Secret) ([]string, []map[string]interface{}) {
+	keys, _ := secret.Data["keys"].([]interface{})
+	info, _ := secret.Data["key_info"].(map[string]interface{})
+
+	seen := make(map[string]struct{})
+	var columns []string
+	rows := make([]map[string]interface{}, 0, len(keys))
+	for _, k := range keys {
+		row := make(map[string]interface{})
+		if s, ok := k.(string); ok {
+			fields, _ := info[s].(map[string]interface{})
+			for f, v := range fields {
+				if f == "key" {
+					continue
+				}
+				row[f] = v
+				if _, ok := seen[f]; !ok {
+					seen[f] = struct{}{}
+					columns = append(columns, f)
+				}
+			}
+		}
+		row["key"] = k
+		rows = append(rows, row)
+	}
+	sort.Strings(columns)
+
+	return append([]string{"key"}, columns...), rows
+}
+
+// sortListKeys returns the keys of a list response sorted by the given
+// column of its rows, as returned by listRows.
+func sortListKeys(secret *api.Secret, by string, desc bool) ([]interface{}, error) {
+	if by == "" {
+		by = "key"
+	}
+
+	columns, rows := listRows(secret)
+	if err := sortRows(rows, columns, by, desc); err != nil {
+		return nil, err
+	}
+
+	keys := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row["key"])
+	}
+	return keys, nil
+}
+
+// sortRows sorts the rows by the values of the given column, which must be
+// one of columns. The column is compared numerically if all its values are
+// numbers, chronologically if they are all RFC 3339 timestamps, and as
+// strings otherwise. Rows without a value sort first, and rows with equal
+// values keep their order.
+func sortRows(rows []map[string]interface{}, columns []string, by string, desc bool) error {
+	if !strutil.StrListContains(columns, by) {
+		return fmt.Errorf("Unknown column %q to sort by, expected one of: %s",
+			by, strings.Join(columns, ", "))
+	}
+
+	less := columnLess(rows, by)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return less(rows[j][by], rows[i][by])
+		}
+		return less(rows[i][by], rows[j][by])
+	})
+	return nil
+}
+
+// columnLess returns the function comparing the values of the column, based
+// on the type of its values as detected for -typed-json.
+func columnLess(rows []map[string]interface{}, column string) func(a, b interface{}) bool {
+	numeric, timestamp := true, true
+	for _, row := range rows {
+		v, ok := row[column]
+		if !ok || v == nil {
+			continue
+		}
+		switch annotateValue(v).Type {
+		case "integer", "number":
+			timestamp = false
+		case "string":
+			numeric = false
+			if _, err := time.Parse(time.RFC3339Nano, v.(string)); err != nil {
+				timestamp = false
+			}
+		default:
+			numeric, timestamp = false, false
+		}
+	}
+
+	var less func(a, b interface{}) bool
+	switch {
+	case numeric:
+		less = func(a, b interface{}) bool {
+			fa, _ := strconv.ParseFloat(fmt.Sprintf("%v", a), 64)
+			fb, _ := strconv.ParseFloat(fmt.Sprintf("%v", b), 64)
+			return fa < fb
+		}
+	case timestamp:
+		less = func(a, b interface{}) bool {
+			ta, _ := time.Parse(time.RFC3339Nano, a.(string))
+			tb, _ := time.Parse(time.RFC3339Nano, b.(string))
+			return ta.Before(tb)
+		}
+	default:
+		less = func(a, b interface{}) bool {
+			return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
+		}
+	}
+
+	return func(a, b interface{}) bool {
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return less(a, b)
+	}
+}
@@ -79,7 +79,7 @@ func (c *SSHCommand) Run(args []string) int {
 	// Common options
 	flags.StringVar(&c.mode, "mode", "", "")
 	flags.BoolVar(&c.noExec, "no-exec", false, "")
-	flags.StringVar(&c.format, "format", "table", "")
+	flags.StringVar(&c.format, "format", meta.DefaultFormat("table"), "")
 	flags.StringVar(&c.mountPoint, "mount-point", "ssh", "")
 	flags.StringVar(&c.role, "role", "", "")
 
@@ -250,7 +250,7 @@ func (c *SSHCommand) handleTypeCA() error {
 	// Handle no-exec
 	if c.noExec {
 		// This is hacky, but OutputSecret returns an int, not an error :(
-		if i := OutputSecret(c.Ui, c.format, secret); i != 0 {
+		if i := OutputSecret(c.Ui, c.format, secret, nil); i != 0 {
 			return fmt.Errorf("an error occurred outputting the secret")
 		}
 		return nil
@@ -337,7 +337,7 @@ func (c *SSHCommand) handleTypeOTP() error {
 	// Handle no-exec
 	if c.noExec {
 		// This is hacky, but OutputSecret returns an int, not an error :(
-		if i := OutputSecret(c.Ui, c.format, secret); i != 0 {
+		if i := OutputSecret(c.Ui, c.format, secret, nil); i != 0 {
 			return fmt.Errorf("an error occurred outputting the secret")
 		}
 		return nil
@@ -407,7 +407,7 @@ func (c *SSHCommand) handleTypeDynamic() error {
 	// Handle no-exec
 	if c.noExec {
 		// This is hacky, but OutputSecret returns an int, not an error :(
-		if i := OutputSecret(c.Ui, c.format, secret); i != 0 {
+		if i := OutputSecret(c.Ui, c.format, secret, nil); i != 0 {
 			return fmt.Errorf("an error occurred outputting the secret")
 		}
 		return nil
@@ -2,7 +2,10 @@ package command
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/meta"
@@ -15,12 +18,21 @@ type StatusCommand struct {
 }
 
 func (c *StatusCommand) Run(args []string) int {
+	var format string
+	var summaryOnly, probe bool
 	flags := c.Meta.FlagSet("status", meta.FlagSetDefault)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
+	flags.BoolVar(&summaryOnly, "summary-only", false, "")
+	flags.BoolVar(&probe, "probe", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
+	if probe {
+		return c.probe()
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -35,6 +47,22 @@ func (c *StatusCommand) Run(args []string) int {
 		return 1
 	}
 
+	if summaryOnly {
+		leaderStatus, err := leaderStatus(client)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf(
+				"Error checking leader status: %s", err))
+			return 1
+		}
+		if code := c.outputSummary(format, sealStatus, leaderStatus); code != 0 {
+			return code
+		}
+		if sealStatus.Sealed {
+			return 2
+		}
+		return 0
+	}
+
 	outStr := fmt.Sprintf(
 		"Sealed: %v\n"+
 			"Key Shares: %d\n"+
@@ -55,13 +83,7 @@ func (c *StatusCommand) Run(args []string) int {
 
 	c.Ui.Output(outStr)
 
-	// Mask the 'Vault is sealed' error, since this means HA is enabled,
-	// but that we cannot query for the leader since we are sealed.
-	leaderStatus, err := client.Sys().Leader()
-	if err != nil && strings.Contains(err.Error(), "Vault is sealed") {
-		leaderStatus = &api.LeaderResponse{HAEnabled: true}
-		err = nil
-	}
+	leaderStatus, err := leaderStatus(client)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error checking leader status: %s", err))
@@ -98,6 +120,115 @@ func (c *StatusCommand) Run(args []string) int {
 	}
 }
 
+// probeTimeout is how long -probe waits for a connection.
+const probeTimeout = 5 * time.Second
+
+// probe connects to the host and port of the address over TCP and reports
+// whether the server is reachable and how long connecting took. Nothing is
+// sent, so neither TLS nor a token is needed.
+func (c *StatusCommand) probe() int {
+	addr, err := c.Address()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 1
+	}
+	hostport, err := probeHostPort(addr)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", hostport, probeTimeout)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Unreachable: %s: %s", hostport, err))
+		return 1
+	}
+	latency := time.Since(start)
+	conn.Close()
+
+	c.Ui.Output(fmt.Sprintf("Reachable: %s (%s)", hostport, latency))
+	return 0
+}
+
+// probeHostPort returns the host and port of the address, with the default
+// port of its scheme if it has none.
+func probeHostPort(addr string) (string, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid address %q", addr)
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	port := "443"
+	if u.Scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// leaderStatus returns the HA status of the server.
+func leaderStatus(client *api.Client) (*api.LeaderResponse, error) {
+	// Mask the 'Vault is sealed' error, since this means HA is enabled,
+	// but that we cannot query for the leader since we are sealed.
+	leaderStatus, err := client.Sys().Leader()
+	if err != nil && strings.Contains(err.Error(), "Vault is sealed") {
+		return &api.LeaderResponse{HAEnabled: true}, nil
+	}
+	return leaderStatus, err
+}
+
+// outputSummary outputs the status as a single line of space-separated
+// key=value fields, preceded by "healthy" or "sealed", or, with the json and
+// yaml formats, as a flat object of the same fields.
+func (c *StatusCommand) outputSummary(format string, seal *api.SealStatusResponse, leader *api.LeaderResponse) int {
+	status := "healthy"
+	if seal.Sealed {
+		status = "sealed"
+	}
+
+	keys := []string{"status", "version"}
+	fields := map[string]interface{}{
+		"status":  status,
+		"version": seal.Version,
+	}
+	if seal.ClusterName != "" {
+		keys = append(keys, "cluster_name")
+		fields["cluster_name"] = seal.ClusterName
+	}
+	keys = append(keys, "sealed", "ha_enabled")
+	fields["sealed"] = seal.Sealed
+	fields["ha_enabled"] = leader.HAEnabled
+	if leader.HAEnabled {
+		mode := "sealed"
+		if !seal.Sealed {
+			mode = "standby"
+			if leader.IsSelf {
+				mode = "active"
+			}
+		}
+		keys = append(keys, "mode")
+		fields["mode"] = mode
+		if leader.LeaderAddress != "" {
+			keys = append(keys, "leader")
+			fields["leader"] = leader.LeaderAddress
+		}
+	}
+
+	if strings.ToLower(format) != "table" {
+		return outputWithFormat(c.Ui, format, nil, fields, nil)
+	}
+
+	parts := []string{status}
+	for _, k := range keys[1:] {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	c.Ui.Output(strings.Join(parts, " "))
+	return 0
+}
+
 func (c *StatusCommand) Synopsis() string {
 	return "Outputs status of whether Vault is sealed and if HA mode is enabled"
 }
@@ -112,6 +243,24 @@ Usage: vault status [options]
   code also reflects the seal status (0 unsealed, 2 sealed, 1 error).
 
 General Options:
-` + meta.GeneralOptionsUsage()
+` + meta.GeneralOptionsUsage() + `
+Status Options:
+
+  -probe                  Only check that a TCP connection can be made to the
+                          host and port of the address, and output how long
+                          it took. No TLS handshake is made and no token is
+                          needed, so it works before the server is fully up.
+                          The exit code is 1 if the server is unreachable.
+
+  -summary-only           Output the status as a single line, such as
+                          "healthy version=0.8.4 sealed=false ha_enabled=true
+                          mode=active leader=https://node-a:8200", which is
+                          easy to scrape.
+
+  -format=table           The format of the -summary-only output. With json or
+                          yaml, the summary is output as a flat object of the
+                          same fields, with "status" giving whether the Vault
+                          is healthy or sealed.
+`
 	return strings.TrimSpace(helpText)
 }
@@ -1,6 +1,13 @@
 package command
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/http"
@@ -37,3 +44,107 @@ func TestStatus(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestStatus_summaryOnly(t *testing.T) {
+	sealed := false
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/seal-status":
+			fmt.Fprintf(w, `{"sealed":%t,"t":3,"n":5,"progress":0,"version":"0.8.4","cluster_name":"vault-cluster-1"}`, sealed)
+		case "/v1/sys/leader":
+			fmt.Fprint(w, `{"ha_enabled":true,"is_self":true,"leader_address":"https://node-a:8200"}`)
+		}
+	}))
+	defer ts.Close()
+
+	ui := cli.NewMockUi()
+	c := &StatusCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+	}
+	if code := c.Run([]string{"-address", ts.URL, "-summary-only"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	expected := "healthy version=0.8.4 cluster_name=vault-cluster-1 sealed=false ha_enabled=true mode=active leader=https://node-a:8200\n"
+	if actual := ui.OutputWriter.String(); actual != expected {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	// The json format is a flat object of the same fields
+	sealed = true
+	ui = cli.NewMockUi()
+	c = &StatusCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+	}
+	if code := c.Run([]string{"-address", ts.URL, "-summary-only", "-format", "json"}); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+	expectedJSON := map[string]interface{}{
+		"status":       "sealed",
+		"version":      "0.8.4",
+		"cluster_name": "vault-cluster-1",
+		"sealed":       true,
+		"ha_enabled":   true,
+		"mode":         "sealed",
+		"leader":       "https://node-a:8200",
+	}
+	if !reflect.DeepEqual(actual, expectedJSON) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestStatus_probe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "https://" + ln.Addr().String()
+
+	ui := cli.NewMockUi()
+	c := &StatusCommand{Meta: meta.Meta{Ui: ui}}
+	if code := c.Run([]string{"-address", addr, "-probe"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.HasPrefix(out, "Reachable: "+ln.Addr().String()+" (") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Once the listener is down, the server is unreachable
+	ln.Close()
+	ui = cli.NewMockUi()
+	c = &StatusCommand{Meta: meta.Meta{Ui: ui}}
+	if code := c.Run([]string{"-address", addr, "-probe"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if errOut := ui.ErrorWriter.String(); !strings.Contains(errOut, "Unreachable: "+ln.Addr().String()) {
+		t.Fatalf("bad: %s", errOut)
+	}
+}
+
+func TestProbeHostPort(t *testing.T) {
+	cases := map[string]string{
+		"https://vault.example.com:8200": "vault.example.com:8200",
+		"https://vault.example.com":      "vault.example.com:443",
+		"http://127.0.0.1":               "127.0.0.1:80",
+		"https://[::1]":                  "[::1]:443",
+	}
+	for addr, expected := range cases {
+		actual, err := probeHostPort(addr)
+		if err != nil {
+			t.Fatalf("%s: err: %s", addr, err)
+		}
+		if actual != expected {
+			t.Fatalf("%s: bad: %s", addr, actual)
+		}
+	}
+	if _, err := probeHostPort("vault.example.com"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,149 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// syslogNetwork and syslogAddr select the syslog server to send to. When
+// both are empty the local syslog daemon is used; they can be overwritten for
+// tests.
+var syslogNetwork, syslogAddr string
+
+// syslogSender is the subset of a syslog writer used to send messages.
+type syslogSender interface {
+	Info(m string) error
+	Err(m string) error
+	Close() error
+}
+
+// SyslogUi is a cli.Ui that sends the outcome of an operation on a path to
+// syslog, in addition to showing the output with the wrapped Ui or instead of
+// it. The output itself is never sent, since it may hold secret values: a
+// message only has the path, the outcome and, once the command has set
+// them, the keys of the result. Errors are sent in full.
+type SyslogUi struct {
+	cli.Ui
+
+	// Path is the path the command operates on, included in each message.
+	Path string
+
+	// Keys are the keys of the result being output, included in each
+	// message of a success.
+	Keys []string
+
+	// Only suppresses the output of the wrapped Ui, so that the results only
+	// go to syslog. Errors are always shown.
+	Only bool
+
+	writer syslogSender
+}
+
+// NewSyslogUi returns a Ui wrapping ui that sends to syslog using the
+// facility and tag from the output options.
+func NewSyslogUi(ui cli.Ui, path string, opts *meta.OutputOptions) (*SyslogUi, error) {
+	writer, err := dialSyslog(opts.SyslogFacility, opts.SyslogTag)
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to syslog: %s", err)
+	}
+
+	return &SyslogUi{
+		Ui:     ui,
+		Path:   path,
+		Only:   opts.SyslogOnly,
+		writer: writer,
+	}, nil
+}
+
+func (u *SyslogUi) Output(s string) {
+	u.success()
+	if !u.Only {
+		u.Ui.Output(s)
+	}
+}
+
+// OutputRaw is like Output, but writes s with the wrapped Ui without a
+// trailing newline, for outputRaw.
+func (u *SyslogUi) OutputRaw(s string) {
+	u.success()
+	if !u.Only {
+		outputRaw(u.Ui, s)
+	}
+}
+
+func (u *SyslogUi) Error(s string) {
+	if err := u.writer.Err(u.message("error", strings.TrimSpace(s))); err != nil {
+		u.Ui.Error(fmt.Sprintf("Error writing to syslog: %s", err))
+	}
+	u.Ui.Error(s)
+}
+
+func (u *SyslogUi) success() {
+	var detail string
+	if len(u.Keys) > 0 {
+		detail = "keys=" + strings.Join(u.Keys, ",")
+	}
+	if err := u.writer.Info(u.message("success", detail)); err != nil {
+		u.Ui.Error(fmt.Sprintf("Error writing to syslog: %s", err))
+	}
+}
+
+// Close closes the connection to syslog.
+func (u *SyslogUi) Close() error {
+	return u.writer.Close()
+}
+
+func (u *SyslogUi) message(outcome, detail string) string {
+	msg := fmt.Sprintf("path=%s outcome=%s", u.Path, outcome)
+	if detail != "" {
+		msg += " " + detail
+	}
+	return msg
+}
+
+// setSyslogKeys sets the keys of the result about to be output with ui, if
+// it sends to syslog, so that its messages name them.
+func setSyslogKeys(ui cli.Ui, keys []string) {
+	if u, ok := ui.(*SyslogUi); ok {
+		u.Keys = keys
+	}
+}
+
+// secretKeys returns the sorted keys of the data of the secret.
+func secretKeys(secret *api.Secret) []string {
+	if secret == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// startSyslog replaces the Ui of the command with one that also sends to
+// syslog, if requested by the output options. It returns a function that
+// restores the original Ui and closes the connection to syslog.
+func startSyslog(m *meta.Meta, path string) (func(), error) {
+	opts := m.OutputOptions()
+	if !opts.Syslog && !opts.SyslogOnly {
+		return func() {}, nil
+	}
+
+	ui, err := NewSyslogUi(m.Ui, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Ui = ui
+	return func() {
+		m.Ui = ui.Ui
+		ui.Close()
+	}, nil
+}
@@ -0,0 +1,107 @@
+// +build !windows,!nacl,!plan9
+
+package command
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestSyslogUi(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	syslogNetwork, syslogAddr = "udp", conn.LocalAddr().String()
+	defer func() { syslogNetwork, syslogAddr = "", "" }()
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+
+	mockUi := cli.NewMockUi()
+	opts := &meta.OutputOptions{SyslogFacility: "local3", SyslogTag: "vault-test"}
+	ui, err := NewSyslogUi(mockUi, "secret/foo", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ui.Close()
+
+	// The values of the result are not sent, only its keys
+	if code := OutputSecret(ui, "table", &api.Secret{Data: map[string]interface{}{"value": "bar", "other": "baz"}}, nil); code != 0 {
+		t.Fatalf("bad: %d", code)
+	}
+	msg := read()
+	// local3 (19) * 8 + info (6)
+	if !strings.HasPrefix(msg, "<158>") {
+		t.Fatalf("bad priority: %s", msg)
+	}
+	if !strings.Contains(msg, "vault-test") || !strings.HasSuffix(strings.TrimSpace(msg), "path=secret/foo outcome=success keys=other,value") {
+		t.Fatalf("bad message: %s", msg)
+	}
+	if strings.Contains(msg, "bar") || strings.Contains(msg, "baz") {
+		t.Fatalf("value sent to syslog: %s", msg)
+	}
+	if !strings.Contains(mockUi.OutputWriter.String(), "bar") {
+		t.Fatalf("bad output: %s", mockUi.OutputWriter.String())
+	}
+
+	// Raw output is written without a trailing newline
+	basicUi := &cli.BasicUi{Writer: new(bytes.Buffer), ErrorWriter: new(bytes.Buffer)}
+	ui.Ui = basicUi
+	if code := PrintRawField(ui, &api.Secret{Data: map[string]interface{}{"value": "bar"}}, "value", nil); code != 0 {
+		t.Fatalf("bad: %d", code)
+	}
+	if msg := read(); !strings.HasSuffix(strings.TrimSpace(msg), "path=secret/foo outcome=success keys=value") {
+		t.Fatalf("bad message: %s", msg)
+	}
+	if out := basicUi.Writer.(*bytes.Buffer).String(); out != "bar" {
+		t.Fatalf("bad output: %q", out)
+	}
+	ui.Ui = mockUi
+
+	ui.Error("permission denied")
+	msg = read()
+	if !strings.Contains(msg, "path=secret/foo outcome=error permission denied") {
+		t.Fatalf("bad message: %s", msg)
+	}
+	if !strings.Contains(mockUi.ErrorWriter.String(), "permission denied") {
+		t.Fatalf("bad error output: %s", mockUi.ErrorWriter.String())
+	}
+
+	// With -syslog-only the output is not printed
+	mockUi = cli.NewMockUi()
+	opts.SyslogOnly = true
+	ui, err = NewSyslogUi(mockUi, "secret/foo", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ui.Close()
+
+	ui.Output("value  bar")
+	if msg := read(); !strings.Contains(msg, "outcome=success") {
+		t.Fatalf("bad message: %s", msg)
+	}
+	if mockUi.OutputWriter.String() != "" {
+		t.Fatalf("bad output: %s", mockUi.OutputWriter.String())
+	}
+
+	if _, err := NewSyslogUi(mockUi, "secret/foo", &meta.OutputOptions{SyslogFacility: "nope"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
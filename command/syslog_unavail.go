@@ -0,0 +1,9 @@
+// +build windows nacl plan9
+
+package command
+
+import "errors"
+
+func dialSyslog(facility, tag string) (syslogSender, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
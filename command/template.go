@@ -0,0 +1,196 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// outputTemplates renders each file in the -template-dir with the data of
+// the secret, such as {{.password}}, and writes the result to the file with
+// the same relative path in the -output-dir. A template that cannot be
+// rendered, for example because it uses a key the secret does not have, is
+// reported and skipped; the other templates are still written.
+func outputTemplates(ui cli.Ui, secret *api.Secret, opts *meta.OutputOptions) int {
+	if opts.TemplateDir == "" || opts.OutputDir == "" {
+		ui.Error("-template-dir and -output-dir must be given together")
+		return 1
+	}
+	file, err := parseOutputFile(opts.FileMode, opts.FileOwner, opts.FileGroup)
+	if err != nil {
+		ui.Error(err.Error())
+		return 1
+	}
+
+	var paths []string
+	err = filepath.Walk(opts.TemplateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error reading templates: %s", err))
+		return 1
+	}
+	if len(paths) == 0 {
+		ui.Error(fmt.Sprintf("No templates found in %s", opts.TemplateDir))
+		return 1
+	}
+
+	ret := 0
+	for _, path := range paths {
+		rel, err := filepath.Rel(opts.TemplateDir, path)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error rendering %s: %s", path, err))
+			ret = 1
+			continue
+		}
+
+		out := filepath.Join(opts.OutputDir, rel)
+		if err := renderTemplate(path, out, secret.Data, file); err != nil {
+			ui.Error(fmt.Sprintf("Error rendering %s: %s", rel, err))
+			ret = 1
+			continue
+		}
+		if !opts.Quiet {
+			ui.Output(fmt.Sprintf("Wrote %s", out))
+		}
+	}
+	return ret
+}
+
+// renderTemplate renders the template file at path with the given data and
+// writes it to out. Nothing is written if the template cannot be rendered.
+func renderTemplate(path, out string, data map[string]interface{}, file *outputFile) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(contents))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	return file.write(out, buf.Bytes())
+}
+
+// TemplateMissingKeys are the values of -template-missing-key.
+var TemplateMissingKeys = []string{"error", "zero", "default"}
+
+// An output formatter that renders the response with the Go template given
+// by -template.
+type TemplateFormatter struct {
+}
+
+// templateData is what a template given by -template is executed with. For
+// a versioned secret, Data is the secret itself and Metadata its metadata;
+// otherwise Data is the data of the response, or the keys of a list.
+type templateData struct {
+	Data          interface{}
+	Metadata      map[string]interface{}
+	RequestID     string
+	LeaseID       string
+	LeaseDuration int
+	Renewable     bool
+	Warnings      []string
+	Auth          *api.SecretAuth
+	WrapInfo      *api.SecretWrapInfo
+}
+
+func (t TemplateFormatter) Output(ui cli.Ui, secret *api.Secret, data interface{}, opts *meta.OutputOptions) error {
+	tmpl, err := loadTemplate(opts)
+	if err != nil {
+		return err
+	}
+
+	var td templateData
+	if secret != nil {
+		td = templateData{
+			RequestID:     secret.RequestID,
+			LeaseID:       secret.LeaseID,
+			LeaseDuration: secret.LeaseDuration,
+			Renewable:     secret.Renewable,
+			Warnings:      secret.Warnings,
+			Auth:          secret.Auth,
+			WrapInfo:      secret.WrapInfo,
+		}
+	}
+	td.Data = data
+	if s, ok := data.(*api.Secret); ok {
+		td.Data = s.Data
+		inner, isData := s.Data["data"].(map[string]interface{})
+		metadata, isMetadata := s.Data["metadata"].(map[string]interface{})
+		if isData && isMetadata {
+			td.Data, td.Metadata = inner, metadata
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, td); err != nil {
+		return fmt.Errorf("error executing template: %s", err)
+	}
+
+	out := strings.TrimSuffix(buf.String(), "\n")
+	if !opts.NoSanitize {
+		out = sanitize(out)
+	}
+	ui.Output(out)
+	return nil
+}
+
+// loadTemplate parses the template given by -template, reading it from a
+// file if it starts with "@".
+func loadTemplate(opts *meta.OutputOptions) (*template.Template, error) {
+	text := opts.Template
+	if text == "" {
+		return nil, fmt.Errorf("-format=template requires a -template")
+	}
+	name := "template"
+	if strings.HasPrefix(text, "@") {
+		name = text[1:]
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template: %s", err)
+		}
+		text = string(contents)
+	}
+
+	missingKey := opts.TemplateMissingKey
+	if missingKey == "" {
+		missingKey = "error"
+	}
+	if !strutil.StrListContains(TemplateMissingKeys, missingKey) {
+		return nil, fmt.Errorf("invalid -template-missing-key %q: must be one of %s",
+			missingKey, strings.Join(TemplateMissingKeys, ", "))
+	}
+
+	tmpl, err := template.New(filepath.Base(name)).Option("missingkey=" + missingKey).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %s", err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,229 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestOutputSecret_templates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"username": "admin",
+			"password": "hunter2",
+			"port":     json.Number("5432"),
+		},
+	}
+	opts := &meta.OutputOptions{
+		TemplateDir: filepath.Join(FixturePath, "templates"),
+		OutputDir:   filepath.Join(dir, "out"),
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputSecret(ui, "table", s, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := map[string]string{
+		"app.conf": "user = \"admin\"\nport = 5432\n",
+		"db/env":   "DB_PASSWORD=hunter2\n",
+	}
+	for name, contents := range expected {
+		path := filepath.Join(opts.OutputDir, name)
+		actual, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(actual) != contents {
+			t.Fatalf("%s: bad: %q", name, actual)
+		}
+		if !strings.Contains(ui.OutputWriter.String(), "Wrote "+path) {
+			t.Fatalf("bad: %s", ui.OutputWriter.String())
+		}
+	}
+
+	// A missing key fails only the templates that use it
+	os.RemoveAll(opts.OutputDir)
+	delete(s.Data, "password")
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", s, opts); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if errOut := ui.ErrorWriter.String(); !strings.Contains(errOut, "Error rendering "+filepath.Join("db", "env")) ||
+		!strings.Contains(errOut, "password") {
+		t.Fatalf("bad: %s", errOut)
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutputDir, "db", "env")); !os.IsNotExist(err) {
+		t.Fatalf("expected no output file, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutputDir, "app.conf")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Both directories are needed
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", s, &meta.OutputOptions{TemplateDir: opts.TemplateDir}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestOutputSecret_templatesFileMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"username": "admin",
+			"password": "hunter2",
+			"port":     json.Number("5432"),
+		},
+	}
+	opts := &meta.OutputOptions{
+		TemplateDir: filepath.Join(FixturePath, "templates"),
+		OutputDir:   filepath.Join(dir, "out"),
+	}
+
+	for _, mode := range []string{"", "0640", "444"} {
+		opts.FileMode = mode
+		ui := new(cli.MockUi)
+		if code := OutputSecret(ui, "table", s, opts); code != 0 {
+			t.Fatalf("%q: bad: %d\n\n%s", mode, code, ui.ErrorWriter.String())
+		}
+		expected := map[string]os.FileMode{"": 0600, "0640": 0640, "444": 0444}[mode]
+		info, err := os.Stat(filepath.Join(opts.OutputDir, "app.conf"))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if info.Mode().Perm() != expected {
+			t.Fatalf("%q: bad: %s", mode, info.Mode())
+		}
+	}
+
+	// No temporary files are left behind
+	files, err := ioutil.ReadDir(opts.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("bad: %v", files)
+	}
+
+	for _, tc := range []struct {
+		mode, owner, group string
+		err                string
+	}{
+		{"rw-r-----", "", "", "invalid -file-mode"},
+		{"01777", "", "", "invalid -file-mode"},
+		{"", "no-such-user-vault", "", "no such user"},
+		{"", "", "no-such-group-vault", "no such group"},
+	} {
+		opts.FileMode, opts.FileOwner, opts.FileGroup = tc.mode, tc.owner, tc.group
+		ui := new(cli.MockUi)
+		if code := OutputSecret(ui, "table", s, opts); code != 1 || !strings.Contains(ui.ErrorWriter.String(), tc.err) {
+			t.Fatalf("%v: expected error %q, got %d: %s", tc, tc.err, code, ui.ErrorWriter.String())
+		}
+	}
+
+	// Only root can give the files to another user
+	if runtime.GOOS == "windows" {
+		t.Skip("files have no owner on Windows")
+	}
+	opts.FileMode, opts.FileGroup = "", ""
+	opts.FileOwner = "65534"
+	ui := new(cli.MockUi)
+	code := OutputSecret(ui, "table", s, opts)
+	if os.Getuid() != 0 {
+		if code != 1 || !strings.Contains(ui.ErrorWriter.String(), "not permitted") {
+			t.Fatalf("expected error, got %d: %s", code, ui.ErrorWriter.String())
+		}
+		return
+	}
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+}
+
+func TestTemplateFormatter(t *testing.T) {
+	versioned := &api.Secret{
+		LeaseID: "lease",
+		Data: map[string]interface{}{
+			"data":     map[string]interface{}{"password": "hunter2"},
+			"metadata": map[string]interface{}{"version": json.Number("3")},
+		},
+	}
+	plain := &api.Secret{
+		Data: map[string]interface{}{"password": "hunter2"},
+	}
+
+	cases := []struct {
+		Secret     *api.Secret
+		Template   string
+		MissingKey string
+		Output     string
+		Error      string
+	}{
+		{versioned, "{{.Data.password}} v{{.Metadata.version}} {{.LeaseID}}\n", "", "hunter2 v3 lease\n", ""},
+		{plain, "{{.Data.password}}", "", "hunter2\n", ""},
+		{plain, "{{.Data.missing}}", "", "", "error executing template"},
+		{plain, "{{.Data.missing}}", "default", "<no value>\n", ""},
+		{plain, "{{.Data.password", "", "", "error parsing template"},
+		{plain, "{{.Data.password}}", "bogus", "", "invalid -template-missing-key"},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		opts := &meta.OutputOptions{
+			Template:           tc.Template,
+			TemplateMissingKey: tc.MissingKey,
+		}
+		code := OutputSecret(ui, "template", tc.Secret, opts)
+		if tc.Error != "" {
+			if code == 0 || !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+				t.Fatalf("%q: expected error %q, got %d: %s", tc.Template, tc.Error, code, ui.ErrorWriter.String())
+			}
+			continue
+		}
+		if code != 0 {
+			t.Fatalf("%q: bad: %d\n\n%s", tc.Template, code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != tc.Output {
+			t.Fatalf("%q: bad output: %q", tc.Template, output)
+		}
+	}
+
+	// The template can be read from a file, and is only valid with the
+	// template format
+	ui := new(cli.MockUi)
+	opts := &meta.OutputOptions{Template: "@" + filepath.Join(FixturePath, "output.tmpl")}
+	if code := OutputSecret(ui, "template", plain, opts); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); output != "DB_PASSWORD=hunter2\n" {
+		t.Fatalf("bad output: %q", output)
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputSecret(ui, "table", plain, opts); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "-template can only be used with -format=template") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
@@ -22,8 +22,8 @@ func (c *TokenCreateCommand) Run(args []string) int {
 	var metadata map[string]string
 	var numUses int
 	var policies []string
-	flags := c.Meta.FlagSet("mount", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
+	flags := c.Meta.FlagSet("mount", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
 	flags.StringVar(&displayName, "display-name", "", "")
 	flags.StringVar(&id, "id", "", "")
 	flags.StringVar(&lease, "lease", "", "")
@@ -89,7 +89,7 @@ func (c *TokenCreateCommand) Run(args []string) int {
 		return 2
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 }
 
 func (c *TokenCreateCommand) Synopsis() string {
@@ -165,12 +165,16 @@ Token Options:
                           it is automatically revoked.
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+                          delimited table. This can also be json, yaml, csv,
+                          or raw, which outputs the data as compact JSON
+                          without a trailing newline.
 
   -role=name              If set, the token will be created against the named
                           role. The role may override other parameters. This
                           requires the client to have permissions on the
                           appropriate endpoint (auth/token/create/<name>).
-`
+
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
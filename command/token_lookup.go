@@ -17,9 +17,9 @@ type TokenLookupCommand struct {
 func (c *TokenLookupCommand) Run(args []string) int {
 	var format string
 	var accessor bool
-	flags := c.Meta.FlagSet("token-lookup", meta.FlagSetDefault)
+	flags := c.Meta.FlagSet("token-lookup", meta.FlagSetDefault|meta.FlagSetOutput)
 	flags.BoolVar(&accessor, "accessor", false, "")
-	flags.StringVar(&format, "format", "table", "")
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -59,7 +59,7 @@ func (c *TokenLookupCommand) Run(args []string) int {
 			"error looking up token: %s", err))
 		return 1
 	}
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 }
 
 func doTokenLookup(args []string, client *api.Client) (*api.Secret, error) {
@@ -93,8 +93,11 @@ Token Lookup Options:
                           (and for revocation via '/auth/token/revoke-accessor/<accessor>' endpoint).
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+                          delimited table. This can also be json, yaml, csv,
+                          or raw, which outputs the data as compact JSON
+                          without a trailing newline.
 
-`
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
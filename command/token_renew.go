@@ -17,8 +17,8 @@ type TokenRenewCommand struct {
 
 func (c *TokenRenewCommand) Run(args []string) int {
 	var format, increment string
-	flags := c.Meta.FlagSet("token-renew", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
+	flags := c.Meta.FlagSet("token-renew", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
 	flags.StringVar(&increment, "increment", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
@@ -74,7 +74,7 @@ func (c *TokenRenewCommand) Run(args []string) int {
 		return 1
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 }
 
 func (c *TokenRenewCommand) Synopsis() string {
@@ -108,8 +108,11 @@ Token Renew Options:
                           of seconds or a string duration (e.g. "72h").
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+                          delimited table. This can also be json, yaml, csv,
+                          or raw, which outputs the data as compact JSON
+                          without a trailing newline.
 
-`
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
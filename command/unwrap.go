@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/flag-slice"
 	"github.com/hashicorp/vault/meta"
 )
 
@@ -17,13 +18,15 @@ type UnwrapCommand struct {
 
 func (c *UnwrapCommand) Run(args []string) int {
 	var format string
-	var field string
+	var fields []string
+	var fieldDefault string
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
-	flags = c.Meta.FlagSet("unwrap", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
-	flags.StringVar(&field, "field", "", "")
+	flags = c.Meta.FlagSet("unwrap", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
+	flags.Var((*sliceflag.StringFlag)(&fields), "field", "")
+	flags.StringVar(&fieldDefault, "field-default", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -59,9 +62,12 @@ func (c *UnwrapCommand) Run(args []string) int {
 		return 1
 	}
 
-	// Handle single field output
-	if field != "" {
-		return PrintRawField(c.Ui, secret, field)
+	// Handle field output
+	if len(fields) > 0 {
+		if flagIsSet(flags, "field-default") {
+			return PrintRawFieldsDefault(c.Ui, secret, fields, fieldDefault, c.OutputOptions())
+		}
+		return PrintRawFields(c.Ui, secret, fields, c.OutputOptions())
 	}
 
 	// Check if the original was a list response and format as a list if so
@@ -70,10 +76,10 @@ func (c *UnwrapCommand) Run(args []string) int {
 		secret.Data["keys"] != nil {
 		_, ok := secret.Data["keys"].([]interface{})
 		if ok {
-			return OutputList(c.Ui, format, secret)
+			return OutputList(c.Ui, format, secret, c.OutputOptions())
 		}
 	}
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 }
 
 func (c *UnwrapCommand) Synopsis() string {
@@ -94,11 +100,22 @@ General Options:
 Read Options:
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+                          delimited table. This can also be json, yaml, csv,
+                          or raw, which outputs the data as compact JSON
+                          without a trailing newline.
 
   -field=field            If included, the raw value of the specified field
-                          will be output raw to stdout.
-
-`
+                          will be output raw to stdout. Nested fields can be
+                          given as a dotted path, such as "a.b" or "list.0".
+                          This can be specified multiple times to output
+                          several fields on one line, separated by tabs in
+                          the order given.
+
+  -field-default=value    The value to output when the field given by -field
+                          is not present in the secret. By default a missing
+                          field is an error.
+
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
@@ -1,36 +1,141 @@
 package command
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/command/token"
+	"github.com/hashicorp/vault/meta"
 	"github.com/mitchellh/cli"
 )
 
-// DefaultTokenHelper returns the token helper that is configured for Vault.
+// DefaultTokenHelper returns the token helper that is configured for Vault,
+// taking into account the profile selected by the VAULT_PROFILE environment
+// variable.
 func DefaultTokenHelper() (token.TokenHelper, error) {
 	config, err := LoadConfig("")
 	if err != nil {
 		return nil, err
 	}
 
+	return configTokenHelper(config, os.Getenv(ProfileEnv))
+}
+
+// configTokenHelper returns the token helper configured for the given
+// profile, or at the top level if profile is empty or does not set one.
+func configTokenHelper(config *DefaultConfig, profile string) (token.TokenHelper, error) {
 	path := config.TokenHelper
+	if profile != "" {
+		p, ok := config.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("Profile %q is not defined in the configuration", profile)
+		}
+		if p.TokenHelper != "" {
+			helperPath, err := token.ExternalTokenHelperPath(p.TokenHelper)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Error with the token helper %q of profile %q: %s",
+					p.TokenHelper, profile, err)
+			}
+			return &token.ExternalTokenHelper{BinaryPath: helperPath}, nil
+		}
+	}
+
 	if path == "" {
 		return &token.InternalTokenHelper{}, nil
 	}
 
-	path, err = token.ExternalTokenHelperPath(path)
+	path, err := token.ExternalTokenHelperPath(path)
 	if err != nil {
 		return nil, err
 	}
 	return &token.ExternalTokenHelper{BinaryPath: path}, nil
 }
 
-func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
+// DefaultAllowedAddresses returns the address patterns the Vault server
+// address must match, as configured in the CLI configuration file.
+func DefaultAllowedAddresses() ([]string, error) {
+	config, err := LoadConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	return config.AllowedAddresses, nil
+}
+
+// PrintRawField prints the raw value of a single field of the secret. The
+// output options may be nil, in which case the defaults are used.
+func PrintRawField(ui cli.Ui, secret *api.Secret, field string, opts *meta.OutputOptions) int {
+	return printRawFields(ui, secret, []string{field}, nil, opts)
+}
+
+// PrintRawFieldDefault is like PrintRawField, but prints the given default
+// value rather than failing when the field is not present in the secret.
+func PrintRawFieldDefault(ui cli.Ui, secret *api.Secret, field, def string, opts *meta.OutputOptions) int {
+	return printRawFields(ui, secret, []string{field}, &def, opts)
+}
+
+// PrintRawFields prints the raw values of the given fields of the secret,
+// separated by tabs in the order given, on a single line. Like
+// PrintRawField, a single field is printed without a trailing newline.
+func PrintRawFields(ui cli.Ui, secret *api.Secret, fields []string, opts *meta.OutputOptions) int {
+	return printRawFields(ui, secret, fields, nil, opts)
+}
+
+// PrintRawFieldsDefault is like PrintRawFields, but prints the given default
+// value for each field that is not present in the secret.
+func PrintRawFieldsDefault(ui cli.Ui, secret *api.Secret, fields []string, def string, opts *meta.OutputOptions) int {
+	return printRawFields(ui, secret, fields, &def, opts)
+}
+
+func printRawFields(ui cli.Ui, secret *api.Secret, fields []string, def *string, opts *meta.OutputOptions) int {
+	secret = redactSensitive(secret, opts)
+	setSyslogKeys(ui, fields)
+
+	// Nothing is printed unless every field is found
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if opts != nil && opts.FieldSingle {
+			if n := len(lookupFields(secret.Data, field)); n > 1 {
+				ui.Error(fmt.Sprintf(
+					"Field %s is ambiguous: it matches %d values in the secret", field, n))
+				return 1
+			}
+		}
+
+		val := rawFieldValue(secret, field)
+		if val == nil && def != nil {
+			val = *def
+		}
+		if val == nil {
+			ui.Error(fieldNotPresent(secret.Data, field))
+			return 1
+		}
+
+		out := fmt.Sprintf("%v", val)
+		if opts == nil || !opts.NoSanitize {
+			out = sanitize(out)
+		}
+		values = append(values, out)
+	}
+
+	if len(values) == 1 {
+		outputRaw(ui, values[0])
+	} else {
+		ui.Output(strings.Join(values, "\t"))
+	}
+	return 0
+}
+
+// rawFieldValue returns the value of the field of the secret for -field, or
+// nil if it is not present. Besides the data, the fields of the auth and wrap
+// info that table output shows can be given.
+func rawFieldValue(secret *api.Secret, field string) interface{} {
 	var val interface{}
 	switch {
 	case secret.Auth != nil:
@@ -46,7 +151,7 @@ func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
 		case "token_policies":
 			val = secret.Auth.Policies
 		default:
-			val = secret.Data[field]
+			val, _ = lookupField(secret.Data, field)
 		}
 
 	case secret.WrapInfo != nil:
@@ -62,7 +167,7 @@ func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
 		case "wrapped_accessor":
 			val = secret.WrapInfo.WrappedAccessor
 		default:
-			val = secret.Data[field]
+			val, _ = lookupField(secret.Data, field)
 		}
 
 	default:
@@ -70,25 +175,111 @@ func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
 		case "refresh_interval":
 			val = secret.LeaseDuration
 		default:
-			val = secret.Data[field]
+			val, _ = lookupField(secret.Data, field)
 		}
 	}
+	return val
+}
+
+// fieldNotPresent returns the error for a field that lookupField did not
+// find. For a path of keys it names the part of the path that was found and
+// the key that is missing from it.
+func fieldNotPresent(data map[string]interface{}, field string) string {
+	msg := fmt.Sprintf("Field %s not present in secret", field)
+	if _, ok := lookupField(data, field); ok {
+		// The field is there, but null
+		return msg
+	}
 
-	if val != nil {
-		// c.Ui.Output() prints a CR character which in this case is
-		// not desired. Since Vault CLI currently only uses BasicUi,
-		// which writes to standard output, os.Stdout is used here to
-		// directly print the message. If mitchellh/cli exposes method
-		// to print without CR, this check needs to be removed.
-		if reflect.TypeOf(ui).String() == "*cli.BasicUi" {
-			fmt.Fprintf(os.Stdout, "%v", val)
-		} else {
-			ui.Output(fmt.Sprintf("%v", val))
-		}
-		return 0
-	} else {
-		ui.Error(fmt.Sprintf(
-			"Field %s not present in secret", field))
-		return 1
+	// Find the longest part of the path that is present
+	for i := len(field) - 1; i > 0; i-- {
+		if field[i] != '.' {
+			continue
+		}
+		parent, key := field[:i], field[i+1:]
+		v, ok := lookupField(data, parent)
+		if !ok {
+			continue
+		}
+		if j := strings.Index(key, "."); j >= 0 {
+			key = key[:j]
+		}
+		switch v.(type) {
+		case map[string]interface{}:
+			return fmt.Sprintf("%s: %s has no field %q", msg, parent, key)
+		case []interface{}:
+			return fmt.Sprintf("%s: %s has no element %q", msg, parent, key)
+		default:
+			return fmt.Sprintf("%s: %s is not an object or array", msg, parent)
+		}
 	}
+	return msg
+}
+
+// lookupField returns the value of the given field of the data, and whether
+// it was found. The field can be a path of keys separated by dots, such as
+// "a.b", to look up a field of a nested object, and a key can be the index of
+// an element of an array, such as "a.0". Keys that contain dots themselves
+// are found as well, and take precedence if the path matches more than one
+// value.
+func lookupField(data map[string]interface{}, field string) (interface{}, bool) {
+	values := lookupFields(data, field)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// lookupFields returns every value the field path matches in the data, in
+// the order lookupField prefers them, as a path such as "a.b" may be a key
+// with a dot as well as a nested field.
+func lookupFields(data map[string]interface{}, field string) []interface{} {
+	var values []interface{}
+	if v, ok := data[field]; ok {
+		values = append(values, v)
+	}
+
+	for i := 0; i < len(field); i++ {
+		if field[i] != '.' {
+			continue
+		}
+		if v, ok := data[field[:i]]; ok {
+			values = append(values, lookupValues(v, field[i+1:])...)
+		}
+	}
+	return values
+}
+
+// lookupValues looks up the field path within a nested object or array.
+func lookupValues(v interface{}, field string) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return lookupFields(v, field)
+	case []interface{}:
+		index, rest := field, ""
+		if i := strings.Index(field, "."); i >= 0 {
+			index, rest = field[:i], field[i+1:]
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil
+		}
+		if rest == "" {
+			return []interface{}{v[i]}
+		}
+		return lookupValues(v[i], rest)
+	}
+	return nil
+}
+
+// flagIsSet returns whether the flag with the given name was given on the
+// command line, as opposed to having its default value.
+func flagIsSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
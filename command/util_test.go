@@ -0,0 +1,114 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+func TestLookupField(t *testing.T) {
+	data := map[string]interface{}{
+		"top":        "value",
+		"dotted.key": "dotted",
+		"nested": map[string]interface{}{
+			"inner": map[string]interface{}{"deep": "found"},
+			"null":  nil,
+		},
+		"list": []interface{}{
+			"zero",
+			map[string]interface{}{"name": "one"},
+		},
+	}
+
+	cases := []struct {
+		Field string
+		Value interface{}
+		Found bool
+	}{
+		{"top", "value", true},
+		{"dotted.key", "dotted", true},
+		{"nested.inner.deep", "found", true},
+		{"nested.inner", map[string]interface{}{"deep": "found"}, true},
+		{"nested.null", nil, true},
+		{"list.0", "zero", true},
+		{"list.1.name", "one", true},
+		{"nope", nil, false},
+		{"top.nope", nil, false},
+		{"nested.nope", nil, false},
+		{"list.2", nil, false},
+		{"list.x", nil, false},
+	}
+
+	for _, tc := range cases {
+		value, found := lookupField(data, tc.Field)
+		if found != tc.Found || !reflect.DeepEqual(value, tc.Value) {
+			t.Fatalf("%s: bad: %#v, %t", tc.Field, value, found)
+		}
+	}
+}
+
+func TestFieldNotPresent(t *testing.T) {
+	data := map[string]interface{}{
+		"top": "value",
+		"nested": map[string]interface{}{
+			"inner": map[string]interface{}{"deep": "found"},
+			"null":  nil,
+		},
+		"list": []interface{}{"zero"},
+	}
+
+	cases := map[string]string{
+		"nope":             "Field nope not present in secret",
+		"nested.null":      "Field nested.null not present in secret",
+		"nested.nope":      `Field nested.nope not present in secret: nested has no field "nope"`,
+		"nested.inner.x.y": `Field nested.inner.x.y not present in secret: nested.inner has no field "x"`,
+		"list.3.name":      `Field list.3.name not present in secret: list has no element "3"`,
+		"top.nope":         "Field top.nope not present in secret: top is not an object or array",
+		"nested.null.nope": "Field nested.null.nope not present in secret: nested.null is not an object or array",
+	}
+
+	for field, expected := range cases {
+		if actual := fieldNotPresent(data, field); actual != expected {
+			t.Fatalf("%s: bad: %s", field, actual)
+		}
+	}
+}
+
+func TestPrintRawField_single(t *testing.T) {
+	s := &api.Secret{
+		Data: map[string]interface{}{
+			"a.b": "dotted",
+			"a":   map[string]interface{}{"b": "nested", "c": "only"},
+		},
+	}
+
+	cases := []struct {
+		Field  string
+		Single bool
+		Code   int
+		Output string
+		Error  string
+	}{
+		{"a.b", false, 0, "dotted\n", ""},
+		{"a.b", true, 1, "", "Field a.b is ambiguous: it matches 2 values in the secret"},
+		{"a.c", true, 0, "only\n", ""},
+		{"a.d", true, 1, "", "Field a.d not present in secret"},
+	}
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		opts := &meta.OutputOptions{FieldSingle: tc.Single}
+		if code := PrintRawField(ui, s, tc.Field, opts); code != tc.Code {
+			t.Fatalf("%s: bad: %d\n\n%s", tc.Field, code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+			t.Fatalf("%s: expected error %q, got: %s", tc.Field, tc.Error, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != tc.Output {
+			t.Fatalf("%s: bad output: %q", tc.Field, output)
+		}
+	}
+}
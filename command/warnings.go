@@ -0,0 +1,53 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/vault/meta"
+)
+
+// warningLines returns the lines the warnings returned with a response are
+// printed with below a table. By default they are listed under a heading;
+// with -warnings-as-footnotes they are numbered, so that the rows they refer
+// to can point at them with warningRefs.
+func (t TableFormatter) warningLines(warnings []string, opts *meta.OutputOptions) []string {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(warnings)+2)
+	lines = append(lines, "")
+	if !opts.WarningsAsFootnotes {
+		lines = append(lines, "The following warnings were returned from the Vault server:")
+	}
+	for i, warning := range warnings {
+		if opts.WarningsAsFootnotes {
+			lines = append(lines, fmt.Sprintf("[%d] %s", i+1, t.formatText(warning, opts)))
+		} else {
+			lines = append(lines, fmt.Sprintf("* %s", t.formatText(warning, opts)))
+		}
+	}
+	return lines
+}
+
+// warningRefs returns the footnote markers, such as " [1][3]", of the
+// warnings that mention the key as a whole word, with
+// -warnings-as-footnotes.
+func warningRefs(warnings []string, key string, opts *meta.OutputOptions) string {
+	if !opts.WarningsAsFootnotes || len(warnings) == 0 || key == "" {
+		return ""
+	}
+
+	re := regexp.MustCompile(`(^|[^\w])` + regexp.QuoteMeta(key) + `([^\w]|$)`)
+	var refs string
+	for i, warning := range warnings {
+		if re.MatchString(warning) {
+			refs += fmt.Sprintf("[%d]", i+1)
+		}
+	}
+	if refs == "" {
+		return ""
+	}
+	return " " + refs
+}
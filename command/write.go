@@ -6,7 +6,9 @@ import (
 	"os"
 	"strings"
 
+	"github.com/hashicorp/vault/helper/flag-slice"
 	"github.com/hashicorp/vault/helper/kv-builder"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/meta"
 	"github.com/posener/complete"
 )
@@ -20,11 +22,18 @@ type WriteCommand struct {
 }
 
 func (c *WriteCommand) Run(args []string) int {
-	var field, format string
+	var fields []string
+	var format string
+	var fieldDefault string
+	var inputFormat string
+	var onDuplicate string
 	var force bool
-	flags := c.Meta.FlagSet("write", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
-	flags.StringVar(&field, "field", "", "")
+	flags := c.Meta.FlagSet("write", meta.FlagSetDefault|meta.FlagSetOutput)
+	flags.StringVar(&format, "format", meta.DefaultFormat("table"), "")
+	flags.Var((*sliceflag.StringFlag)(&fields), "field", "")
+	flags.StringVar(&fieldDefault, "field-default", "", "")
+	flags.StringVar(&inputFormat, "input-format", "json", "")
+	flags.StringVar(&onDuplicate, "on-duplicate", "append", "")
 	flags.BoolVar(&force, "force", false, "")
 	flags.BoolVar(&force, "f", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
@@ -45,18 +54,41 @@ func (c *WriteCommand) Run(args []string) int {
 		return 1
 	}
 
-	path := args[0]
+	path, err := expandPath(args[0])
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 	if path[0] == '/' {
 		path = path[1:]
 	}
 
-	data, err := c.parseData(args[1:])
+	if !strutil.StrListContains(kvbuilder.Formats, inputFormat) {
+		c.Ui.Error(fmt.Sprintf(
+			"Invalid input format: %s", inputFormat))
+		return 1
+	}
+
+	if !strutil.StrListContains(kvbuilder.DuplicateModes, onDuplicate) {
+		c.Ui.Error(fmt.Sprintf(
+			"Invalid -on-duplicate mode: %s", onDuplicate))
+		return 1
+	}
+
+	data, err := c.parseData(args[1:], inputFormat, onDuplicate)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error loading data: %s", err))
 		return 1
 	}
 
+	done, err := startSyslog(&c.Meta, path)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	defer done()
+
 	client, err := c.Client()
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -79,21 +111,24 @@ func (c *WriteCommand) Run(args []string) int {
 		return 0
 	}
 
-	// Handle single field output
-	if field != "" {
-		return PrintRawField(c.Ui, secret, field)
+	// Handle field output
+	if len(fields) > 0 {
+		if flagIsSet(flags, "field-default") {
+			return PrintRawFieldsDefault(c.Ui, secret, fields, fieldDefault, c.OutputOptions())
+		}
+		return PrintRawFields(c.Ui, secret, fields, c.OutputOptions())
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, format, secret, c.OutputOptions())
 }
 
-func (c *WriteCommand) parseData(args []string) (map[string]interface{}, error) {
+func (c *WriteCommand) parseData(args []string, format, onDuplicate string) (map[string]interface{}, error) {
 	var stdin io.Reader = os.Stdin
 	if c.testStdin != nil {
 		stdin = c.testStdin
 	}
 
-	builder := &kvbuilder.Builder{Stdin: stdin}
+	builder := &kvbuilder.Builder{Stdin: stdin, Format: format, OnDuplicate: onDuplicate}
 	if err := builder.Add(args...); err != nil {
 		return nil, err
 	}
@@ -118,10 +153,14 @@ Usage: vault write [options] path [data]
   Consul at that key. Check the documentation of the logical backend you're
   using for more information on key structure.
 
+  The path may be a template using the date and env functions, such as
+  secret/{{env "APP"}}/config; see "vault read -h".
+
   Data is sent via additional arguments in "key=value" pairs. If value begins
-  with an "@", then it is loaded from a file. Write expects data in the file to
-  be in JSON format. If you want to start the value with a literal "@", then
-  prefix the "@" with a slash: "\@".
+  with an "@", then it is loaded from a file. If you want to start the value
+  with a literal "@", then prefix the "@" with a slash: "\@". The data can
+  also be given as a whole, from a file with "@path" or from stdin with "-",
+  in the format given by -input-format.
 
 General Options:
 ` + meta.GeneralOptionsUsage() + `
@@ -132,12 +171,32 @@ Write Options:
                           need or expect any fields to be specified.
 
   -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+                          delimited table. This can also be json, yaml, csv,
+                          or raw, which outputs the data as compact JSON
+                          without a trailing newline.
 
   -field=field            If included, the raw value of the specified field
-                          will be output raw to stdout.
-
-`
+                          will be output raw to stdout. Nested fields can be
+                          given as a dotted path, such as "a.b" or "list.0".
+                          This can be specified multiple times to output
+                          several fields on one line, separated by tabs in
+                          the order given.
+
+  -field-default=value    The value to output when the field given by -field
+                          is not present in the secret. By default a missing
+                          field is an error.
+
+  -input-format=json      The format of the data read from a file with "@path"
+                          or from stdin with "-". This can also be yaml or
+                          hcl.
+
+  -on-duplicate=append    How a key given more than once as "key=value" is
+                          handled. By default the values are sent as an
+                          array; "first" and "last" keep only the first or
+                          last value, and "error" rejects the write.
+
+Output Options:
+` + meta.OutputOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
 
@@ -147,8 +206,11 @@ func (c *WriteCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *WriteCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-force":  complete.PredictNothing,
-		"-format": predictFormat,
-		"-field":  complete.PredictNothing,
+		"-force":         complete.PredictNothing,
+		"-format":        predictFormat,
+		"-field":         complete.PredictNothing,
+		"-field-default": complete.PredictNothing,
+		"-input-format":  complete.PredictSet(kvbuilder.Formats...),
+		"-on-duplicate":  complete.PredictSet(kvbuilder.DuplicateModes...),
 	}
 }
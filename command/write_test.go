@@ -3,6 +3,8 @@ package command
 import (
 	"io"
 	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -270,3 +272,134 @@ func TestWrite_force(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestWrite_inputFormat(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &WriteCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+
+		testStdin: strings.NewReader("foo: bar\nttl: 1h\n"),
+	}
+
+	args := []string{
+		"-address", addr,
+		"-input-format", "yaml",
+		"secret/foo",
+		"-",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp, err := client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.Data["foo"] != "bar" || resp.Data["ttl"] != "1h" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestWrite_inputFormatInvalid(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		requests++
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Format string
+		Input  string
+		Error  string
+	}{
+		{"hcl", "foo = [", "Error loading data"},
+		{"yaml", "- foo\n", "Error loading data"},
+		{"toml", `foo = "bar"`, "Invalid input format: toml"},
+	}
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		c := &WriteCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+
+			testStdin: strings.NewReader(tc.Input),
+		}
+
+		args := []string{"-address", ts.URL, "-input-format", tc.Format, "secret/foo", "-"}
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("%s: bad: %d", tc.Format, code)
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+			t.Fatalf("%s: bad error: %s", tc.Format, ui.ErrorWriter.String())
+		}
+	}
+	if requests != 0 {
+		t.Fatalf("expected no requests, got %d", requests)
+	}
+}
+
+func TestWrite_onDuplicate(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, strings.TrimSpace(string(body)))
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Mode  string
+		Code  int
+		Body  string
+		Error string
+	}{
+		{"", 0, `{"foo":["a","b"]}`, ""},
+		{"append", 0, `{"foo":["a","b"]}`, ""},
+		{"first", 0, `{"foo":"a"}`, ""},
+		{"last", 0, `{"foo":"b"}`, ""},
+		{"error", 1, "", `duplicate key "foo"`},
+		{"merge", 1, "", "Invalid -on-duplicate mode: merge"},
+	}
+	for _, tc := range cases {
+		bodies = nil
+		ui := cli.NewMockUi()
+		c := &WriteCommand{
+			Meta: meta.Meta{
+				ClientToken: "foo",
+				Ui:          ui,
+			},
+		}
+
+		args := []string{"-address", ts.URL}
+		if tc.Mode != "" {
+			args = append(args, "-on-duplicate", tc.Mode)
+		}
+		if code := c.Run(append(args, "secret/foo", "foo=a", "foo=b")); code != tc.Code {
+			t.Fatalf("%q: bad: %d\n\n%s", tc.Mode, code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), tc.Error) {
+			t.Fatalf("%q: bad error: %s", tc.Mode, ui.ErrorWriter.String())
+		}
+		if tc.Body == "" {
+			if len(bodies) != 0 {
+				t.Fatalf("%q: expected no requests, got %v", tc.Mode, bodies)
+			}
+		} else if len(bodies) != 1 || bodies[0] != tc.Body {
+			t.Fatalf("%q: bad body: %v", tc.Mode, bodies)
+		}
+	}
+}
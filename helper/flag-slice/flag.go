@@ -14,3 +14,21 @@ func (s *StringFlag) Set(value string) error {
 	*s = append(*s, value)
 	return nil
 }
+
+// CommaStringFlag is like StringFlag, but also splits each value on commas,
+// so that "-f a,b -f c" results in the list "a", "b", "c". Empty elements
+// are dropped.
+type CommaStringFlag []string
+
+func (s *CommaStringFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *CommaStringFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
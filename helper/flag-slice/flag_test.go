@@ -31,3 +31,29 @@ func TestStringFlagSet(t *testing.T) {
 		t.Fatalf("Bad: %#v", sv)
 	}
 }
+
+func TestCommaStringFlag_implements(t *testing.T) {
+	var raw interface{}
+	raw = new(CommaStringFlag)
+	if _, ok := raw.(flag.Value); !ok {
+		t.Fatalf("CommaStringFlag should be a Value")
+	}
+}
+
+func TestCommaStringFlagSet(t *testing.T) {
+	sv := new(CommaStringFlag)
+	err := sv.Set("foo, bar")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = sv.Set("baz,")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"foo", "bar", "baz"}
+	if !reflect.DeepEqual([]string(*sv), expected) {
+		t.Fatalf("Bad: %#v", sv)
+	}
+}
@@ -2,21 +2,40 @@ package kvbuilder
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"strings"
 
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/mitchellh/mapstructure"
 )
 
+// Formats are the formats that data read from stdin or a file can be in.
+var Formats = []string{"json", "yaml", "hcl"}
+
+// DuplicateModes are the ways a key given more than once can be handled:
+// "append" collects the values into a slice, "first" and "last" keep only
+// the first or last value, and "error" rejects the key.
+var DuplicateModes = []string{"append", "first", "last", "error"}
+
 // Builder is a struct to build a key/value mapping based on a list
 // of "k=v" pairs, where the value might come from stdin, a file, etc.
 type Builder struct {
 	Stdin io.Reader
 
+	// Format is the format of the data read from stdin or a file, one of
+	// Formats. It defaults to JSON.
+	Format string
+
+	// OnDuplicate is how a key given more than once as "k=v" is handled, one
+	// of DuplicateModes. It defaults to "append".
+	OnDuplicate string
+
 	result map[string]interface{}
 	stdin  bool
 }
@@ -111,8 +130,22 @@ func (b *Builder) add(raw string) error {
 		}
 	}
 
-	// Repeated keys will be converted into a slice
+	// Repeated keys will be converted into a slice unless another mode is
+	// given
 	if existingValue, ok := b.result[key]; ok {
+		switch b.OnDuplicate {
+		case "", "append":
+		case "first":
+			return nil
+		case "last":
+			b.result[key] = value
+			return nil
+		case "error":
+			return fmt.Errorf("duplicate key %q", key)
+		default:
+			return fmt.Errorf("unsupported duplicate mode %q", b.OnDuplicate)
+		}
+
 		var sliceValue []interface{}
 		if err := mapstructure.WeakDecode(existingValue, &sliceValue); err != nil {
 			return err
@@ -127,5 +160,36 @@ func (b *Builder) add(raw string) error {
 }
 
 func (b *Builder) addReader(r io.Reader) error {
-	return jsonutil.DecodeJSONFromReader(r, &b.result)
+	if b.Format == "" || b.Format == "json" {
+		return jsonutil.DecodeJSONFromReader(r, &b.result)
+	}
+
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Other formats are converted to JSON so that the values are decoded
+	// the same way as JSON ones
+	var converted []byte
+	switch b.Format {
+	case "yaml":
+		converted, err = yaml.YAMLToJSON(contents)
+		if err != nil {
+			return fmt.Errorf("error parsing YAML: %s", err)
+		}
+	case "hcl":
+		var m map[string]interface{}
+		if err := hcl.Decode(&m, string(contents)); err != nil {
+			return fmt.Errorf("error parsing HCL: %s", err)
+		}
+		converted, err = json.Marshal(m)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", b.Format)
+	}
+
+	return jsonutil.DecodeJSON(converted, &b.result)
 }
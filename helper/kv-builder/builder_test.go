@@ -2,7 +2,9 @@ package kvbuilder
 
 import (
 	"bytes"
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -119,6 +121,39 @@ func TestBuilder_sameKeyMultipleTimes(t *testing.T) {
 	}
 }
 
+func TestBuilder_onDuplicate(t *testing.T) {
+	cases := []struct {
+		Mode     string
+		Expected interface{}
+		Err      string
+	}{
+		{"", []interface{}{"bar", "baz", "bay"}, ""},
+		{"append", []interface{}{"bar", "baz", "bay"}, ""},
+		{"first", "bar", ""},
+		{"last", "bay", ""},
+		{"error", nil, `duplicate key "foo"`},
+	}
+
+	for _, tc := range cases {
+		b := Builder{OnDuplicate: tc.Mode}
+		err := b.Add("foo=bar", "other=x", "foo=baz", "foo=bay")
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%q: bad: %v", tc.Mode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: err: %s", tc.Mode, err)
+		}
+
+		expected := map[string]interface{}{"foo": tc.Expected, "other": "x"}
+		if actual := b.Map(); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%q: bad: %#v", tc.Mode, actual)
+		}
+	}
+}
+
 func TestBuilder_specialCharacteresInKey(t *testing.T) {
 	var b Builder
 	b.Stdin = bytes.NewBufferString("{\"foo\": \"bay\"}")
@@ -137,3 +172,42 @@ func TestBuilder_specialCharacteresInKey(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 }
+
+func TestBuilder_stdinFormats(t *testing.T) {
+	inputs := map[string]string{
+		"json": `{"foo": "bar", "ttl": 3600, "policies": ["a", "b"]}`,
+		"yaml": "foo: bar\nttl: 3600\npolicies:\n- a\n- b\n",
+		"hcl":  "foo = \"bar\"\nttl = 3600\npolicies = [\"a\", \"b\"]\n",
+	}
+
+	expected := map[string]interface{}{
+		"foo":      "bar",
+		"ttl":      json.Number("3600"),
+		"policies": []interface{}{"a", "b"},
+		"bar":      "baz",
+	}
+	for format, input := range inputs {
+		b := Builder{Format: format}
+		b.Stdin = bytes.NewBufferString(input)
+		if err := b.Add("-", "bar=baz"); err != nil {
+			t.Fatalf("%s: err: %s", format, err)
+		}
+		if actual := b.Map(); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%s: bad: %#v", format, actual)
+		}
+	}
+
+	// Input that is not in the given format is an error
+	for format, input := range map[string]string{
+		"json": "foo: bar",
+		"yaml": "- foo\n- bar\n",
+		"hcl":  "foo = [",
+		"toml": `foo = "bar"`,
+	} {
+		b := Builder{Format: format}
+		b.Stdin = bytes.NewBufferString(input)
+		if err := b.Add("-"); err == nil {
+			t.Fatalf("%s: expected error", format)
+		}
+	}
+}
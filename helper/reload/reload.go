@@ -52,3 +52,9 @@ func (cg *CertificateGetter) GetCertificate(clientHello *tls.ClientHelloInfo) (*
 
 	return cg.cert, nil
 }
+
+// GetClientCertificate satisfies the tls.Config GetClientCertificate function
+// signature, so that a client can present the certificate.
+func (cg *CertificateGetter) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return cg.GetCertificate(nil)
+}
@@ -0,0 +1,49 @@
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// AutoLoginFunc logs in to Vault with the client, which has no token, using
+// the given -auth-config settings, and returns the response of the login.
+type AutoLoginFunc func(client *api.Client, config map[string]string) (*api.Secret, error)
+
+// autoLogin logs in with the method given by -auth and returns the token.
+// With -auth-cache the token is also stored with the token helper, so that
+// later commands use it without logging in again.
+func (m *Meta) autoLogin(client *api.Client) (string, error) {
+	login, ok := m.AutoLogins[m.flagAuth]
+	if !ok {
+		methods := make([]string, 0, len(m.AutoLogins))
+		for k := range m.AutoLogins {
+			methods = append(methods, k)
+		}
+		sort.Strings(methods)
+		return "", fmt.Errorf(
+			"unknown -auth method %q, expected one of: %s", m.flagAuth, strings.Join(methods, ", "))
+	}
+
+	secret, err := login(client, m.flagAuthConfig)
+	if err != nil {
+		return "", fmt.Errorf("error logging in with -auth=%s: %s", m.flagAuth, err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return "", fmt.Errorf("error logging in with -auth=%s: no token was returned", m.flagAuth)
+	}
+	token := secret.Auth.ClientToken
+
+	if m.flagAuthCache && m.TokenHelper != nil {
+		tokenHelper, err := m.TokenHelper()
+		if err != nil {
+			return "", err
+		}
+		if err := tokenHelper.Store(token); err != nil {
+			return "", fmt.Errorf("error caching the token from -auth=%s: %s", m.flagAuth, err)
+		}
+	}
+	return token, nil
+}
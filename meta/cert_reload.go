@@ -0,0 +1,36 @@
+package meta
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hashicorp/vault/helper/reload"
+	"github.com/mitchellh/cli"
+)
+
+// reloadingClientCert loads the client certificate and its key, and reloads
+// them from disk whenever the process receives SIGHUP, so that a command
+// that stays running picks up rotated certificates. The TLS handshakes of
+// new connections present the certificate through the getter's
+// GetClientCertificate. If a reload fails, the error is reported to the UI
+// and the previous certificate is kept.
+func reloadingClientCert(certFile, keyFile string, ui cli.Ui) (*reload.CertificateGetter, error) {
+	cg := reload.NewCertificateGetter(certFile, keyFile)
+	if err := cg.Reload(nil); err != nil {
+		return nil, err
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		for range sigCh {
+			if err := cg.Reload(nil); err != nil && ui != nil {
+				ui.Error(fmt.Sprintf(
+					"Error reloading the client certificate, keeping the previous one: %s", err))
+			}
+		}
+	}()
+	return cg, nil
+}
@@ -0,0 +1,96 @@
+package meta
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClient_reloadClientCert(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP cannot be sent on Windows")
+	}
+
+	var lock sync.Mutex
+	var subject string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		subject = r.TLS.PeerCertificates[0].Subject.CommonName
+		lock.Unlock()
+		// Each request makes a new connection, and so a new handshake
+		w.Header().Set("Connection", "close")
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault-meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	install := func(name string) {
+		for src, dst := range map[string]string{name + "-cert.pem": certFile, name + "-key.pem": keyFile} {
+			data, err := ioutil.ReadFile(filepath.Join("test-fixtures", src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(dst, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	install("client")
+	m := Meta{ClientToken: "foo"}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-tls-skip-verify",
+		"-client-cert", certFile, "-client-key", keyFile}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	read := func() string {
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		return subject
+	}
+	if s := read(); s != "vault-client" {
+		t.Fatalf("bad: %s", s)
+	}
+
+	// The new certificate is only used once the signal is received
+	install("client2")
+	if s := read(); s != "vault-client" {
+		t.Fatalf("bad: %s", s)
+	}
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for read() != "vault-client-2" {
+		if time.Now().After(deadline) {
+			t.Fatal("the reloaded certificate was not presented")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
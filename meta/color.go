@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// EnvVaultCLINoColor is the environment variable that disables colored
+// output, like -no-color, when set to a true value.
+const EnvVaultCLINoColor = "VAULT_CLI_NO_COLOR"
+
+// ansiEscape matches an ANSI control sequence, such as a color code.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]")
+
+// NoColorEnv reports whether the environment disables colored output: either
+// VAULT_CLI_NO_COLOR is set to a true value, or NO_COLOR is set to anything,
+// as described at https://no-color.org.
+func NoColorEnv() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	noColor, _ := strconv.ParseBool(os.Getenv(EnvVaultCLINoColor))
+	return noColor
+}
+
+// StripANSI returns s with its ANSI control sequences removed.
+func StripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// HelpWriter returns the writer that the help of the CLI is written to. Help
+// is only colored when it is written to a terminal and neither -no-color,
+// which is looked for in the arguments since they have not been parsed yet,
+// nor the environment disables it; otherwise the ANSI control sequences are
+// stripped from it.
+func HelpWriter(w *os.File, args []string) io.Writer {
+	color := !NoColorEnv() && terminal.IsTerminal(int(w.Fd()))
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch arg {
+		case "-no-color", "--no-color", "-no-color=true", "--no-color=true":
+			color = false
+		}
+	}
+
+	if color {
+		return w
+	}
+	return &stripANSIWriter{w}
+}
+
+// stripANSIWriter strips the ANSI control sequences from what is written to
+// it. A sequence split across writes is not stripped, which is not a concern
+// for help, which is written at once.
+type stripANSIWriter struct {
+	w io.Writer
+}
+
+func (s *stripANSIWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(s.w, StripANSI(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
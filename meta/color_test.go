@@ -0,0 +1,68 @@
+package meta
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNoColorEnv(t *testing.T) {
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+	defer os.Setenv(EnvVaultCLINoColor, os.Getenv(EnvVaultCLINoColor))
+
+	cases := []struct {
+		NoColor      string
+		VaultNoColor string
+		Expected     bool
+	}{
+		{"", "", false},
+		{"1", "", true},
+		{"", "true", true},
+		{"", "1", true},
+		{"", "false", false},
+		{"", "nope", false},
+	}
+
+	for i, tc := range cases {
+		os.Setenv("NO_COLOR", tc.NoColor)
+		os.Setenv(EnvVaultCLINoColor, tc.VaultNoColor)
+		if actual := NoColorEnv(); actual != tc.Expected {
+			t.Fatalf("%d: bad: %t", i, actual)
+		}
+	}
+}
+
+func TestHelpWriter(t *testing.T) {
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+	os.Setenv("NO_COLOR", "")
+
+	f, err := ioutil.TempFile("", "vault-help")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// A file is not a terminal, so the help is never colored
+	for _, args := range [][]string{nil, {"read", "-no-color"}} {
+		w := HelpWriter(f, args)
+		if w == f {
+			t.Fatalf("%v: help is not stripped", args)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := &stripANSIWriter{&buf}
+	in := []byte("\x1b[1mUsage:\x1b[0m vault \x1b[34;1mread\x1b[0m\n")
+	n, err := w.Write(in)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n != len(in) {
+		t.Fatalf("bad: %d", n)
+	}
+	if buf.String() != "Usage: vault read\n" {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
@@ -0,0 +1,44 @@
+package meta
+
+import (
+	"net/http"
+	"time"
+)
+
+// requestTimeoutHeader is the header -propagate-deadline tells the server
+// how long the client will wait for the response in. It is advisory: this
+// version of the server ignores it, but a proxy in front of it, or a later
+// version, may use it to give up on requests nobody is waiting for.
+const requestTimeoutHeader = "X-Vault-Request-Timeout"
+
+// deadlineTransport is an http.RoundTripper that sets the
+// X-Vault-Request-Timeout header of each request to the time left until the
+// client gives up on it: the timeout of the client, or the deadline of the
+// request's context if that is sooner.
+type deadlineTransport struct {
+	client *http.Client
+	base   http.RoundTripper
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	remaining := t.client.Timeout
+	if deadline, ok := req.Context().Deadline(); ok {
+		if until := time.Until(deadline); remaining <= 0 || until < remaining {
+			remaining = until
+		}
+	}
+	if remaining <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// Copy the request so that the original is not modified
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	r.Header.Set(requestTimeoutHeader, remaining.Truncate(time.Millisecond).String())
+
+	return t.base.RoundTrip(r)
+}
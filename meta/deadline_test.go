@@ -0,0 +1,85 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestClient_propagateDeadline(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(requestTimeoutHeader)
+		fmt.Fprint(w, `{"data":{}}`)
+	}))
+	defer server.Close()
+
+	client := func(args ...string) *api.Client {
+		m := Meta{ClientToken: "foo", Ui: cli.NewMockUi()}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		c, err := m.Client()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return c
+	}
+
+	// The header is the time left of the client timeout
+	c := client("-propagate-deadline")
+	c.SetClientTimeout(30 * time.Second)
+	if _, err := c.Logical().Read("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	remaining, err := time.ParseDuration(header)
+	if err != nil {
+		t.Fatalf("bad header %q: %s", header, err)
+	}
+	if remaining > 30*time.Second || remaining < 29*time.Second {
+		t.Fatalf("bad: %s", remaining)
+	}
+
+	// A sooner deadline of the request is used instead
+	transport := &deadlineTransport{
+		client: &http.Client{Timeout: 30 * time.Second},
+		base:   http.DefaultTransport,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequest("GET", server.URL+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	remaining, err = time.ParseDuration(header)
+	if err != nil {
+		t.Fatalf("bad header %q: %s", header, err)
+	}
+	if remaining > 5*time.Second || remaining < 4*time.Second {
+		t.Fatalf("bad: %s", remaining)
+	}
+	if req.Header.Get(requestTimeoutHeader) != "" {
+		t.Fatalf("the original request was modified")
+	}
+
+	// Without the flag there is no header
+	header = ""
+	if _, err := client().Logical().Read("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if header != "" {
+		t.Fatalf("bad: %q", header)
+	}
+}
@@ -0,0 +1,307 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/version"
+	"github.com/mitchellh/cli"
+)
+
+// harRedacted replaces tokens recorded in the -har-file.
+const harRedacted = "REDACTED"
+
+// harRedactedHeaders are the headers whose values are redacted in the
+// -har-file.
+var harRedactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"X-Vault-Token":       true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"Proxy-Authorization": true,
+}
+
+// harRedactedFields are the fields of JSON request and response bodies whose
+// values are redacted in the -har-file. Token lookups return the token
+// itself as the "id".
+var harRedactedFields = map[string]bool{
+	"client_token":     true,
+	"token":            true,
+	"accessor":         true,
+	"wrapped_accessor": true,
+	"id":               true,
+}
+
+// harTransport is an http.RoundTripper that records each request and its
+// response as an entry of an HTTP Archive (HAR) written to the -har-file.
+// The file is rewritten after each request, so it holds the requests made
+// so far even if the command does not complete. Tokens are redacted. A
+// failure to write the file is reported as a warning.
+type harTransport struct {
+	path string
+	ui   cli.Ui
+	base http.RoundTripper
+
+	l   sync.Mutex
+	har harFile
+}
+
+func newHarTransport(path string, ui cli.Ui, base http.RoundTripper) *harTransport {
+	return &harTransport{
+		path: path,
+		ui:   ui,
+		base: base,
+		har: harFile{Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "vault", Version: version.GetVersion().VersionNumber()},
+			Entries: []harEntry{},
+		}},
+	}
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	var respBody []byte
+	if err == nil {
+		respBody, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	}
+	elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+	entry := harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            elapsed,
+		Request:         harNewRequest(req, reqBody),
+		Response:        harNewResponse(resp, respBody, err),
+		Cache:           struct{}{},
+		Timings:         harTimings{Send: 0, Wait: elapsed, Receive: 0},
+	}
+
+	if writeErr := t.record(entry); writeErr != nil && t.ui != nil {
+		t.ui.Error(fmt.Sprintf("Warning: error writing HAR file: %s", writeErr))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// record adds the entry to the archive and writes it out.
+func (t *harTransport) record(entry harEntry) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	t.har.Log.Entries = append(t.har.Log.Entries, entry)
+	contents, err := json.MarshalIndent(&t.har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.path, contents, 0600)
+}
+
+func harNewRequest(req *http.Request, body []byte) harRequest {
+	u := *req.URL
+	query := u.Query()
+	r := harRequest{
+		Method:      req.Method,
+		URL:         u.String(),
+		HTTPVersion: req.Proto,
+		Headers:     harHeaders(req.Header),
+		QueryString: []harNameValue{},
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if r.HTTPVersion == "" {
+		r.HTTPVersion = "HTTP/1.1"
+	}
+	for _, k := range sortedKeys(query) {
+		for _, v := range query[k] {
+			r.QueryString = append(r.QueryString, harNameValue{Name: k, Value: v})
+		}
+	}
+	if len(body) > 0 {
+		r.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     harRedactBody(body),
+		}
+	}
+	return r
+}
+
+func harNewResponse(resp *http.Response, body []byte, err error) harResponse {
+	r := harResponse{
+		HTTPVersion: "HTTP/1.1",
+		Headers:     []harNameValue{},
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if err != nil {
+		// The request failed without a response
+		r.StatusText = err.Error()
+		return r
+	}
+
+	r.Status = resp.StatusCode
+	r.StatusText = http.StatusText(resp.StatusCode)
+	r.HTTPVersion = resp.Proto
+	r.Headers = harHeaders(resp.Header)
+	r.Content = harContent{
+		Size:     len(body),
+		MimeType: resp.Header.Get("Content-Type"),
+		Text:     harRedactBody(body),
+	}
+	return r
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	result := []harNameValue{}
+	for _, k := range sortedKeys(header) {
+		for _, v := range header[k] {
+			if harRedactedHeaders[k] {
+				v = harRedacted
+			}
+			result = append(result, harNameValue{Name: k, Value: v})
+		}
+	}
+	return result
+}
+
+// harRedactBody returns the body with the values of the fields that hold
+// tokens redacted. Bodies that are not JSON are recorded as they are.
+func harRedactBody(body []byte) string {
+	var v interface{}
+	if err := jsonutil.DecodeJSON(body, &v); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(harRedactValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func harRedactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range v {
+			if harRedactedFields[strings.ToLower(k)] {
+				if s, ok := elem.(string); ok && s != "" {
+					v[k] = harRedacted
+					continue
+				}
+			}
+			v[k] = harRedactValue(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = harRedactValue(elem)
+		}
+	}
+	return v
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The types below are the subset of the HAR 1.2 format needed to record the
+// requests made to the server.
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
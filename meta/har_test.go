@@ -0,0 +1,119 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestClient_harFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"value":"bar"},"auth":{"client_token":"s.8d4a2c09","accessor":"47b21f8e"}}`)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.har")
+
+	ui := cli.NewMockUi()
+	m := Meta{ClientToken: "root-token", Ui: ui}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-har-file", path}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	secret, err := client.Logical().Write("auth/userpass/login/bob", map[string]interface{}{
+		"password": "hunter2",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	// The response is passed on intact
+	if secret.Auth.ClientToken != "s.8d4a2c09" {
+		t.Fatalf("bad: %#v", secret.Auth)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"root-token", "s.8d4a2c09", "47b21f8e"} {
+		if strings.Contains(string(contents), token) {
+			t.Fatalf("expected %q to be redacted:\n%s", token, contents)
+		}
+	}
+
+	var har harFile
+	if err := json.Unmarshal(contents, &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || har.Log.Creator.Name != "vault" || len(har.Log.Entries) != 1 {
+		t.Fatalf("bad: %#v", har.Log)
+	}
+
+	entry := har.Log.Entries[0]
+	if entry.StartedDateTime == "" {
+		t.Fatalf("bad: %#v", entry)
+	}
+	req := entry.Request
+	if req.Method != "PUT" || req.URL != server.URL+"/v1/auth/userpass/login/bob" {
+		t.Fatalf("bad: %#v", req)
+	}
+	var token string
+	for _, h := range req.Headers {
+		if h.Name == "X-Vault-Token" {
+			token = h.Value
+		}
+	}
+	if token != harRedacted {
+		t.Fatalf("bad token header: %q", token)
+	}
+	if req.PostData == nil || req.PostData.Text != `{"password":"hunter2"}` {
+		t.Fatalf("bad: %#v", req.PostData)
+	}
+
+	resp := entry.Response
+	if resp.Status != 200 || resp.Content.MimeType != "application/json" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	expected := `{"auth":{"accessor":"REDACTED","client_token":"REDACTED"},"data":{"value":"bar"}}`
+	if resp.Content.Text != expected {
+		t.Fatalf("bad: %s", resp.Content.Text)
+	}
+
+	// Further requests append entries
+	if _, err := client.Logical().Read("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	contents, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	har = harFile{}
+	if err := json.Unmarshal(contents, &har); err != nil {
+		t.Fatal(err)
+	}
+	if len(har.Log.Entries) != 2 || har.Log.Entries[1].Request.Method != "GET" {
+		t.Fatalf("bad: %#v", har.Log.Entries)
+	}
+	if ui.ErrorWriter.String() != "" {
+		t.Fatalf("unexpected warning: %s", ui.ErrorWriter.String())
+	}
+}
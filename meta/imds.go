@@ -0,0 +1,124 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-multierror"
+)
+
+// imdsAddressPrefix marks an -address that is looked up in the instance
+// metadata, such as "imds:vault_addr", "imds:aws:vault_addr" or
+// "imds:gcp:vault_addr".
+const imdsAddressPrefix = "imds:"
+
+// The instance metadata services; they can be overwritten for tests.
+var (
+	imdsAWSEndpoint = "http://169.254.169.254"
+	imdsGCPEndpoint = "http://metadata.google.internal"
+)
+
+// imdsTimeout is how long a metadata request may take. The services answer
+// quickly on instances and not at all elsewhere.
+const imdsTimeout = 2 * time.Second
+
+// maxIMDSValueSize is the largest metadata value that is read.
+const maxIMDSValueSize = 4096
+
+// resolveIMDSAddress looks up the Vault address in the instance metadata
+// given by spec, the part of the -address after "imds:". The spec is the name
+// of an AWS instance tag or GCP instance attribute, optionally prefixed by
+// "aws:" or "gcp:" to select the cloud; otherwise AWS is tried before GCP.
+func resolveIMDSAddress(spec string) (string, error) {
+	providers := []string{"aws", "gcp"}
+	name := spec
+	if i := strings.Index(spec, ":"); i != -1 {
+		providers, name = []string{spec[:i]}, spec[i+1:]
+	}
+	if name == "" {
+		return "", errors.New("missing the name of the instance metadata to look up")
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = imdsTimeout
+
+	var result error
+	for _, provider := range providers {
+		var addr string
+		var err error
+		switch provider {
+		case "aws":
+			addr, err = awsInstanceTag(client, name)
+		case "gcp":
+			addr, err = gcpInstanceAttribute(client, name)
+		default:
+			return "", fmt.Errorf("unknown instance metadata provider %q, expected aws or gcp", provider)
+		}
+		if err == nil {
+			return addr, nil
+		}
+		result = multierror.Append(result, fmt.Errorf("%s: %s", provider, err))
+	}
+	return "", result
+}
+
+// awsInstanceTag returns the value of the instance tag using IMDSv2, which
+// requires a session token.
+func awsInstanceTag(client *http.Client, name string) (string, error) {
+	req, err := http.NewRequest("PUT", imdsAWSEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := imdsGet(client, req)
+	if err != nil {
+		return "", fmt.Errorf("error getting a session token: %s", err)
+	}
+
+	req, err = http.NewRequest("GET",
+		imdsAWSEndpoint+"/latest/meta-data/tags/instance/"+url.PathEscape(name), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	return imdsGet(client, req)
+}
+
+// gcpInstanceAttribute returns the value of the custom instance attribute.
+func gcpInstanceAttribute(client *http.Client, name string) (string, error) {
+	req, err := http.NewRequest("GET",
+		imdsGCPEndpoint+"/computeMetadata/v1/instance/attributes/"+url.PathEscape(name), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return imdsGet(client, req)
+}
+
+// imdsGet makes the metadata request and returns the value it responds with.
+func imdsGet(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL)
+	}
+
+	value, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIMDSValueSize))
+	if err != nil {
+		return "", err
+	}
+	if v := strings.TrimSpace(string(value)); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("empty value from %s", req.URL)
+}
@@ -0,0 +1,89 @@
+package meta
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestClient_imdsAddress(t *testing.T) {
+	aws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+			if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				w.WriteHeader(400)
+				return
+			}
+			fmt.Fprint(w, "aws-session")
+		case r.Header.Get("X-aws-ec2-metadata-token") != "aws-session":
+			w.WriteHeader(401)
+		case r.URL.Path == "/latest/meta-data/tags/instance/vault_addr":
+			fmt.Fprint(w, "https://vault.aws.example.com:8200\n")
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer aws.Close()
+
+	gcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Metadata-Flavor") != "Google":
+			w.WriteHeader(403)
+		case r.URL.Path == "/computeMetadata/v1/instance/attributes/vault_addr",
+			r.URL.Path == "/computeMetadata/v1/instance/attributes/gcp_vault_addr":
+			fmt.Fprint(w, "https://vault.gcp.example.com:8200")
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer gcp.Close()
+
+	oldAWS, oldGCP := imdsAWSEndpoint, imdsGCPEndpoint
+	imdsAWSEndpoint, imdsGCPEndpoint = aws.URL, gcp.URL
+	defer func() { imdsAWSEndpoint, imdsGCPEndpoint = oldAWS, oldGCP }()
+
+	client := func(args ...string) (string, string) {
+		ui := cli.NewMockUi()
+		m := Meta{ClientToken: "foo", Ui: ui, statePath: "/nonexistent/state"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		c, err := m.Client()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return c.Address(), ui.ErrorWriter.String()
+	}
+	defaultAddr, _ := client()
+
+	cases := []struct {
+		Address  string
+		Expected string
+		Warning  bool
+	}{
+		{"imds:vault_addr", "https://vault.aws.example.com:8200", false},
+		{"imds:aws:vault_addr", "https://vault.aws.example.com:8200", false},
+		{"imds:gcp:vault_addr", "https://vault.gcp.example.com:8200", false},
+		// AWS does not have the tag, so GCP is tried
+		{"imds:gcp_vault_addr", "https://vault.gcp.example.com:8200", false},
+		// Failures fall back to the usual address
+		{"imds:missing", defaultAddr, true},
+		{"imds:azure:vault_addr", defaultAddr, true},
+		{"imds:", defaultAddr, true},
+	}
+
+	for _, tc := range cases {
+		addr, warning := client("-disable-srv-lookup", "-address", tc.Address)
+		if addr != tc.Expected {
+			t.Fatalf("%s: expected %q, got %q", tc.Address, tc.Expected, addr)
+		}
+		if strings.Contains(warning, "Warning: error looking up the address") != tc.Warning {
+			t.Fatalf("%s: bad warning: %q", tc.Address, warning)
+		}
+	}
+}
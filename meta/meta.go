@@ -2,12 +2,31 @@ package meta
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/command/token"
+	"github.com/hashicorp/vault/helper/flag-kv"
+	"github.com/hashicorp/vault/helper/flag-slice"
+	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/mitchellh/cli"
 )
 
@@ -17,12 +36,21 @@ type FlagSetFlags uint
 
 type TokenHelperFunc func() (token.TokenHelper, error)
 
+// AllowedAddressesFunc returns the glob patterns that the address of the
+// Vault server must match. An empty list allows any address.
+type AllowedAddressesFunc func() ([]string, error)
+
 const (
-	FlagSetNone    FlagSetFlags = 0
-	FlagSetServer  FlagSetFlags = 1 << iota
-	FlagSetDefault              = FlagSetServer
+	FlagSetNone   FlagSetFlags = 0
+	FlagSetServer FlagSetFlags = 1 << iota
+	FlagSetOutput
+	FlagSetDefault = FlagSetServer
 )
 
+// maxCACertSize is the largest CA certificate bundle that will be read from
+// -ca-cert-url.
+const maxCACertSize = 1024 * 1024
+
 var (
 	additionalOptionsUsage = func() string {
 		return `
@@ -48,16 +76,74 @@ type Meta struct {
 	ForceAddress string // Address to force for API clients
 
 	// These are set by the command line flags.
-	flagAddress    string
-	flagCACert     string
-	flagCAPath     string
-	flagClientCert string
-	flagClientKey  string
-	flagWrapTTL    string
-	flagInsecure   bool
+	flagAddress     string
+	flagCACert      string
+	flagCAPath      string
+	flagCACertURL   string
+	flagCACertPEM   string
+	flagClientCert  string
+	flagClientKey   string
+	flagPKCS12      string
+	flagPKCS12Pass  string
+	flagWrapTTL     string
+	flagInsecure    bool
+	flagRetryBudget int
+	flagRetryStatus []string
+	flagTimeout     *time.Duration
+	flagConnTimeout time.Duration
+	flagMaxRetries  *int
+	flagRetryMin    time.Duration
+	flagRetryMax    time.Duration
+	flagProxy       string
+	flagUnixSocket  string
+	flagNamespace   string
+	flagHeaders     map[string]string
+	flagUserAgent   string
+	flagTLSMinVer   string
+	flagTLSCiphers  []string
+	flagSNIFromAddr bool
+	flagFailStandby bool
+	flagStandbyOK   bool
+	flagAllowAny    bool
+	flagRequestHook string
+	flagHookTimeout time.Duration
+	flagDisableSRV  bool
+	flagPrewarmTLS  bool
+	flagShowIdent   bool
+	flagOtelURL     string
+	flagRemember    bool
+	flagNoRemember  bool
+	flagIdleTimeout time.Duration
+	flagRenegotiate string
+	flagHARFile     string
+	flagWarnTTL     time.Duration
+	flagWarnRoot    bool
+	flagDeadline    bool
+	flagAuth        string
+	flagAuthConfig  map[string]string
+	flagAuthCache   bool
+	flagOutput      OutputOptions
 
 	// Queried if no token can be found
 	TokenHelper TokenHelperFunc
+
+	// Queried to restrict the addresses that may be connected to
+	AllowedAddresses AllowedAddressesFunc
+
+	// The login methods of -auth, which are used if no token can be found
+	AutoLogins map[string]AutoLoginFunc
+
+	// caCertPEM caches the CA certificate fetched from -ca-cert-url so it
+	// is only fetched once per invocation.
+	caCertPEM []byte
+
+	// caCertURLClient is used to fetch the -ca-cert-url certificate; it can
+	// be overwritten for tests.
+	caCertURLClient *http.Client
+
+	// statePath is the path of the file -remember keeps its settings in; it
+	// can be overwritten for tests.
+	statePath string
 }
 
 func (m *Meta) DefaultWrappingLookupFunc(operation, path string) string {
@@ -71,39 +157,68 @@ func (m *Meta) DefaultWrappingLookupFunc(operation, path string) string {
 // Client returns the API client to a Vault server given the configured
 // flag settings for this command.
 func (m *Meta) Client() (*api.Client, error) {
-	config := api.DefaultConfig()
+	config, settings, err := m.clientConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	err := config.ReadEnvironment()
+	// Build the client
+	client, err := api.NewClient(config)
 	if err != nil {
-		return nil, errwrap.Wrapf("error reading environment: {{err}}", err)
+		return nil, err
 	}
 
-	if m.flagAddress != "" {
-		config.Address = m.flagAddress
+	client.SetWrappingLookupFunc(m.DefaultWrappingLookupFunc)
+
+	if err := addHeaders(client, m.flagHeaders); err != nil {
+		return nil, err
 	}
-	if m.ForceAddress != "" {
-		config.Address = m.ForceAddress
+
+	// This replaces the Go HTTP client's default
+	userAgent := m.flagUserAgent
+	if userAgent == "" {
+		userAgent = os.Getenv(api.EnvVaultUserAgent)
 	}
-	// If we need custom TLS configuration, then set it
-	if m.flagCACert != "" || m.flagCAPath != "" || m.flagClientCert != "" || m.flagClientKey != "" || m.flagInsecure {
-		t := &api.TLSConfig{
-			CACert:        m.flagCACert,
-			CAPath:        m.flagCAPath,
-			ClientCert:    m.flagClientCert,
-			ClientKey:     m.flagClientKey,
-			TLSServerName: "",
-			Insecure:      m.flagInsecure,
+	if userAgent != "" {
+		client.AddHeader("User-Agent", userAgent)
+	}
+
+	if m.flagRequestHook != "" {
+		config.HttpClient.Transport = &requestHookTransport{
+			program: m.flagRequestHook,
+			timeout: m.flagHookTimeout,
+			base:    config.HttpClient.Transport,
 		}
-		config.ConfigureTLS(t)
 	}
 
-	// Build the client
-	client, err := api.NewClient(config)
-	if err != nil {
-		return nil, err
+	if m.flagRemember {
+		config.HttpClient.Transport = &rememberTransport{
+			state: settings,
+			write: m.writeRemembered,
+			ui:    m.Ui,
+			base:  config.HttpClient.Transport,
+		}
 	}
 
-	client.SetWrappingLookupFunc(m.DefaultWrappingLookupFunc)
+	if m.flagHARFile != "" {
+		config.HttpClient.Transport = newHarTransport(
+			m.flagHARFile, m.Ui, config.HttpClient.Transport)
+	}
+
+	if m.flagOtelURL != "" {
+		config.HttpClient.Transport, err = newOtelTransport(
+			m.flagOtelURL, m.Ui, config.HttpClient.Transport)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m.flagDeadline {
+		config.HttpClient.Transport = &deadlineTransport{
+			client: config.HttpClient,
+			base:   config.HttpClient.Transport,
+		}
+	}
 
 	// If we have a token directly, then set that
 	token := m.ClientToken
@@ -128,14 +243,701 @@ func (m *Meta) Client() (*api.Client, error) {
 		}
 	}
 
+	// Set the namespace, before any -auth login so that it logs in to the
+	// namespace too
+	if settings.Namespace != "" {
+		client.SetNamespace(settings.Namespace)
+	}
+
+	// If we still don't have a token, log in with -auth
+	if token == "" && m.flagAuth != "" {
+		token, err = m.autoLogin(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Set the token
 	if token != "" {
 		client.SetToken(token)
 	}
 
+	if m.flagPrewarmTLS {
+		prewarm(client)
+	}
+
+	if m.flagShowIdent || m.flagWarnTTL > 0 || m.flagWarnRoot {
+		if err := m.checkToken(client); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.flagFailStandby && !m.flagStandbyOK {
+		if err := checkActive(client); err != nil {
+			return nil, err
+		}
+	}
+
 	return client, nil
 }
 
+// checkActive returns an error unless the server is the active node, with
+// -fail-on-redirect-to-standby. Standbys normally forward or redirect
+// requests to the active node, but performance standbys serve reads
+// themselves, which may be stale.
+func checkActive(client *api.Client) error {
+	r := client.NewRequest("GET", "/v1/sys/health")
+	// Answer with a success for any state, so that the body can be read
+	for _, param := range []string{"standbycode", "perfstandbycode", "sealedcode", "uninitcode"} {
+		r.Params.Add(param, "299")
+	}
+	resp, err := client.RawRequest(r)
+	if err != nil {
+		return errwrap.Wrapf("error checking that the server is the active node: {{err}}", err)
+	}
+	defer resp.Body.Close()
+
+	var health api.HealthResponse
+	if err := resp.DecodeJSON(&health); err != nil {
+		return errwrap.Wrapf("error checking that the server is the active node: {{err}}", err)
+	}
+	switch {
+	case health.PerfStandby:
+		return fmt.Errorf("the server at %s is a performance standby, not the active node", client.Address())
+	case health.Standby:
+		return fmt.Errorf("the server at %s is a standby, not the active node", client.Address())
+	}
+	return nil
+}
+
+// Address returns the address of the Vault server given the configured flag
+// settings and environment, without creating a client.
+func (m *Meta) Address() (string, error) {
+	config, _, err := m.clientConfig()
+	if err != nil {
+		return "", err
+	}
+	return config.Address, nil
+}
+
+// Clients returns a client for each of the given addresses, which are used
+// in place of -address. The clients are otherwise configured like the one
+// returned by Client.
+func (m *Meta) Clients(addresses []string) ([]*api.Client, error) {
+	orig := m.flagAddress
+	defer func() { m.flagAddress = orig }()
+
+	clients := make([]*api.Client, 0, len(addresses))
+	for _, addr := range addresses {
+		m.flagAddress = addr
+		client, err := m.Client()
+		if err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("%s: {{err}}", addr), err)
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// checkToken looks up the token of the client once for -show-identity,
+// -warn-token-ttl and -warn-root-token. Only -show-identity fails if the
+// lookup does; the warnings are skipped with a note instead.
+func (m *Meta) checkToken(client *api.Client) error {
+	secret, err := client.Auth().Token().LookupSelf()
+	if err == nil && (secret == nil || secret.Data == nil) {
+		err = fmt.Errorf("empty response")
+	}
+	if err != nil {
+		if m.flagShowIdent {
+			return errwrap.Wrapf("error looking up token: {{err}}", err)
+		}
+		if m.Ui != nil {
+			m.Ui.Error(fmt.Sprintf(
+				"Warning: error looking up token, not checking its TTL: %s", err))
+		}
+		return nil
+	}
+
+	if m.flagShowIdent {
+		m.showIdentity(secret)
+	}
+	m.warnToken(secret)
+	return nil
+}
+
+// warnToken prints a warning on stderr if the token expires sooner than
+// -warn-token-ttl, or with -warn-root-token if it is a root token or does
+// not expire.
+func (m *Meta) warnToken(secret *api.Secret) {
+	if m.Ui == nil || (m.flagWarnTTL <= 0 && !m.flagWarnRoot) {
+		return
+	}
+
+	ttl, err := parseutil.ParseDurationSecond(secret.Data["ttl"])
+	if err != nil {
+		m.Ui.Error(fmt.Sprintf("Warning: error parsing token TTL: %s", err))
+		return
+	}
+
+	if m.flagWarnRoot {
+		policies, _ := secret.Data["policies"].([]interface{})
+		for _, p := range policies {
+			if p == "root" {
+				m.Ui.Error("Warning: the token in use is a root token")
+				return
+			}
+		}
+		if ttl == 0 {
+			m.Ui.Error("Warning: the token in use does not expire")
+			return
+		}
+	}
+
+	if ttl > 0 && ttl < m.flagWarnTTL {
+		m.Ui.Error(fmt.Sprintf(
+			"Warning: the token in use expires in %s, less than the %s given by -warn-token-ttl",
+			ttl, m.flagWarnTTL))
+	}
+}
+
+// showIdentity prints a summary of the identity the looked up token belongs
+// to on stderr.
+func (m *Meta) showIdentity(secret *api.Secret) {
+	entityID, _ := secret.Data["entity_id"].(string)
+	if entityID == "" {
+		entityID = "n/a"
+	}
+	if m.Ui != nil {
+		m.Ui.Error(fmt.Sprintf("Acting as display_name=%v policies=%v entity_id=%s",
+			secret.Data["display_name"], secret.Data["policies"], entityID))
+	}
+}
+
+// prewarm makes a throwaway request to the server so that the connection,
+// including the TLS handshake, is established and pooled before the command
+// makes its own requests. Any failure is ignored, as the requests of the
+// command will report it.
+func prewarm(client *api.Client) {
+	r := client.NewRequest("HEAD", "/v1/sys/health")
+	r.Params.Add("standbyok", "true")
+	r.Params.Add("sealedcode", "299")
+	r.Params.Add("uninitcode", "299")
+	resp, _ := client.RawRequest(r)
+	if resp != nil {
+		// The body must be drained for the connection to be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// clientConfig returns the configuration of the API client given the
+// configured flag settings for this command, along with the address and
+// namespace to use, as they are remembered by -remember. The address is the
+// one the user gave, such as a unix:// address, rather than the one the
+// configuration is left with.
+func (m *Meta) clientConfig() (*api.Config, *rememberedState, error) {
+	config := api.DefaultConfig()
+
+	err := config.ReadEnvironment()
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("error reading environment: {{err}}", err)
+	}
+
+	address := m.flagAddress
+	if strings.HasPrefix(address, imdsAddressPrefix) {
+		resolved, err := resolveIMDSAddress(strings.TrimPrefix(address, imdsAddressPrefix))
+		if err != nil {
+			if m.Ui != nil {
+				m.Ui.Error(fmt.Sprintf(
+					"Warning: error looking up the address in the instance metadata, "+
+						"ignoring -address: %s", err))
+			}
+			resolved = ""
+		}
+		address = resolved
+	}
+
+	// The namespace is remembered along with the address, and only used
+	// with the remembered address
+	settings := &rememberedState{Namespace: m.flagNamespace}
+	if settings.Namespace == "" {
+		settings.Namespace = os.Getenv(api.EnvVaultNamespace)
+	}
+
+	addressSet := address != "" || os.Getenv(api.EnvVaultAddress) != ""
+	if address != "" {
+		config.Address = address
+	} else if os.Getenv(api.EnvVaultAddress) == "" && m.ForceAddress == "" && !m.flagNoRemember {
+		state, err := m.readRemembered()
+		if err != nil {
+			return nil, nil, err
+		}
+		if state.Address != "" {
+			config.Address = state.Address
+			addressSet = true
+			if settings.Namespace == "" {
+				settings.Namespace = state.Namespace
+			}
+		}
+	}
+	if m.ForceAddress != "" {
+		config.Address = m.ForceAddress
+		addressSet = true
+	}
+	if err := m.checkAllowedAddress(config.Address); err != nil {
+		return nil, nil, err
+	}
+	settings.Address = config.Address
+
+	// -unix-socket takes precedence over a unix:// address. Either way the
+	// address only sets the URL of the requests, which defaults to
+	// http://localhost for a socket, and the connections are made to the
+	// socket instead.
+	socket, addr, err := unixSocketAddress(config.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.flagUnixSocket != "" {
+		socket = m.flagUnixSocket
+		if !addressSet {
+			addr = "http://localhost"
+		}
+	}
+	config.Address = addr
+	if socket != "" {
+		config.DisableSRVLookup = true
+
+		// The socket is remembered rather than the address of the requests,
+		// so that later commands connect to it too
+		if abs, err := filepath.Abs(socket); err == nil {
+			socket = abs
+		}
+		settings.Address = "unix://" + socket
+	}
+
+	// The inline CA certificate from the environment gives way to one given
+	// by a flag, but the flags are exclusive
+	caCertPEM := m.flagCACertPEM
+	if caCertPEM != "" && (m.flagCACert != "" || m.flagCAPath != "" || m.flagCACertURL != "") {
+		return nil, nil, fmt.Errorf("-ca-cert-pem cannot be used with -ca-cert, -ca-path or -ca-cert-url")
+	}
+	if caCertPEM == "" && m.flagCACert == "" && m.flagCAPath == "" && m.flagCACertURL == "" {
+		caCertPEM = os.Getenv(api.EnvVaultCACertPEM)
+	}
+
+	pkcs12Path := m.flagPKCS12
+	if pkcs12Path != "" && (m.flagClientCert != "" || m.flagClientKey != "") {
+		return nil, nil, fmt.Errorf("-client-pkcs12 cannot be used with -client-cert or -client-key")
+	}
+	if pkcs12Path == "" && m.flagClientCert == "" && m.flagClientKey == "" {
+		pkcs12Path = os.Getenv(api.EnvVaultClientPKCS12)
+	}
+
+	// If we need custom TLS configuration, then set it
+	if m.flagCACert != "" || m.flagCAPath != "" || m.flagCACertURL != "" || caCertPEM != "" || m.flagClientCert != "" || m.flagClientKey != "" || m.flagInsecure {
+		t := &api.TLSConfig{
+			CACert:        m.flagCACert,
+			CAPath:        m.flagCAPath,
+			ClientCert:    m.flagClientCert,
+			ClientKey:     m.flagClientKey,
+			TLSServerName: "",
+			Insecure:      m.flagInsecure,
+		}
+		if m.flagCACertURL != "" {
+			caCert, err := m.caCertFromURL()
+			if err != nil {
+				return nil, nil, err
+			}
+			t.CACertBytes = caCert
+		}
+		if caCertPEM != "" {
+			if !x509.NewCertPool().AppendCertsFromPEM([]byte(caCertPEM)) {
+				return nil, nil, fmt.Errorf(
+					"the inline CA certificate from -ca-cert-pem or %s does not contain "+
+						"any valid PEM encoded certificates", api.EnvVaultCACertPEM)
+			}
+			t.CACertBytes = []byte(caCertPEM)
+		}
+		if err := config.ConfigureTLS(t); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Like VAULT_MAX_RETRIES, which this overrides, the limit is of retries
+	// while the API client counts attempts
+	if m.flagMaxRetries != nil {
+		config.MaxRetries = *m.flagMaxRetries + 1
+	}
+	if m.flagRetryMax > 0 && m.flagRetryMin <= 0 {
+		return nil, nil, fmt.Errorf("-retry-wait-max requires -retry-wait-min")
+	}
+	if m.flagRetryMax > 0 && m.flagRetryMax < m.flagRetryMin {
+		return nil, nil, fmt.Errorf("-retry-wait-max must not be less than -retry-wait-min")
+	}
+	config.RetryWaitMin = m.flagRetryMin
+	config.RetryWaitMax = m.flagRetryMax
+
+	if m.flagRetryBudget > 0 {
+		config.RetryBudget = api.NewRetryBudget(m.flagRetryBudget)
+	}
+	for _, s := range m.flagRetryStatus {
+		code, err := strconv.Atoi(s)
+		if err != nil || code < 100 || code > 599 {
+			return nil, nil, fmt.Errorf("invalid -retry-on-status %q: must be an HTTP status code", s)
+		}
+		config.RetryStatuses = append(config.RetryStatuses, code)
+	}
+	config.DisableSRVLookup = config.DisableSRVLookup || m.flagDisableSRV
+
+	// The CLI never retries writes, which may have been applied before
+	// failing
+	config.RetryIdempotentOnly = true
+
+	// This overrides VAULT_CLIENT_TIMEOUT, and zero disables the timeout
+	if m.flagTimeout != nil {
+		config.Timeout = *m.flagTimeout
+		config.HttpClient.Timeout = *m.flagTimeout
+	}
+
+	if t, ok := config.HttpClient.Transport.(*http.Transport); ok {
+		t.IdleConnTimeout = m.flagIdleTimeout
+
+		proxy := m.flagProxy
+		if proxy == "" {
+			proxy = os.Getenv(api.EnvVaultProxyAddr)
+		}
+		if proxy != "" && socket != "" {
+			return nil, nil, fmt.Errorf("a proxy cannot be used to connect over a Unix socket")
+		}
+		if proxy != "" {
+			proxyURL, err := parseProxyURL(proxy)
+			if err != nil {
+				return nil, nil, err
+			}
+			t.Proxy = http.ProxyURL(proxyURL)
+		}
+
+		if m.flagConnTimeout < 0 {
+			return nil, nil, fmt.Errorf("-connect-timeout must not be negative")
+		}
+		if m.flagConnTimeout > 0 {
+			// This only bounds establishing the connection, the TCP
+			// connection and the TLS handshake, leaving the rest of the
+			// request to -client-timeout
+			dialer := &net.Dialer{
+				Timeout:   m.flagConnTimeout,
+				KeepAlive: 30 * time.Second,
+			}
+			t.DialContext = dialer.DialContext
+			t.TLSHandshakeTimeout = m.flagConnTimeout
+		}
+
+		if socket != "" {
+			// The environment's proxy settings would otherwise apply to
+			// the address, which is not where the connections are made
+			t.Proxy = nil
+			t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: m.flagConnTimeout}
+				return d.DialContext(ctx, "unix", socket)
+			}
+		}
+
+		if m.flagRenegotiate != "" {
+			renegotiation, ok := tlsRenegotiation[m.flagRenegotiate]
+			if !ok {
+				return nil, nil, fmt.Errorf(
+					"invalid TLS renegotiation %q, expected never, once, or freely", m.flagRenegotiate)
+			}
+			if t.TLSClientConfig == nil {
+				t.TLSClientConfig = &tls.Config{}
+			}
+			t.TLSClientConfig.Renegotiation = renegotiation
+		}
+
+		// This is applied after ConfigureTLS, which leaves the version alone
+		minVersion := m.flagTLSMinVer
+		if minVersion == "" {
+			minVersion = os.Getenv(api.EnvVaultTLSMinVersion)
+		}
+		if minVersion == "" {
+			minVersion = "tls12"
+		}
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf(
+				"invalid TLS version %q, expected tls10, tls11, tls12, or tls13", minVersion)
+		}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.MinVersion = version
+
+		if pkcs12Path != "" {
+			password := m.flagPKCS12Pass
+			if password == "" {
+				password = os.Getenv(api.EnvVaultClientPKCS12Password)
+			}
+			cert, err := loadPKCS12(pkcs12Path, password)
+			if err != nil {
+				return nil, nil, err
+			}
+			t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		// The certificate loaded by ConfigureTLS is replaced by one that is
+		// reloaded on SIGHUP
+		certFile, keyFile := m.flagClientCert, m.flagClientKey
+		if certFile == "" && keyFile == "" {
+			certFile, keyFile = os.Getenv(api.EnvVaultClientCert), os.Getenv(api.EnvVaultClientKey)
+		}
+		if pkcs12Path == "" && certFile != "" && keyFile != "" {
+			cg, err := reloadingClientCert(certFile, keyFile, m.Ui)
+			if err != nil {
+				return nil, nil, err
+			}
+			t.TLSClientConfig.Certificates = nil
+			t.TLSClientConfig.GetClientCertificate = cg.GetClientCertificate
+		}
+
+		// A server name from VAULT_TLS_SERVER_NAME takes precedence
+		if m.flagSNIFromAddr && t.TLSClientConfig.ServerName == "" {
+			serverName, err := serverNameFromAddress(config.Address)
+			if err != nil {
+				return nil, nil, err
+			}
+			t.TLSClientConfig.ServerName = serverName
+		}
+
+		ciphers := m.flagTLSCiphers
+		if len(ciphers) == 0 {
+			if v := os.Getenv(api.EnvVaultTLSCiphers); v != "" {
+				ciphers = strings.Split(v, ",")
+			}
+		}
+		if len(ciphers) > 0 {
+			suites, err := parseCipherSuites(ciphers)
+			if err != nil {
+				return nil, nil, err
+			}
+			t.TLSClientConfig.CipherSuites = suites
+		}
+
+		// Connections are not kept alive by default, so a prewarmed
+		// connection could not be reused
+		if m.flagPrewarmTLS {
+			t.DisableKeepAlives = false
+			t.MaxIdleConnsPerHost = 1
+		}
+	}
+
+	return config, settings, nil
+}
+
+// parseProxyURL parses the address of the proxy given by -proxy, which must
+// be an http, https, or socks5 URL.
+func parseProxyURL(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %q: %s", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf(
+			"invalid proxy address %q: expected an http://, https://, or socks5:// URL", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address %q: missing host", proxy)
+	}
+	return u, nil
+}
+
+// serverNameFromAddress returns the host of the address of the Vault server
+// as the TLS server name to verify its certificate against, for
+// -tls-server-name-from-addr. The address may leave out the scheme, and the
+// port and the brackets of an IPv6 address are removed.
+func serverNameFromAddress(address string) (string, error) {
+	addr := address
+	if !strings.Contains(addr, "://") {
+		addr = "https://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", fmt.Errorf("cannot take the TLS server name from the address %q: %s", address, err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("cannot take the TLS server name from the address %q: missing host", address)
+	}
+	return u.Hostname(), nil
+}
+
+// unixSocketAddress returns the path of the socket of a unix:// address,
+// such as unix:///run/vault/agent.sock, and http://localhost as the address
+// to use in its place. Any other address is returned unchanged with an empty
+// path.
+func unixSocketAddress(address string) (string, string, error) {
+	if !strings.HasPrefix(address, "unix://") {
+		return "", address, nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Unix socket address %q: %s", address, err)
+	}
+	if u.Host != "" || u.Path == "" {
+		return "", "", fmt.Errorf(
+			"invalid Unix socket address %q: expected an absolute path such as "+
+				"unix:///run/vault/agent.sock", address)
+	}
+	return u.Path, "http://localhost", nil
+}
+
+// reservedHeaders are the headers that -header may not set, and the flags
+// that set them instead.
+var reservedHeaders = map[string]string{
+	"X-Vault-Token":     "VAULT_TOKEN",
+	"X-Vault-Wrap-Ttl":  "-wrap-ttl",
+	"X-Vault-Namespace": "-namespace",
+	"User-Agent":        "-user-agent",
+}
+
+// addHeaders adds the headers given by -header to the requests of the
+// client.
+func addHeaders(client *api.Client, headers map[string]string) error {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := http.CanonicalHeaderKey(strings.TrimSpace(k))
+		if key == "" {
+			return fmt.Errorf("invalid -header %q: missing the name of the header", k+"="+headers[k])
+		}
+		if use, ok := reservedHeaders[key]; ok {
+			return fmt.Errorf("the %s header cannot be set with -header, use %s instead", key, use)
+		}
+		client.AddHeader(key, headers[k])
+	}
+	return nil
+}
+
+// tlsVersions maps the values of -tls-min-version to TLS versions.
+var tlsVersions = map[string]uint16{
+	"tls10": tls.VersionTLS10,
+	"tls11": tls.VersionTLS11,
+	"tls12": tls.VersionTLS12,
+	"tls13": tls.VersionTLS13,
+}
+
+// parseCipherSuites returns the IDs of the TLS cipher suites with the given
+// IANA names, such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Only the suites
+// of TLS 1.2 and earlier can be chosen, as those of TLS 1.3 are fixed.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	var valid []string
+	for _, suite := range tls.CipherSuites() {
+		for _, version := range suite.SupportedVersions {
+			if version < tls.VersionTLS13 {
+				suites[suite.Name] = suite.ID
+				valid = append(valid, suite.Name)
+				break
+			}
+		}
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown TLS cipher suite %q, expected one of: %s", name, strings.Join(valid, ", "))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// tlsRenegotiation maps the values of -tls-renegotiation to the renegotiation
+// support of the TLS client.
+var tlsRenegotiation = map[string]tls.RenegotiationSupport{
+	"never":  tls.RenegotiateNever,
+	"once":   tls.RenegotiateOnceAsClient,
+	"freely": tls.RenegotiateFreelyAsClient,
+}
+
+// checkAllowedAddress returns an error if the address does not match any of
+// the allowed address patterns, unless -allow-any-address was given.
+func (m *Meta) checkAllowedAddress(addr string) error {
+	if m.flagAllowAny || m.AllowedAddresses == nil {
+		return nil
+	}
+
+	patterns, err := m.AllowedAddresses()
+	if err != nil {
+		return errwrap.Wrapf("error loading allowed addresses: {{err}}", err)
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, addr)
+		if err != nil {
+			return fmt.Errorf("invalid allowed address pattern %q: %s", pattern, err)
+		}
+		if matched {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(
+		"address %q does not match any of the allowed addresses in the CLI "+
+			"configuration; use -allow-any-address to connect anyway", addr)
+}
+
+// caCertFromURL fetches the PEM-encoded CA certificate served at the
+// -ca-cert-url address. The address must use HTTPS and is verified against
+// the system's trusted certificates.
+func (m *Meta) caCertFromURL() ([]byte, error) {
+	if m.caCertPEM != nil {
+		return m.caCertPEM, nil
+	}
+
+	u, err := url.Parse(m.flagCACertURL)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing CA certificate URL: {{err}}", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("CA certificate URL must use https, got %q", m.flagCACertURL)
+	}
+
+	client := m.caCertURLClient
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+		client.Timeout = 30 * time.Second
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, errwrap.Wrapf("error fetching CA certificate: {{err}}", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching CA certificate: unexpected status %d from %s", resp.StatusCode, u)
+	}
+
+	caCert, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCACertSize))
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading CA certificate: {{err}}", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no valid PEM certificates found at %s", u)
+	}
+
+	m.caCertPEM = caCert
+	return caCert, nil
+}
+
 // FlagSet returns a FlagSet with the common flags that every
 // command implements. The exact behavior of FlagSet can be configured
 // using the flags as the second parameter, for example to disable
@@ -149,11 +951,60 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 		f.StringVar(&m.flagAddress, "address", "", "")
 		f.StringVar(&m.flagCACert, "ca-cert", "", "")
 		f.StringVar(&m.flagCAPath, "ca-path", "", "")
+		f.StringVar(&m.flagCACertURL, "ca-cert-url", "", "")
+		f.StringVar(&m.flagCACertPEM, "ca-cert-pem", "", "")
 		f.StringVar(&m.flagClientCert, "client-cert", "", "")
 		f.StringVar(&m.flagClientKey, "client-key", "", "")
+		f.StringVar(&m.flagPKCS12, "client-pkcs12", "", "")
+		f.StringVar(&m.flagPKCS12Pass, "client-pkcs12-password", "", "")
 		f.StringVar(&m.flagWrapTTL, "wrap-ttl", "", "")
 		f.BoolVar(&m.flagInsecure, "insecure", false, "")
 		f.BoolVar(&m.flagInsecure, "tls-skip-verify", false, "")
+		f.Var(intPtrValue{&m.flagMaxRetries}, "max-retries", "")
+		f.DurationVar(&m.flagRetryMin, "retry-wait-min", 0, "")
+		f.DurationVar(&m.flagRetryMax, "retry-wait-max", 0, "")
+		f.IntVar(&m.flagRetryBudget, "retry-budget", 0, "")
+		f.Var((*sliceflag.CommaStringFlag)(&m.flagRetryStatus), "retry-on-status", "")
+		f.Var(durationPtrValue{&m.flagTimeout}, "client-timeout", "")
+		f.DurationVar(&m.flagConnTimeout, "connect-timeout", 0, "")
+		f.BoolVar(&m.flagAllowAny, "allow-any-address", false, "")
+		f.StringVar(&m.flagRequestHook, "request-hook", "", "")
+		f.DurationVar(&m.flagHookTimeout, "request-hook-timeout", 10*time.Second, "")
+		f.BoolVar(&m.flagDisableSRV, "disable-srv-lookup", false, "")
+		f.BoolVar(&m.flagPrewarmTLS, "prewarm-tls", false, "")
+		f.BoolVar(&m.flagShowIdent, "show-identity", false, "")
+		f.StringVar(&m.flagOtelURL, "otel-endpoint", "", "")
+		f.BoolVar(&m.flagRemember, "remember", false, "")
+		f.BoolVar(&m.flagNoRemember, "no-remember", false, "")
+		f.DurationVar(&m.flagIdleTimeout, "idle-conn-timeout", 90*time.Second, "")
+		f.StringVar(&m.flagRenegotiate, "tls-renegotiation", "never", "")
+		f.StringVar(&m.flagTLSMinVer, "tls-min-version", "", "")
+		f.Var((*sliceflag.CommaStringFlag)(&m.flagTLSCiphers), "tls-ciphers", "")
+		f.BoolVar(&m.flagSNIFromAddr, "tls-server-name-from-addr", false, "")
+		f.StringVar(&m.flagProxy, "proxy", "", "")
+		f.StringVar(&m.flagUnixSocket, "unix-socket", "", "")
+		f.StringVar(&m.flagNamespace, "namespace", "", "")
+		f.Var((*kvFlag.Flag)(&m.flagHeaders), "header", "")
+		f.StringVar(&m.flagUserAgent, "user-agent", "", "")
+		f.StringVar(&m.flagHARFile, "har-file", "", "")
+		f.DurationVar(&m.flagWarnTTL, "warn-token-ttl", 0, "")
+		f.BoolVar(&m.flagWarnRoot, "warn-root-token", false, "")
+		f.BoolVar(&m.flagDeadline, "propagate-deadline", false, "")
+		f.BoolVar(&m.flagFailStandby, "fail-on-redirect-to-standby", false, "")
+		f.BoolVar(&m.flagStandbyOK, "allow-standby", false, "")
+		f.StringVar(&m.flagAuth, "auth", "", "")
+		f.Var((*kvFlag.Flag)(&m.flagAuthConfig), "auth-config", "")
+		f.BoolVar(&m.flagAuthCache, "auth-cache", false, "")
+	}
+
+	// -no-color is accepted by every command, so that colored output can
+	// always be turned off
+	f.BoolVar(&m.flagOutput.NoColor, "no-color", false, "")
+
+	// FlagSetOutput tells us to enable the settings that control how
+	// the output of the command is rendered.
+	if fs&FlagSetOutput != 0 {
+		m.flagOutput.flags(f)
 	}
 
 	// Create an io.Writer that writes to our Ui properly for errors.
@@ -178,6 +1029,18 @@ func GeneralOptionsUsage() string {
 	general := `
   -address=addr           The address of the Vault server.
                           Overrides the VAULT_ADDR environment variable if set.
+                          An address of "imds:name" is looked up in the
+                          instance metadata: the AWS instance tag or else the
+                          GCP instance attribute with the given name. Use
+                          "imds:aws:name" or "imds:gcp:name" to only look in
+                          one. If the lookup fails, a warning is printed and
+                          the address is resolved as if -address was not set.
+                          An address of "unix:///path" connects to the Unix
+                          socket at the path, like -unix-socket.
+
+  -allow-any-address      Connect to the Vault server even if its address does
+                          not match any of the "allowed_addresses" patterns in
+                          the CLI configuration file.
 
   -ca-cert=path           Path to a PEM encoded CA cert file to use to
                           verify the Vault server SSL certificate.
@@ -188,19 +1051,246 @@ func GeneralOptionsUsage() string {
                           -ca-cert and -ca-path are specified, -ca-cert is used.
                           Overrides the VAULT_CAPATH environment variable if set.
 
+  -ca-cert-url=url        HTTPS URL serving a PEM encoded CA cert to use to
+                          verify the Vault server SSL certificate. The URL
+                          itself is verified using the system's trusted CAs.
+                          Takes precedence over -ca-cert and -ca-path.
+
+  -ca-cert-pem=pem        The PEM encoded CA cert itself, rather than a path,
+                          for environments where it is given as a string.
+                          Cannot be used with -ca-cert, -ca-path, or
+                          -ca-cert-url. Overrides the VAULT_CACERT_PEM
+                          environment variable if set.
+
   -client-cert=path       Path to a PEM encoded client certificate for TLS
                           authentication to the Vault server. Must also specify
                           -client-key. Overrides the VAULT_CLIENT_CERT
-                          environment variable if set.
+                          environment variable if set. The certificate and
+                          key are reloaded from disk when the process
+                          receives SIGHUP.
 
   -client-key=path        Path to an unencrypted PEM encoded private key
                           matching the client certificate from -client-cert.
                           Overrides the VAULT_CLIENT_KEY environment variable
                           if set.
 
+  -client-pkcs12=path     Path to a PKCS#12 bundle, such as a .p12 or .pfx
+                          file, holding the client certificate and its
+                          private key, in place of -client-cert and
+                          -client-key. Overrides the VAULT_CLIENT_PKCS12
+                          environment variable if set.
+
+  -client-pkcs12-password=password
+                          The password of the -client-pkcs12 bundle.
+                          Overrides the VAULT_CLIENT_PKCS12_PASSWORD
+                          environment variable if set.
+
+  -header=key=value       Send this header with every request, such as
+                          -header=X-Request-Id=1234, for tracing. May be
+                          given more than once. X-Vault-Token,
+                          X-Vault-Wrap-TTL, X-Vault-Namespace and User-Agent
+                          cannot be set this way.
+
+  -user-agent=string      The User-Agent header to send with every request,
+                          to identify the caller. Overrides the
+                          VAULT_USER_AGENT environment variable if set.
+
+  -namespace=ns           The namespace to make requests in, sent in the
+                          X-Vault-Namespace header. Overrides the
+                          VAULT_NAMESPACE environment variable if set.
+
   -tls-skip-verify        Do not verify TLS certificate. This is highly
                           not recommended. Verification will also be skipped
                           if VAULT_SKIP_VERIFY is set.
+
+  -max-retries=n          Retry requests that fail with a 5xx error or a
+                          connection error up to n times. Only reads, which
+                          can safely be repeated, are retried; writes are
+                          never retried, so that a write that reached the
+                          server before failing is not applied twice.
+                          Overrides the VAULT_MAX_RETRIES environment
+                          variable if set. By default requests are not
+                          retried.
+
+  -retry-wait-min=dur     Wait this long before the first retry of a request,
+                          such as "500ms", doubling the wait for each retry
+                          after it. By default the wait grows by about a
+                          second for each retry.
+
+  -retry-wait-max=dur     The longest to wait between retries with
+                          -retry-wait-min. By default the wait is not capped.
+
+  -retry-budget=n         Cap the total number of retries performed across
+                          all requests made by this command, including
+                          concurrent ones. Each request is still retried at
+                          most as many times as -max-retries, or else
+                          VAULT_MAX_RETRIES, allows, and not at all if
+                          neither is set. By default no budget is applied.
+
+  -client-timeout=60s     Timeout for each request made to Vault, such as
+                          "30s" or "2m". Zero disables the timeout, so that a
+                          hung server is waited on forever. Overrides the
+                          VAULT_CLIENT_TIMEOUT environment variable if set,
+                          and defaults to 60 seconds otherwise.
+
+  -connect-timeout=duration
+                          Timeout for connecting to Vault, covering the TCP
+                          connection and the TLS handshake but not the
+                          request itself, such as "5s". This fails fast when
+                          the server is unreachable while -client-timeout
+                          still allows slow responses. By default the TCP
+                          connection times out after 30 seconds and the TLS
+                          handshake after 10.
+
+  -retry-on-status=codes  Comma-separated HTTP status codes to retry requests
+                          on, in addition to 5xx errors, such as
+                          -retry-on-status=408,520. Requests are still retried
+                          at most VAULT_MAX_RETRIES times. This can be
+                          specified multiple times.
+
+  -request-hook=program   Program to run before each request is sent. It is
+                          given the request as JSON on stdin and must print a
+                          JSON object of headers to add to the request. If
+                          the program fails, the request is aborted.
+
+  -request-hook-timeout=10s
+                          How long the -request-hook program may run before
+                          the request is aborted.
+
+  -disable-srv-lookup     Do not look up the SRV record of the host of the
+                          address when it does not include a port. By default
+                          the SRV record, if any, gives the host and port to
+                          connect to.
+
+  -prewarm-tls            Establish the connection to the server, including
+                          the TLS handshake, with a throwaway request before
+                          running the command, so that the command's own
+                          requests reuse it. This enables keep-alives, which
+                          are otherwise disabled.
+
+  -show-identity          Before running the command, look up the token in
+                          use and print its display name, policies, and
+                          entity ID on stderr.
+
+  -otel-endpoint=url      Export a trace of the command, with a span for the
+                          command and one for each request made to the
+                          server, with its path, method, status, and latency,
+                          to the OpenTelemetry collector at this URL using
+                          OTLP over HTTP, for example http://localhost:4318.
+                          The trace is exported once the command has run,
+                          delaying its exit by at most 2s. A failure to
+                          export is reported as a warning.
+
+  -remember               Once the server is reached, remember the address and
+                          namespace in use in ~/.vault-state. With
+                          -unix-socket, the socket is remembered as a unix://
+                          address. Later commands use the remembered address
+                          when neither -address nor VAULT_ADDR is given, and
+                          the remembered namespace with that address when
+                          neither -namespace nor VAULT_NAMESPACE is given.
+
+  -no-remember            Do not use the remembered address or namespace.
+
+  -proxy=url              Connect to Vault through the proxy at this address,
+                          an http://, https://, or socks5:// URL, such as
+                          socks5://127.0.0.1:1080. Overrides the
+                          VAULT_PROXY_ADDR environment variable if set. By
+                          default the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY
+                          environment variables are used.
+
+  -unix-socket=path       Connect to Vault through the Unix socket at this
+                          path, such as that of a local agent. Takes
+                          precedence over a unix:// address. If another
+                          address is given by -address or VAULT_ADDR, it is
+                          only used as the URL of the requests; otherwise
+                          that is http://localhost.
+
+  -idle-conn-timeout=90s  Close connections to the server that have been idle
+                          for this long, so that they are not dropped by a
+                          load balancer while still in use. Zero means idle
+                          connections are never closed. This only matters
+                          when connections are kept alive, such as with
+                          -prewarm-tls.
+
+  -tls-min-version=tls12  The oldest TLS version to connect with: tls10,
+                          tls11, tls12, or tls13. Overrides the
+                          VAULT_TLS_MIN_VERSION environment variable if set.
+
+  -tls-ciphers=names      The TLS cipher suites that may be used, by their
+                          IANA names, such as
+                          TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. May be
+                          comma-separated or given more than once. This only
+                          restricts TLS 1.2 and earlier, as the cipher suites
+                          of TLS 1.3 cannot be configured. Overrides the
+                          VAULT_TLS_CIPHERS environment variable if set.
+
+  -tls-server-name-from-addr
+                          Use the host of the address of the Vault server,
+                          without its port, as the name its certificate is
+                          verified against and sent in SNI, even when the
+                          connection is made to another host, such as the
+                          target of the SRV record of the address. The
+                          VAULT_TLS_SERVER_NAME environment variable takes
+                          precedence if set.
+
+  -tls-renegotiation=never
+                          Whether the server may renegotiate TLS, which some
+                          legacy servers and middleboxes require: never,
+                          once per connection, or freely. Renegotiation has
+                          been the subject of several attacks and lets the
+                          server change the certificates in use during a
+                          connection, so only allow it for servers that need
+                          it.
+
+  -har-file=path          Record the requests made to the server and their
+                          responses to this file in the HTTP Archive (HAR)
+                          format, for inspection with other tools. Tokens and
+                          accessors in headers and bodies are redacted, but
+                          secrets are not, so handle the file with care.
+
+  -warn-token-ttl=dur     Before running the command, look up the token in
+                          use and print a warning on stderr if it expires in
+                          less than this duration, such as "1h".
+
+  -warn-root-token        Before running the command, look up the token in
+                          use and print a warning on stderr if it is a root
+                          token or a token that does not expire.
+
+  -propagate-deadline     Send the time left until each request times out, as
+                          set by -client-timeout or else VAULT_CLIENT_TIMEOUT,
+                          to the server in the X-Vault-Request-Timeout header,
+                          such as "59.998s". The header is advisory: this
+                          version of Vault ignores it, so the server does not
+                          stop working on requests the client has given up
+                          on, but a proxy in front of it may use it.
+
+  -fail-on-redirect-to-standby
+                          Before running the command, check that the server
+                          is the active node and fail if it is a standby or
+                          performance standby, whose reads may be stale.
+
+  -allow-standby          Allow the server to be a standby, overriding
+                          -fail-on-redirect-to-standby.
+
+  -auth=method            If no token is given or stored, log in with this
+                          method using the credentials of the machine before
+                          running the command: aws, with the instance's IAM
+                          credentials; gcp, with the identity of the
+                          instance's service account; or kubernetes, with the
+                          pod's service account token.
+
+  -auth-config=key=value  A setting of the -auth login, which may be given
+                          more than once. "role" is the role to log in as
+                          and "mount" the path the auth backend is mounted
+                          at, which defaults to the name of the method.
+
+  -auth-cache             Store the token from -auth with the token helper,
+                          so that later commands use it without logging in.
+
+  -no-color               Never color the output or the help. By default
+                          they are colored when written to a terminal,
+                          unless VAULT_CLI_NO_COLOR is set to true or
+                          NO_COLOR is set.
 `
 
 	general += additionalOptionsUsage()
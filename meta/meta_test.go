@@ -1,10 +1,27 @@
 package meta
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/pem"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"os"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/token"
+	"github.com/mitchellh/cli"
 )
 
 func TestFlagSet(t *testing.T) {
@@ -14,11 +31,15 @@ func TestFlagSet(t *testing.T) {
 	}{
 		{
 			FlagSetNone,
-			[]string{},
+			[]string{"no-color"},
 		},
 		{
 			FlagSetServer,
-			[]string{"address", "ca-cert", "ca-path", "client-cert", "client-key", "insecure", "tls-skip-verify", "wrap-ttl"},
+			[]string{"address", "allow-any-address", "allow-standby", "auth", "auth-cache", "auth-config", "ca-cert", "ca-cert-pem", "ca-cert-url", "ca-path", "client-cert", "client-key", "client-pkcs12", "client-pkcs12-password", "client-timeout", "connect-timeout", "disable-srv-lookup", "fail-on-redirect-to-standby", "har-file", "header", "idle-conn-timeout", "insecure", "max-retries", "namespace", "no-color", "no-remember", "otel-endpoint", "prewarm-tls", "propagate-deadline", "proxy", "remember", "request-hook", "request-hook-timeout", "retry-budget", "retry-on-status", "retry-wait-max", "retry-wait-min", "show-identity", "tls-ciphers", "tls-min-version", "tls-renegotiation", "tls-server-name-from-addr", "tls-skip-verify", "unix-socket", "user-agent", "warn-root-token", "warn-token-ttl", "wrap-ttl"},
+		},
+		{
+			FlagSetOutput,
+			[]string{"array-style", "avro-schema", "avro-strict", "binary-preview", "bool-style", "bytes-base", "color", "env-prefix", "fail-if-empty", "field-single", "file-group", "file-mode", "file-owner", "hide-empty-columns", "humanize-bytes", "json-indent", "json-indent-tab", "jsonpath", "jsonpath-allow-empty", "list-format", "max-col-width", "merge-sections", "no-color", "no-final-newline", "no-sanitize", "no-sep-keys", "only", "output-dir", "quiet", "quote-values", "respect-sensitive-metadata", "reveal", "show-age", "show-truncated", "sign-output", "signature-file", "sort-by", "sort-desc", "syslog", "syslog-facility", "syslog-only", "syslog-tag", "template", "template-dir", "template-missing-key", "thousands-sep", "transpose", "ttl-as-expiry", "typed-json", "warnings-as-footnotes"},
 		},
 	}
 
@@ -39,3 +60,1220 @@ func TestFlagSet(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultFormat(t *testing.T) {
+	defer os.Setenv("VAULT_FORMAT", os.Getenv("VAULT_FORMAT"))
+
+	os.Setenv("VAULT_FORMAT", "")
+	if actual := DefaultFormat("json"); actual != "json" {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	os.Setenv("VAULT_FORMAT", "yaml")
+	if actual := DefaultFormat("json"); actual != "yaml" {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	// The flag still wins
+	var format string
+	fs := flag.NewFlagSet("foo", flag.ContinueOnError)
+	fs.StringVar(&format, "format", DefaultFormat("json"), "")
+	if err := fs.Parse([]string{"-format", "table"}); err != nil {
+		t.Fatal(err)
+	}
+	if format != "table" {
+		t.Fatalf("bad: %s", format)
+	}
+}
+
+func TestClient_caCertURL(t *testing.T) {
+	var fetches int
+	var caPEM []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(caPEM)
+	}))
+	defer server.Close()
+	caPEM = pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+
+	m := Meta{caCertURLClient: server.Client()}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-ca-cert-url", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.Client(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("expected the CA certificate to be fetched once, got %d", fetches)
+	}
+	if !bytes.Equal(m.caCertPEM, caPEM) {
+		t.Fatalf("bad: %s", m.caCertPEM)
+	}
+
+	// Anything that is not a certificate is rejected
+	caPEM = []byte("not a certificate")
+	m = Meta{caCertURLClient: server.Client()}
+	fs = m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-ca-cert-url", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Plain HTTP is not allowed
+	m = Meta{}
+	fs = m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-ca-cert-url", "http://127.0.0.1/ca.pem"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClient_caCertPEM(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+	caPEM := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}))
+
+	defer os.Setenv("VAULT_CACERT_PEM", os.Getenv("VAULT_CACERT_PEM"))
+	cases := []struct {
+		Env  string
+		Args []string
+		Err  string
+	}{
+		{"", []string{"-ca-cert-pem", caPEM}, ""},
+		{caPEM, nil, ""},
+		{"", nil, "certificate"},
+		{"", []string{"-ca-cert-pem", "not a certificate"}, "does not contain any valid PEM encoded certificates"},
+		{"", []string{"-ca-cert-pem", caPEM, "-ca-cert", "ca.pem"}, "cannot be used with"},
+	}
+
+	for _, tc := range cases {
+		os.Setenv("VAULT_CACERT_PEM", tc.Env)
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, tc.Args...)); err != nil {
+			t.Fatal(err)
+		}
+		client, err := m.Client()
+		if err == nil {
+			_, err = client.Logical().Read("secret/foo")
+		}
+		if tc.Err == "" && err != nil {
+			t.Fatalf("%d %v: err: %s", len(tc.Env), tc.Args, err)
+		}
+		if tc.Err != "" && (err == nil || !strings.Contains(err.Error(), tc.Err)) {
+			t.Fatalf("%d %v: expected error %q, got %v", len(tc.Env), tc.Args, tc.Err, err)
+		}
+	}
+}
+
+func TestClient_clientPKCS12(t *testing.T) {
+	var subjects []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subjects = append(subjects, r.TLS.PeerCertificates[0].Subject.CommonName)
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	defer os.Setenv("VAULT_CLIENT_PKCS12_PASSWORD", os.Getenv("VAULT_CLIENT_PKCS12_PASSWORD"))
+	os.Setenv("VAULT_CLIENT_PKCS12_PASSWORD", "")
+	cases := []struct {
+		Args []string
+		Env  string
+		Err  string
+	}{
+		{[]string{"-client-pkcs12", "test-fixtures/client.p12", "-client-pkcs12-password", "vault"}, "", ""},
+		{[]string{"-client-pkcs12", "test-fixtures/client.p12"}, "vault", ""},
+		{[]string{"-client-pkcs12", "test-fixtures/client.p12", "-client-pkcs12-password", "wrong"}, "", "the password is wrong"},
+		{[]string{"-client-pkcs12", "test-fixtures/client-nokey.p12", "-client-pkcs12-password", "vault"}, "", "must hold exactly one certificate and its private key"},
+		{[]string{"-client-pkcs12", "test-fixtures/missing.p12"}, "", "error reading the PKCS#12 bundle"},
+		{[]string{"-client-pkcs12", "test-fixtures/client.p12", "-client-cert", "cert.pem"}, "", "cannot be used with"},
+	}
+
+	for _, tc := range cases {
+		subjects = nil
+		os.Setenv("VAULT_CLIENT_PKCS12_PASSWORD", tc.Env)
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL, "-tls-skip-verify"}, tc.Args...)); err != nil {
+			t.Fatal(err)
+		}
+		client, err := m.Client()
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%v: expected error %q, got %v", tc.Args, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.Args, err)
+		}
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("%v: err: %s", tc.Args, err)
+		}
+		if len(subjects) != 1 || subjects[0] != "vault-client" {
+			t.Fatalf("%v: bad: %v", tc.Args, subjects)
+		}
+	}
+}
+
+func TestClient_allowedAddresses(t *testing.T) {
+	allowed := func() ([]string, error) {
+		return []string{"https://vault-*.dev.example.com:8200", "http://127.0.0.1:*"}, nil
+	}
+
+	cases := []struct {
+		Args []string
+		Err  bool
+	}{
+		{[]string{"-address", "https://vault-1.dev.example.com:8200"}, false},
+		{[]string{"-address", "http://127.0.0.1:8200"}, false},
+		{[]string{"-address", "https://vault.prod.example.com:8200"}, true},
+		{[]string{"-address", "https://vault-1.dev.example.com.evil.com:8200"}, true},
+		{[]string{"-address", "https://vault.prod.example.com:8200", "-allow-any-address"}, false},
+	}
+
+	for i, tc := range cases {
+		m := Meta{AllowedAddresses: allowed}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := m.Client()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%d: %v: expected error %t, got %v", i, tc.Args, tc.Err, err)
+		}
+	}
+
+	// Without any patterns every address is allowed
+	m := Meta{AllowedAddresses: func() ([]string, error) { return nil, nil }}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", "https://anywhere:8200"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestClientConfig_disableSRVLookup(t *testing.T) {
+	for _, disable := range []bool{false, true} {
+		var m Meta
+		fs := m.FlagSet("foo", FlagSetServer)
+		var args []string
+		if disable {
+			args = append(args, "-disable-srv-lookup")
+		}
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if config.DisableSRVLookup != disable {
+			t.Fatalf("bad: expected %t, got %t", disable, config.DisableSRVLookup)
+		}
+	}
+}
+
+func TestClientConfig_idleConnTimeout(t *testing.T) {
+	cases := []struct {
+		Args     []string
+		Expected time.Duration
+	}{
+		{nil, 90 * time.Second},
+		{[]string{"-idle-conn-timeout", "15s"}, 15 * time.Second},
+		{[]string{"-idle-conn-timeout", "0"}, 0},
+	}
+
+	for _, tc := range cases {
+		var m Meta
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		transport := config.HttpClient.Transport.(*http.Transport)
+		if transport.IdleConnTimeout != tc.Expected {
+			t.Fatalf("%v: bad: %s", tc.Args, transport.IdleConnTimeout)
+		}
+	}
+}
+
+func TestClientConfig_tlsRenegotiation(t *testing.T) {
+	cases := []struct {
+		Args     []string
+		Expected tls.RenegotiationSupport
+		Err      bool
+	}{
+		{nil, tls.RenegotiateNever, false},
+		{[]string{"-tls-renegotiation", "never"}, tls.RenegotiateNever, false},
+		{[]string{"-tls-renegotiation", "once"}, tls.RenegotiateOnceAsClient, false},
+		{[]string{"-tls-renegotiation", "freely"}, tls.RenegotiateFreelyAsClient, false},
+		{[]string{"-tls-renegotiation", "always"}, 0, true},
+	}
+
+	for _, tc := range cases {
+		var m Meta
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%v: expected error %t, got %v", tc.Args, tc.Err, err)
+		}
+		if err != nil {
+			continue
+		}
+		transport := config.HttpClient.Transport.(*http.Transport)
+		if actual := transport.TLSClientConfig.Renegotiation; actual != tc.Expected {
+			t.Fatalf("%v: bad: %v", tc.Args, actual)
+		}
+	}
+}
+
+func TestClientConfig_tlsMinVersion(t *testing.T) {
+	defer os.Setenv("VAULT_TLS_MIN_VERSION", os.Getenv("VAULT_TLS_MIN_VERSION"))
+	cases := []struct {
+		Env      string
+		Args     []string
+		Expected uint16
+		Err      bool
+	}{
+		{"", nil, tls.VersionTLS12, false},
+		{"", []string{"-tls-min-version", "tls13"}, tls.VersionTLS13, false},
+		{"", []string{"-tls-min-version", "tls10", "-tls-skip-verify"}, tls.VersionTLS10, false},
+		{"tls11", nil, tls.VersionTLS11, false},
+		{"tls11", []string{"-tls-min-version", "tls13"}, tls.VersionTLS13, false},
+		{"", []string{"-tls-min-version", "ssl3"}, 0, true},
+		{"TLS1.2", nil, 0, true},
+	}
+
+	for _, tc := range cases {
+		os.Setenv("VAULT_TLS_MIN_VERSION", tc.Env)
+		var m Meta
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%q %v: expected error %t, got %v", tc.Env, tc.Args, tc.Err, err)
+		}
+		if err != nil {
+			continue
+		}
+		transport := config.HttpClient.Transport.(*http.Transport)
+		if actual := transport.TLSClientConfig.MinVersion; actual != tc.Expected {
+			t.Fatalf("%q %v: bad: %x", tc.Env, tc.Args, actual)
+		}
+	}
+}
+
+func TestClientConfig_tlsServerNameFromAddr(t *testing.T) {
+	defer os.Setenv("VAULT_TLS_SERVER_NAME", os.Getenv("VAULT_TLS_SERVER_NAME"))
+	os.Setenv("VAULT_TLS_SERVER_NAME", "")
+	cases := []struct {
+		Env      string
+		Args     []string
+		Expected string
+		Err      string
+	}{
+		{"", []string{"-address", "https://vault.example.com:8200"}, "", ""},
+		{"", []string{"-address", "https://vault.example.com:8200", "-tls-server-name-from-addr"}, "vault.example.com", ""},
+		{"", []string{"-address", "https://vault.example.com", "-tls-server-name-from-addr"}, "vault.example.com", ""},
+		{"", []string{"-address", "vault.example.com:8200", "-tls-server-name-from-addr"}, "vault.example.com", ""},
+		{"", []string{"-address", "https://[2001:db8::1]:8200", "-tls-server-name-from-addr"}, "2001:db8::1", ""},
+		{"", []string{"-address", "[2001:db8::1]:8200", "-tls-server-name-from-addr"}, "2001:db8::1", ""},
+		{"other.example.com", []string{"-address", "https://vault.example.com:8200", "-tls-server-name-from-addr"}, "other.example.com", ""},
+		{"", []string{"-address", "https://[2001:db8::1:8200", "-tls-server-name-from-addr"}, "", "cannot take the TLS server name"},
+		{"", []string{"-address", "https://:8200", "-tls-server-name-from-addr"}, "", "missing host"},
+	}
+
+	for _, tc := range cases {
+		os.Setenv("VAULT_TLS_SERVER_NAME", tc.Env)
+		var m Meta
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%q %v: expected error %q, got %v", tc.Env, tc.Args, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q %v: err: %s", tc.Env, tc.Args, err)
+		}
+		transport := config.HttpClient.Transport.(*http.Transport)
+		if actual := transport.TLSClientConfig.ServerName; actual != tc.Expected {
+			t.Fatalf("%q %v: expected %q, got %q", tc.Env, tc.Args, tc.Expected, actual)
+		}
+	}
+}
+
+func TestClientConfig_tlsCiphers(t *testing.T) {
+	defer os.Setenv("VAULT_TLS_CIPHERS", os.Getenv("VAULT_TLS_CIPHERS"))
+	cases := []struct {
+		Env      string
+		Args     []string
+		Expected []uint16
+		Err      string
+	}{
+		{"", nil, nil, ""},
+		{
+			"",
+			[]string{"-tls-ciphers", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			"",
+		},
+		{
+			"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			[]string{"-tls-ciphers", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "-tls-ciphers", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			[]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			"",
+		},
+		{
+			"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			nil,
+			[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			"",
+		},
+		{"", []string{"-tls-ciphers", "RC4-MD5"}, nil, "expected one of: "},
+		{"", []string{"-tls-ciphers", "TLS_AES_128_GCM_SHA256"}, nil, "unknown TLS cipher suite"},
+	}
+
+	for _, tc := range cases {
+		os.Setenv("VAULT_TLS_CIPHERS", tc.Env)
+		var m Meta
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%q %v: expected error %q, got %v", tc.Env, tc.Args, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q %v: err: %s", tc.Env, tc.Args, err)
+		}
+		transport := config.HttpClient.Transport.(*http.Transport)
+		if actual := transport.TLSClientConfig.CipherSuites; !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%q %v: bad: %v", tc.Env, tc.Args, actual)
+		}
+	}
+}
+
+func TestClient_prewarmTLS(t *testing.T) {
+	var l sync.Mutex
+	var conns int
+	var paths []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		l.Unlock()
+		w.WriteHeader(204)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			l.Lock()
+			conns++
+			l.Unlock()
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile, err := ioutil.TempFile("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	caFile.Close()
+
+	for _, prewarm := range []bool{false, true} {
+		l.Lock()
+		conns, paths = 0, nil
+		l.Unlock()
+
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		args := []string{"-address", server.URL, "-ca-cert", caFile.Name()}
+		if prewarm {
+			args = append(args, "-prewarm-tls")
+		}
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		l.Lock()
+		if prewarm && (conns != 1 || len(paths) != 1 || paths[0] != "HEAD /v1/sys/health") {
+			t.Fatalf("expected the connection to be prewarmed: %d, %v", conns, paths)
+		}
+		if !prewarm && conns != 0 {
+			t.Fatalf("expected no connection: %d", conns)
+		}
+		l.Unlock()
+
+		if _, err := client.Logical().Delete("secret/foo"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// The request reused the prewarmed connection
+		l.Lock()
+		if conns != 1 {
+			t.Fatalf("expected 1 connection, got %d", conns)
+		}
+		l.Unlock()
+	}
+}
+
+// traceTransport records whether each request was made on a connection
+// that was reused.
+type traceTransport struct {
+	l      sync.Mutex
+	reused []bool
+	base   http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.l.Lock()
+			t.reused = append(t.reused, info.Reused)
+			t.l.Unlock()
+		},
+	}
+	return t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+func TestPrewarm_reuse(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	for _, warm := range []bool{false, true} {
+		config := api.DefaultConfig()
+		config.Address = server.URL
+		trace := &traceTransport{base: server.Client().Transport.(*http.Transport).Clone()}
+		config.HttpClient.Transport = trace
+
+		client, err := api.NewClient(config)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		client.SetToken("foo")
+
+		if warm {
+			prewarm(client)
+		}
+		if _, err := client.Logical().Delete("secret/foo"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// With prewarming the request is made on the connection it opened
+		expected := []bool{false}
+		if warm {
+			expected = []bool{false, true}
+		}
+		trace.l.Lock()
+		if !reflect.DeepEqual(trace.reused, expected) {
+			t.Fatalf("prewarm %t: bad: %v", warm, trace.reused)
+		}
+		trace.l.Unlock()
+	}
+}
+
+func TestClient_showIdentity(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("X-Vault-Token") != "foo" {
+			w.WriteHeader(403)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"display_name":"approle-ci","policies":["default","ci"],"entity_id":"7d2e3179"}}`)
+	}))
+	defer server.Close()
+
+	ui := cli.NewMockUi()
+	m := Meta{ClientToken: "foo", Ui: ui}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-show-identity"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "Acting as display_name=approle-ci policies=[default ci] entity_id=7d2e3179\n"
+	if actual := ui.ErrorWriter.String(); actual != expected {
+		t.Fatalf("bad: %q", actual)
+	}
+	if len(paths) != 1 || paths[0] != "/v1/auth/token/lookup-self" {
+		t.Fatalf("bad: %v", paths)
+	}
+
+	// A failed lookup is an error
+	m = Meta{ClientToken: "bar", Ui: cli.NewMockUi()}
+	fs = m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-show-identity"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err == nil || !strings.Contains(err.Error(), "error looking up token") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Without the flag there is no lookup
+	paths = nil
+	m = Meta{ClientToken: "foo", Ui: cli.NewMockUi()}
+	fs = m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(paths) != 0 {
+		t.Fatalf("bad: %v", paths)
+	}
+}
+
+func TestClient_warnToken(t *testing.T) {
+	var paths []string
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if response == "" {
+			w.WriteHeader(403)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		Response string
+		Args     []string
+		Warning  string
+	}{
+		{`{"data":{"ttl":300,"policies":["default"]}}`, []string{"-warn-token-ttl", "1h"},
+			"Warning: the token in use expires in 5m0s, less than the 1h0m0s given by -warn-token-ttl"},
+		{`{"data":{"ttl":7200,"policies":["default"]}}`, []string{"-warn-token-ttl", "1h"}, ""},
+		// Tokens that do not expire only warn with -warn-root-token
+		{`{"data":{"ttl":0,"policies":["default"]}}`, []string{"-warn-token-ttl", "1h"}, ""},
+		{`{"data":{"ttl":0,"policies":["default"]}}`, []string{"-warn-root-token"},
+			"Warning: the token in use does not expire"},
+		{`{"data":{"ttl":0,"policies":["root"]}}`, []string{"-warn-root-token", "-warn-token-ttl", "1h"},
+			"Warning: the token in use is a root token"},
+		{`{"data":{"ttl":300,"policies":["default"]}}`, []string{"-warn-root-token"}, ""},
+		// A failed lookup is only a warning
+		{"", []string{"-warn-token-ttl", "1h"},
+			"Warning: error looking up token, not checking its TTL"},
+	}
+
+	for _, tc := range cases {
+		paths, response = nil, tc.Response
+		ui := cli.NewMockUi()
+		m := Meta{ClientToken: "foo", Ui: ui}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, tc.Args...)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Client(); err != nil {
+			t.Fatalf("%s %v: err: %s", tc.Response, tc.Args, err)
+		}
+
+		actual := ui.ErrorWriter.String()
+		if (tc.Warning == "" && actual != "") || !strings.Contains(actual, tc.Warning) {
+			t.Fatalf("%s %v: bad: %q", tc.Response, tc.Args, actual)
+		}
+		if len(paths) != 1 || paths[0] != "/v1/auth/token/lookup-self" {
+			t.Fatalf("%s %v: bad: %v", tc.Response, tc.Args, paths)
+		}
+	}
+}
+
+func TestClient_retryOnStatus(t *testing.T) {
+	cases := []struct {
+		Args []string
+		Err  bool
+	}{
+		{[]string{"-retry-on-status", "408,520", "-retry-on-status", "522"}, false},
+		{[]string{"-retry-on-status", "teapot"}, true},
+		{[]string{"-retry-on-status", "1000"}, true},
+	}
+
+	for i, tc := range cases {
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", "http://127.0.0.1:8200"}, tc.Args...)); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := m.Client()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%d: %v: expected error %t, got %v", i, tc.Args, tc.Err, err)
+		}
+	}
+}
+
+func TestClient_clientTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.Write([]byte(`{"data":{}}`))
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		Args    []string
+		Timeout time.Duration
+		Err     bool
+	}{
+		{nil, 60 * time.Second, false},
+		{[]string{"-client-timeout", "100ms"}, 100 * time.Millisecond, true},
+		{[]string{"-client-timeout", "0"}, 0, false},
+	}
+
+	for i, tc := range cases {
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", ts.URL}, tc.Args...)); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if config.HttpClient.Timeout != tc.Timeout {
+			t.Fatalf("%d: bad timeout: %s", i, config.HttpClient.Timeout)
+		}
+
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if _, err := client.Logical().Read("secret/foo"); (err != nil) != tc.Err {
+			t.Fatalf("%d: expected error %t, got %v", i, tc.Err, err)
+		}
+	}
+}
+
+func TestClient_connectTimeout(t *testing.T) {
+	// A server that accepts connections but never completes the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// The connection is held open until the listener is closed
+			defer conn.Close()
+		}
+	}()
+
+	// A server that is slow to respond once connected
+	slow := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte(`{"data":{}}`))
+	}))
+	defer slow.Close()
+
+	args := []string{"-connect-timeout", "100ms", "-client-timeout", "10s", "-max-retries", "0", "-tls-skip-verify"}
+	m := Meta{ClientToken: "foo"}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse(append([]string{"-address", "https://" + ln.Addr().String()}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := m.clientConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	transport := config.HttpClient.Transport.(*http.Transport)
+	if transport.TLSHandshakeTimeout != 100*time.Millisecond || config.HttpClient.Timeout != 10*time.Second {
+		t.Fatalf("bad timeouts: %s, %s", transport.TLSHandshakeTimeout, config.HttpClient.Timeout)
+	}
+
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	start := time.Now()
+	if _, err := client.Logical().Read("secret/foo"); err == nil {
+		t.Fatal("expected the handshake to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the connect timeout was not applied: %s", elapsed)
+	}
+
+	// The connect timeout does not limit the response
+	m = Meta{ClientToken: "foo"}
+	fs = m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse(append([]string{"-address", slow.URL}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	client, err = m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Logical().Read("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m = Meta{ClientToken: "foo"}
+	fs = m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-connect-timeout", "-1s"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err == nil {
+		t.Fatal("expected an error for a negative timeout")
+	}
+}
+
+func TestClient_maxRetries(t *testing.T) {
+	cases := []struct {
+		Args       []string
+		MaxRetries int
+		Err        bool
+	}{
+		{nil, 0, false},
+		{[]string{"-max-retries", "2"}, 3, false},
+		{[]string{"-max-retries", "0"}, 1, false},
+		{[]string{"-retry-wait-min", "100ms", "-retry-wait-max", "2s"}, 0, false},
+		{[]string{"-retry-wait-max", "2s"}, 0, true},
+		{[]string{"-retry-wait-min", "2s", "-retry-wait-max", "1s"}, 0, true},
+	}
+
+	for i, tc := range cases {
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatal(err)
+		}
+
+		config, _, err := m.clientConfig()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%d: %v: expected error %t, got %v", i, tc.Args, tc.Err, err)
+		}
+		if err == nil && config.MaxRetries != tc.MaxRetries {
+			t.Fatalf("%d: %v: bad max retries: %d", i, tc.Args, config.MaxRetries)
+		}
+		if err == nil && !config.RetryIdempotentOnly {
+			t.Fatalf("%d: %v: expected writes not to be retried", i, tc.Args)
+		}
+	}
+}
+
+func TestClient_proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte(`{"data":{"via":"proxy"}}`))
+	}))
+	defer proxy.Close()
+
+	m := Meta{ClientToken: "foo"}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", "http://vault.example.com:8200", "-proxy", proxy.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	secret, err := client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if secret.Data["via"] != "proxy" || len(proxied) != 1 || proxied[0] != "http://vault.example.com:8200/v1/secret/foo" {
+		t.Fatalf("bad: %v %v", secret.Data, proxied)
+	}
+
+	for _, addr := range []string{"ftp://proxy.example.com", "socks5://", "http://[::1"} {
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse([]string{"-proxy", addr}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Client(); err == nil || !strings.Contains(err.Error(), "invalid proxy address") {
+			t.Fatalf("%s: expected error, got %v", addr, err)
+		}
+	}
+}
+
+func TestClient_namespace(t *testing.T) {
+	var namespaces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Header.Get("X-Vault-Namespace"))
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+
+	defer os.Setenv("VAULT_NAMESPACE", os.Getenv("VAULT_NAMESPACE"))
+	cases := []struct {
+		env       string
+		args      []string
+		namespace string
+	}{
+		{"", nil, ""},
+		{"", []string{"-namespace", "team-a/"}, "team-a/"},
+		{"team-b/", nil, "team-b/"},
+		{"team-b/", []string{"-namespace", "team-a/"}, "team-a/"},
+	}
+	for _, tc := range cases {
+		namespaces = nil
+		os.Setenv("VAULT_NAMESPACE", tc.env)
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, tc.args...)); err != nil {
+			t.Fatal(err)
+		}
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if len(namespaces) != 1 || namespaces[0] != tc.namespace {
+			t.Fatalf("%q %v: bad: %v", tc.env, tc.args, namespaces)
+		}
+	}
+}
+
+func TestClient_header(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header)
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+
+	m := Meta{ClientToken: "foo"}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-header", "x-request-id=1234", "-header", "X-Vault-Request=false"}); err != nil {
+		t.Fatal(err)
+	}
+	clients, err := m.Clients([]string{server.URL, server.URL})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, client := range clients {
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if len(headers) != 2 {
+		t.Fatalf("bad: %v", headers)
+	}
+	for _, h := range headers {
+		if h.Get("X-Request-Id") != "1234" || h.Get("X-Vault-Request") != "false" || h.Get("X-Vault-Token") != "foo" {
+			t.Fatalf("bad: %v", h)
+		}
+	}
+
+	for _, header := range []string{"X-Vault-Token=bar", "x-vault-namespace=team-a/", "=value"} {
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse([]string{"-address", server.URL, "-header", header}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Client(); err == nil || !strings.Contains(err.Error(), "header") {
+			t.Fatalf("%s: expected error, got %v", header, err)
+		}
+	}
+}
+
+func TestClient_userAgent(t *testing.T) {
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+
+	defer os.Setenv("VAULT_USER_AGENT", os.Getenv("VAULT_USER_AGENT"))
+	cases := []struct {
+		env   string
+		args  []string
+		agent string
+	}{
+		{"", []string{"-user-agent", "deploy-bot/1.0"}, "deploy-bot/1.0"},
+		{"cron/2.0", nil, "cron/2.0"},
+		{"cron/2.0", []string{"-user-agent", "deploy-bot/1.0"}, "deploy-bot/1.0"},
+	}
+	for _, tc := range cases {
+		agents = nil
+		os.Setenv("VAULT_USER_AGENT", tc.env)
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, tc.args...)); err != nil {
+			t.Fatal(err)
+		}
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if len(agents) != 1 || agents[0] != tc.agent {
+			t.Fatalf("%q %v: bad: %v", tc.env, tc.args, agents)
+		}
+	}
+
+	// Without it, the default is left alone
+	agents = nil
+	os.Setenv("VAULT_USER_AGENT", "")
+	m := Meta{ClientToken: "foo"}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Logical().Read("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(agents) != 1 || !strings.HasPrefix(agents[0], "Go-http-client/") {
+		t.Fatalf("bad: %v", agents)
+	}
+}
+
+func TestClient_failOnStandby(t *testing.T) {
+	var health string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/health" || r.URL.Query().Get("perfstandbycode") != "299" {
+			t.Errorf("bad request: %s", r.URL)
+		}
+		w.Write([]byte(health))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		health string
+		args   []string
+		err    string
+	}{
+		{`{"standby":false}`, nil, ""},
+		{`{"standby":true}`, nil, "is a standby, not the active node"},
+		{`{"standby":false,"performance_standby":true}`, nil, "is a performance standby"},
+		{`{"standby":true}`, []string{"-allow-standby"}, ""},
+	}
+	for _, tc := range cases {
+		health = tc.health
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		args := append([]string{"-address", server.URL, "-fail-on-redirect-to-standby"}, tc.args...)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		_, err := m.Client()
+		if tc.err == "" && err != nil {
+			t.Fatalf("%s: err: %s", tc.health, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Fatalf("%s: expected error %q, got %v", tc.health, tc.err, err)
+		}
+	}
+}
+
+// memTokenHelper is a token helper that keeps the token in memory.
+type memTokenHelper struct {
+	token string
+}
+
+func (h *memTokenHelper) Path() string             { return "" }
+func (h *memTokenHelper) Erase() error             { h.token = ""; return nil }
+func (h *memTokenHelper) Get() (string, error)     { return h.token, nil }
+func (h *memTokenHelper) Store(token string) error { h.token = token; return nil }
+
+func TestClient_autoLogin(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("X-Vault-Token"))
+		w.Write([]byte(`{"data":{"foo":"bar"}}`))
+	}))
+	defer server.Close()
+
+	var logins []map[string]string
+	autoLogins := map[string]AutoLoginFunc{
+		"test": func(client *api.Client, config map[string]string) (*api.Secret, error) {
+			logins = append(logins, config)
+			if config["role"] == "" {
+				return nil, fmt.Errorf("missing role")
+			}
+			return &api.Secret{Auth: &api.SecretAuth{ClientToken: "login-token"}}, nil
+		},
+	}
+
+	cases := []struct {
+		clientToken string
+		args        []string
+		token       string
+		logins      int
+		cached      string
+	}{
+		{"", []string{"-auth", "test", "-auth-config", "role=web"}, "login-token", 1, ""},
+		{"", []string{"-auth", "test", "-auth-config", "role=web", "-auth-cache"}, "login-token", 1, "login-token"},
+		{"given-token", []string{"-auth", "test", "-auth-config", "role=web"}, "given-token", 0, ""},
+	}
+	for _, tc := range cases {
+		tokens, logins = nil, nil
+		helper := &memTokenHelper{}
+		m := Meta{
+			ClientToken: tc.clientToken,
+			AutoLogins:  autoLogins,
+			TokenHelper: func() (token.TokenHelper, error) { return helper, nil },
+		}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, tc.args...)); err != nil {
+			t.Fatal(err)
+		}
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if len(tokens) != 1 || tokens[0] != tc.token || len(logins) != tc.logins || helper.token != tc.cached {
+			t.Fatalf("%v: bad: %v %v %q", tc.args, tokens, logins, helper.token)
+		}
+		if tc.logins > 0 && logins[0]["role"] != "web" {
+			t.Fatalf("%v: bad: %v", tc.args, logins)
+		}
+	}
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-auth", "test"}, "error logging in with -auth=test: missing role"},
+		{[]string{"-auth", "other"}, `unknown -auth method "other", expected one of: test`},
+	} {
+		m := Meta{AutoLogins: autoLogins}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(append([]string{"-address", server.URL}, tc.args...)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Client(); err == nil || err.Error() != tc.err {
+			t.Fatalf("%v: expected error %q, got %v", tc.args, tc.err, err)
+		}
+	}
+}
+
+func TestClient_unixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := dir + "/agent.sock"
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		w.Write([]byte(`{"data":{"via":"socket"}}`))
+	})}
+	go server.Serve(ln)
+	defer server.Close()
+
+	cases := []struct {
+		args []string
+		host string
+	}{
+		{[]string{"-address", "unix://" + socket}, "localhost"},
+		{[]string{"-unix-socket", socket}, "localhost"},
+		{[]string{"-address", "http://vault.example.com:8200", "-unix-socket", socket}, "vault.example.com:8200"},
+		{[]string{"-address", "unix:///nonexistent.sock", "-unix-socket", socket}, "localhost"},
+	}
+	for _, tc := range cases {
+		hosts = nil
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(tc.args); err != nil {
+			t.Fatal(err)
+		}
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		secret, err := client.Logical().Read("secret/foo")
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.args, err)
+		}
+		if secret.Data["via"] != "socket" || len(hosts) != 1 || hosts[0] != tc.host {
+			t.Fatalf("%v: bad: %v %v", tc.args, secret.Data, hosts)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-address", "unix://relative.sock"},
+		{"-unix-socket", socket, "-proxy", "http://127.0.0.1:3128"},
+	} {
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Client(); err == nil {
+			t.Fatalf("%v: expected error", args)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package meta
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/mitchellh/cli"
+)
+
+// otelExportTimeout is how long exporting the spans of a command may delay
+// its exit. It is a variable so that tests can shorten it.
+var otelExportTimeout = 2 * time.Second
+
+// otelCommandStart is when the command started, taken as when this package
+// was initialized.
+var otelCommandStart = time.Now()
+
+// otelTracers are the tracers of the command, one for each -otel-endpoint,
+// whose spans ExportTraces exports.
+var otelTracers = struct {
+	sync.Mutex
+	m map[string]*otelTracer
+}{m: make(map[string]*otelTracer)}
+
+// otelTracer records the spans of a command: one for the command itself and
+// one for each request made to the server, as its children. The spans are
+// only exported once the command has run, so that a slow or unavailable
+// collector does not delay the requests.
+type otelTracer struct {
+	endpoint string
+	traceID  string
+	spanID   string
+	ui       cli.Ui
+
+	l     sync.Mutex
+	spans []otelSpan
+}
+
+// otelTracerFor returns the tracer of the command for the collector at
+// endpoint, creating it if the command has none yet, so that all the
+// clients of a command record their spans in the same trace.
+func otelTracerFor(endpoint string, ui cli.Ui) (*otelTracer, error) {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+
+	otelTracers.Lock()
+	defer otelTracers.Unlock()
+	if tracer, ok := otelTracers.m[endpoint]; ok {
+		return tracer, nil
+	}
+
+	traceID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	tracer := &otelTracer{
+		endpoint: endpoint,
+		traceID:  traceID,
+		spanID:   spanID,
+		ui:       ui,
+	}
+	otelTracers.m[endpoint] = tracer
+	return tracer, nil
+}
+
+// otelTransport is an http.RoundTripper that records each request as a span
+// of the command's trace, to be exported to the -otel-endpoint collector by
+// ExportTraces.
+type otelTransport struct {
+	tracer *otelTracer
+	base   http.RoundTripper
+}
+
+func newOtelTransport(endpoint string, ui cli.Ui, base http.RoundTripper) (*otelTransport, error) {
+	tracer, err := otelTracerFor(endpoint, ui)
+	if err != nil {
+		return nil, err
+	}
+	return &otelTransport{
+		tracer: tracer,
+		base:   base,
+	}, nil
+}
+
+func (t *otelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	end := time.Now()
+
+	attrs := []otelAttribute{
+		otelString("http.method", req.Method),
+		otelString("url.path", req.URL.Path),
+		otelInt("vault.latency_ms", int64(end.Sub(start)/time.Millisecond)),
+	}
+	status := otelStatus{}
+	if err != nil {
+		status = otelStatus{Code: otelStatusError, Message: err.Error()}
+	} else {
+		attrs = append(attrs, otelInt("http.status_code", int64(resp.StatusCode)))
+		if resp.StatusCode >= 400 {
+			status.Code = otelStatusError
+		}
+	}
+
+	if spanID, idErr := randomHex(8); idErr == nil {
+		t.tracer.record(otelSpan{
+			TraceID:           t.tracer.traceID,
+			SpanID:            spanID,
+			ParentSpanID:      t.tracer.spanID,
+			Name:              req.Method + " " + req.URL.Path,
+			Kind:              otelSpanKindClient,
+			StartTimeUnixNano: otelTime(start),
+			EndTimeUnixNano:   otelTime(end),
+			Attributes:        attrs,
+			Status:            status,
+		})
+	}
+
+	return resp, err
+}
+
+func (t *otelTracer) record(span otelSpan) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.spans = append(t.spans, span)
+}
+
+// ExportTraces ends the span of the command, named name and marked as
+// failed if the command exited with a non-zero code, and exports it along
+// with the spans of its requests to each -otel-endpoint collector in a
+// single request. It is called once the command has run, and does nothing
+// if -otel-endpoint was not given. Exporting is best effort: it delays the
+// exit by at most otelExportTimeout, and a failure is reported as a warning.
+func ExportTraces(name string, exitCode int) {
+	otelTracers.Lock()
+	tracers := otelTracers.m
+	otelTracers.m = make(map[string]*otelTracer)
+	otelTracers.Unlock()
+
+	end := time.Now()
+	for _, tracer := range tracers {
+		if err := tracer.export(name, exitCode, end); err != nil && tracer.ui != nil {
+			tracer.ui.Error(fmt.Sprintf("Warning: error exporting trace: %s", err))
+		}
+	}
+}
+
+// export sends the span of the command and the spans of its requests to
+// the collector.
+func (t *otelTracer) export(name string, exitCode int, end time.Time) error {
+	status := otelStatus{}
+	if exitCode != 0 {
+		status.Code = otelStatusError
+	}
+
+	t.l.Lock()
+	spans := append([]otelSpan{{
+		TraceID:           t.traceID,
+		SpanID:            t.spanID,
+		Name:              name,
+		Kind:              otelSpanKindInternal,
+		StartTimeUnixNano: otelTime(otelCommandStart),
+		EndTimeUnixNano:   otelTime(end),
+		Attributes:        []otelAttribute{otelInt("vault.exit_code", int64(exitCode))},
+		Status:            status,
+	}}, t.spans...)
+	t.l.Unlock()
+
+	body, err := json.Marshal(&otelRequest{
+		ResourceSpans: []otelResourceSpans{{
+			Resource: otelResource{
+				Attributes: []otelAttribute{otelString("service.name", "vault-cli")},
+			},
+			ScopeSpans: []otelScopeSpans{{
+				Scope: otelScope{Name: "github.com/hashicorp/vault/meta"},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = otelExportTimeout
+	resp, err := client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+func otelTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// The types below are the subset of the OTLP JSON encoding of an
+// ExportTraceServiceRequest needed to export client spans.
+
+const (
+	otelSpanKindInternal = 1
+	otelSpanKindClient   = 3
+	otelStatusError      = 2
+)
+
+type otelRequest struct {
+	ResourceSpans []otelResourceSpans `json:"resourceSpans"`
+}
+
+type otelResourceSpans struct {
+	Resource   otelResource     `json:"resource"`
+	ScopeSpans []otelScopeSpans `json:"scopeSpans"`
+}
+
+type otelResource struct {
+	Attributes []otelAttribute `json:"attributes"`
+}
+
+type otelScopeSpans struct {
+	Scope otelScope  `json:"scope"`
+	Spans []otelSpan `json:"spans"`
+}
+
+type otelScope struct {
+	Name string `json:"name"`
+}
+
+type otelSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otelAttribute `json:"attributes"`
+	Status            otelStatus      `json:"status"`
+}
+
+type otelStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otelAttribute struct {
+	Key   string    `json:"key"`
+	Value otelValue `json:"value"`
+}
+
+// otelValue is an AnyValue; 64-bit integers are encoded as strings.
+type otelValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otelString(key, value string) otelAttribute {
+	return otelAttribute{Key: key, Value: otelValue{StringValue: &value}}
+}
+
+func otelInt(key string, value int64) otelAttribute {
+	s := strconv.FormatInt(value, 10)
+	return otelAttribute{Key: key, Value: otelValue{IntValue: &s}}
+}
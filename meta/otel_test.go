@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestClient_otelEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/secret/missing" {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"errors":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"value":"bar"}}`)
+	}))
+	defer server.Close()
+
+	var l sync.Mutex
+	var requests []otelRequest
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+		paths = append(paths, r.URL.Path)
+		var req otelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		requests = append(requests, req)
+	}))
+	defer collector.Close()
+
+	ui := cli.NewMockUi()
+	m := Meta{ClientToken: "foo", Ui: ui}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-otel-endpoint", collector.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Logical().Read("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Logical().Read("secret/missing"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Nothing is exported until the command has run
+	l.Lock()
+	if len(requests) != 0 {
+		t.Fatalf("expected no exports, got %d", len(requests))
+	}
+	l.Unlock()
+
+	ExportTraces("vault read", 2)
+
+	l.Lock()
+	defer l.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 export, got %d", len(requests))
+	}
+	if len(paths) != 1 || paths[0] != "/v1/traces" {
+		t.Fatalf("bad: %v", paths)
+	}
+
+	spans := requests[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans: %#v", spans)
+	}
+	command := spans[0]
+	if command.Name != "vault read" || command.Kind != otelSpanKindInternal ||
+		command.ParentSpanID != "" || command.Status.Code != otelStatusError {
+		t.Fatalf("bad: %#v", command)
+	}
+	if spans[1].Name != "GET /v1/secret/foo" || spans[1].Kind != otelSpanKindClient {
+		t.Fatalf("bad: %#v", spans[1])
+	}
+	for _, span := range spans[1:] {
+		if span.TraceID != command.TraceID || span.ParentSpanID != command.SpanID {
+			t.Fatalf("expected a child of the command span: %#v", span)
+		}
+	}
+	if spans[1].SpanID == spans[2].SpanID {
+		t.Fatalf("bad: %#v", spans)
+	}
+	attrs := make(map[string]string)
+	for _, a := range spans[2].Attributes {
+		if a.Value.StringValue != nil {
+			attrs[a.Key] = *a.Value.StringValue
+		} else if a.Value.IntValue != nil {
+			attrs[a.Key] = *a.Value.IntValue
+		}
+	}
+	if attrs["http.method"] != "GET" || attrs["url.path"] != "/v1/secret/missing" ||
+		attrs["http.status_code"] != "404" || attrs["vault.latency_ms"] == "" {
+		t.Fatalf("bad: %#v", attrs)
+	}
+	if spans[1].Status.Code != 0 || spans[2].Status.Code != otelStatusError {
+		t.Fatalf("bad: %#v", spans)
+	}
+	if ui.ErrorWriter.String() != "" {
+		t.Fatalf("unexpected warning: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestClient_otelEndpointUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"value":"bar"}}`)
+	}))
+	defer server.Close()
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer collector.Close()
+
+	// A failed export is a warning, the request still succeeds
+	ui := cli.NewMockUi()
+	m := Meta{ClientToken: "foo", Ui: ui}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-otel-endpoint", collector.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	secret, err := client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+	ExportTraces("vault read", 0)
+	if !strings.Contains(ui.ErrorWriter.String(), "Warning: error exporting trace") {
+		t.Fatalf("expected a warning: %q", ui.ErrorWriter.String())
+	}
+}
+
+func TestClient_otelEndpointHanging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"value":"bar"}}`)
+	}))
+	defer server.Close()
+
+	release := make(chan struct{})
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer collector.Close()
+	defer close(release)
+
+	defer func(timeout time.Duration) { otelExportTimeout = timeout }(otelExportTimeout)
+	otelExportTimeout = 200 * time.Millisecond
+
+	ui := cli.NewMockUi()
+	m := Meta{ClientToken: "foo", Ui: ui}
+	fs := m.FlagSet("foo", FlagSetServer)
+	if err := fs.Parse([]string{"-address", server.URL, "-otel-endpoint", collector.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := m.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The requests are not delayed by the collector
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.Logical().Read("secret/foo"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > otelExportTimeout {
+		t.Fatalf("requests took %s", elapsed)
+	}
+
+	// Nor is the exit delayed by more than the export timeout
+	start = time.Now()
+	ExportTraces("vault read", 0)
+	if elapsed := time.Since(start); elapsed > 2*otelExportTimeout {
+		t.Fatalf("exporting took %s", elapsed)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Warning: error exporting trace") {
+		t.Fatalf("expected a warning: %q", ui.ErrorWriter.String())
+	}
+}
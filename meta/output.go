@@ -0,0 +1,567 @@
+package meta
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/helper/flag-slice"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// EnvVaultFormat is the environment variable that sets the output format
+// used when -format is not given.
+const EnvVaultFormat = "VAULT_FORMAT"
+
+// DefaultFormat returns the default of the -format flag of a command whose
+// own preferred format is def. VAULT_FORMAT takes precedence over it, and
+// the flag over both.
+func DefaultFormat(def string) string {
+	if v := os.Getenv(EnvVaultFormat); v != "" {
+		return v
+	}
+	return def
+}
+
+// OutputOptions contains the settings that control how the output of a
+// command is rendered. They are set by the flags added to the FlagSet when
+// FlagSetOutput is given; the zero value gives the default behavior.
+type OutputOptions struct {
+	// NoSanitize disables escaping of the control characters found in
+	// values printed to the terminal.
+	NoSanitize bool
+
+	// ArrayStyle controls how array values are rendered in tables: "indexed"
+	// renders one row per element and "joined" renders a single row with
+	// the elements separated by commas.
+	ArrayStyle string
+
+	// MaxColWidth, if greater than zero, is the maximum width of a value in
+	// table output. Longer values are truncated and end with an ellipsis.
+	MaxColWidth int
+
+	// ShowTruncated prints the full value of every truncated table cell
+	// below the table.
+	ShowTruncated bool
+
+	// Transpose renders each row of a table with several columns as a block
+	// of name/value lines, with the blocks separated by a divider.
+	Transpose bool
+
+	// HideEmptyColumns leaves out of table output the columns of a list that
+	// are empty for every row, and the fields of a secret that are empty.
+	HideEmptyColumns bool
+
+	// WarningsAsFootnotes numbers the warnings printed below a table, and
+	// marks the rows whose key a warning mentions with its number.
+	WarningsAsFootnotes bool
+
+	// HumanizeBytes renders the integer values of table output whose key
+	// marks them as a size in bytes with binary prefixes, such as "1.5 MiB",
+	// or decimal ones, such as "1.5 MB", if BytesBase is 10.
+	HumanizeBytes bool
+	BytesBase     int
+
+	// TTLAsExpiry shows the TTL fields of table output as the time they
+	// expire at, which is the current time plus the TTL. The other formats
+	// get a "<key>_expires_at" field alongside each TTL field instead.
+	TTLAsExpiry bool
+
+	// BinaryPreview is the number of leading bytes of a binary string value
+	// that table output shows in hex, in the placeholder it prints instead
+	// of the value.
+	BinaryPreview int
+
+	// ThousandsSep, if set, is inserted between groups of thousands in the
+	// integer values of table output.
+	ThousandsSep string
+
+	// NoSepKeys lists keys whose integer values are never grouped into
+	// thousands, in addition to the keys that look like identifiers.
+	NoSepKeys []string
+
+	// Syslog sends the outcome of the command, without the values of its
+	// result, to the local syslog, in addition to printing the result.
+	// SyslogOnly only sends it to syslog.
+	Syslog     bool
+	SyslogOnly bool
+
+	// SyslogFacility and SyslogTag are used for the messages sent to syslog.
+	SyslogFacility string
+	SyslogTag      string
+
+	// TypedJSON annotates every value of the JSON output with its type.
+	TypedJSON bool
+
+	// QuoteValues quotes the values of table output that contain tabs,
+	// newlines or runs of spaces, which would otherwise break the layout of
+	// the table.
+	QuoteValues bool
+
+	// BoolStyle is how boolean values are rendered in table output, one of
+	// "true-false", "yes-no", "on-off" and "1-0".
+	BoolStyle string
+
+	// AvroSchema is the path of an Avro schema that the JSON output must
+	// match. The output is then given in the Avro JSON encoding. With
+	// AvroStrict, fields not in the schema are an error rather than dropped.
+	AvroSchema string
+	AvroStrict bool
+
+	// JSONIndent, if set, is the number of spaces the json format is
+	// indented with; zero gives compact output. JSONIndentTab indents it with
+	// tabs. By default it is indented with two spaces on a terminal and
+	// compact otherwise.
+	JSONIndent    *int
+	JSONIndentTab bool
+
+	// Color and NoColor force the json format to be colorized or not. By
+	// default it is colorized when written to a terminal, unless NoColorEnv
+	// disables it. NoColor is set by -no-color, which every command accepts.
+	Color   bool
+	NoColor bool
+
+	// Only restricts the output of a response to one of its sections:
+	// "data", "auth" or "wrap".
+	Only string
+
+	// MergeSections outputs a response as a single object with a key for
+	// each of its sections, the same in every format.
+	MergeSections bool
+
+	// FailIfEmpty makes a response without data, or a list without keys,
+	// result in a non-zero exit code. Quiet suppresses the output, leaving
+	// only the exit code.
+	FailIfEmpty bool
+	Quiet       bool
+
+	// NoFinalNewline leaves out the newline at the end of the output.
+	NoFinalNewline bool
+
+	// ListFormat is how the keys of a list are laid out in table output:
+	// "table", "plain", "tree" or "columns".
+	ListFormat string
+
+	// SortBy is the column the rows of a list are sorted by, in descending
+	// order with SortDesc.
+	SortBy   string
+	SortDesc bool
+
+	// RespectSensitive redacts the fields of a versioned secret that its
+	// custom metadata marks as sensitive, unless Reveal is set.
+	RespectSensitive bool
+	Reveal           bool
+
+	// ShowAge adds the age of a versioned secret, computed from the creation
+	// time in its metadata, to the output.
+	ShowAge bool
+
+	// SignOutput, if set, is the gpg key the output is signed with. The
+	// armored detached signature is written to SignatureFile, or else output
+	// after the result.
+	SignOutput    string
+	SignatureFile string
+
+	// TemplateDir and OutputDir, if set, render each template file in
+	// TemplateDir with the data of a secret into the file with the same
+	// relative path in OutputDir, rather than outputting the secret.
+	TemplateDir string
+	OutputDir   string
+
+	// FileMode, FileOwner and FileGroup, if set, are the permissions, owner
+	// and group of the files written to OutputDir.
+	FileMode  string
+	FileOwner string
+	FileGroup string
+
+	// Template is the Go template the template format renders the response
+	// with, or the path of a file holding it if it starts with "@".
+	// TemplateMissingKey is what happens when the template uses a key the
+	// data does not have: "error", "zero" or "default".
+	Template           string
+	TemplateMissingKey string
+
+	// JSONPath, if set, is a JSONPath expression whose matches in the
+	// response are output rather than the response itself. Matching nothing
+	// is an error unless JSONPathAllowEmpty is set.
+	JSONPath           string
+	JSONPathAllowEmpty bool
+
+	// FieldSingle makes a -field or -jsonpath selector that matches more
+	// than one value an error, rather than outputting one or all of them.
+	FieldSingle bool
+
+	// EnvPrefix is added to the names of the variables output by the env
+	// format.
+	EnvPrefix string
+
+	// KeyOrder, if set, is the order of the keys of the objects in the
+	// response as the server sent them, which the table and json formats
+	// then follow rather than sorting the keys. It is set by the commands
+	// that support -preserve-order rather than by a flag of its own.
+	KeyOrder map[string][]string
+}
+
+// OutputOptions returns the output settings configured by the command line
+// flags for this command.
+func (m *Meta) OutputOptions() *OutputOptions {
+	return &m.flagOutput
+}
+
+// flags adds the flags that configure the output options to the FlagSet.
+func (o *OutputOptions) flags(f *flag.FlagSet) {
+	f.BoolVar(&o.NoSanitize, "no-sanitize", false, "")
+	f.StringVar(&o.ArrayStyle, "array-style", "indexed", "")
+	f.IntVar(&o.MaxColWidth, "max-col-width", 0, "")
+	f.BoolVar(&o.ShowTruncated, "show-truncated", false, "")
+	f.BoolVar(&o.Transpose, "transpose", false, "")
+	f.BoolVar(&o.HideEmptyColumns, "hide-empty-columns", false, "")
+	f.BoolVar(&o.WarningsAsFootnotes, "warnings-as-footnotes", false, "")
+	f.BoolVar(&o.HumanizeBytes, "humanize-bytes", false, "")
+	f.IntVar(&o.BytesBase, "bytes-base", 2, "")
+	f.IntVar(&o.BinaryPreview, "binary-preview", 8, "")
+	f.BoolVar(&o.TTLAsExpiry, "ttl-as-expiry", false, "")
+	f.StringVar(&o.ThousandsSep, "thousands-sep", "", "")
+	f.Var((*sliceflag.StringFlag)(&o.NoSepKeys), "no-sep-keys", "")
+	f.BoolVar(&o.Syslog, "syslog", false, "")
+	f.BoolVar(&o.SyslogOnly, "syslog-only", false, "")
+	f.StringVar(&o.SyslogFacility, "syslog-facility", "user", "")
+	f.StringVar(&o.SyslogTag, "syslog-tag", "vault", "")
+	f.BoolVar(&o.TypedJSON, "typed-json", false, "")
+	f.BoolVar(&o.QuoteValues, "quote-values", false, "")
+	f.StringVar(&o.BoolStyle, "bool-style", "true-false", "")
+	f.StringVar(&o.AvroSchema, "avro-schema", "", "")
+	f.BoolVar(&o.AvroStrict, "avro-strict", false, "")
+	f.Var(intPtrValue{&o.JSONIndent}, "json-indent", "")
+	f.BoolVar(&o.JSONIndentTab, "json-indent-tab", false, "")
+	f.BoolVar(&o.Color, "color", false, "")
+	f.StringVar(&o.Only, "only", "", "")
+	f.BoolVar(&o.MergeSections, "merge-sections", false, "")
+	f.BoolVar(&o.FailIfEmpty, "fail-if-empty", false, "")
+	f.BoolVar(&o.Quiet, "quiet", false, "")
+	f.BoolVar(&o.NoFinalNewline, "no-final-newline", false, "")
+	f.StringVar(&o.ListFormat, "list-format", "table", "")
+	f.StringVar(&o.SortBy, "sort-by", "", "")
+	f.BoolVar(&o.SortDesc, "sort-desc", false, "")
+	f.BoolVar(&o.RespectSensitive, "respect-sensitive-metadata", false, "")
+	f.BoolVar(&o.Reveal, "reveal", false, "")
+	f.BoolVar(&o.ShowAge, "show-age", false, "")
+	f.StringVar(&o.SignOutput, "sign-output", "", "")
+	f.StringVar(&o.SignatureFile, "signature-file", "", "")
+	f.StringVar(&o.TemplateDir, "template-dir", "", "")
+	f.StringVar(&o.OutputDir, "output-dir", "", "")
+	f.StringVar(&o.FileMode, "file-mode", "", "")
+	f.StringVar(&o.FileOwner, "file-owner", "", "")
+	f.StringVar(&o.FileGroup, "file-group", "", "")
+	f.StringVar(&o.Template, "template", "", "")
+	f.StringVar(&o.TemplateMissingKey, "template-missing-key", "error", "")
+	f.StringVar(&o.JSONPath, "jsonpath", "", "")
+	f.BoolVar(&o.JSONPathAllowEmpty, "jsonpath-allow-empty", false, "")
+	f.BoolVar(&o.FieldSingle, "field-single", false, "")
+	f.StringVar(&o.EnvPrefix, "env-prefix", "", "")
+}
+
+// OutputOptionsUsage returns the usage documentation for the options that
+// control how output is rendered
+func OutputOptionsUsage() string {
+	return `
+  The default of -format is taken from the VAULT_FORMAT environment variable
+  if it is set. Besides the formats listed for -format, the flat format
+  outputs a "key=value" line for each value, sorted by key, with nested
+  objects and arrays flattened into keys such as "a.b" and "list.0", and
+  line breaks in values escaped, which makes the output easy to diff. The
+  env format outputs an "export NAME='value'" line for each field, to set
+  environment variables with eval "$(vault read -format=env ...)"; the names
+  are uppercased with anything other than letters, digits, and underscores
+  replaced by an underscore.
+
+  -no-sanitize            Do not escape control characters in the values that
+                          are printed. By default, non-printable characters
+                          other than tabs and newlines are escaped so that
+                          values returned by the server cannot manipulate the
+                          terminal. Only use this with trusted servers.
+
+  -array-style=indexed    How array values are rendered in table output. The
+                          "indexed" style prints one "key[0]", "key[1]", ...
+                          row per element, while "joined" prints the elements
+                          comma-separated in a single row.
+
+  -max-col-width=n        Truncate values in table output to at most n
+                          characters. Truncated values end with "…". By
+                          default values are never truncated.
+
+  -show-truncated         Print the full value of every truncated table cell
+                          below the table. Only meaningful with
+                          -max-col-width.
+
+  -transpose              Flip tables so that each row becomes a block of
+                          "column value" lines, with the blocks separated by
+                          "---". This reads better than a wide table, such as
+                          a list with key metadata, on narrow terminals.
+
+  -hide-empty-columns     Leave out the columns of a list in table output that
+                          are null or empty for every key, and the fields of a
+                          secret that are null or empty. The json and yaml
+                          formats are not affected.
+
+  -warnings-as-footnotes  Number the warnings returned by the server below a
+                          table, and mark the rows whose key a warning
+                          mentions with its number, such as "[1]", so that
+                          the warnings stay attached to the data. The json
+                          and yaml formats are not affected.
+
+  -humanize-bytes         Render the integer values of table output whose key
+                          marks them as a size in bytes, such as
+                          "max_lease_bytes" or "size", like "1.5 MiB". Other
+                          numbers, and the json and yaml formats, are not
+                          affected.
+
+  -bytes-base=2           The prefixes used with -humanize-bytes: 2 for binary
+                          prefixes such as MiB, or 10 for decimal prefixes
+                          such as MB.
+
+  -binary-preview=8       Table output shows string values that hold binary
+                          data, such as ones with NUL bytes or invalid UTF-8,
+                          as their size and the hex of this many of their
+                          first bytes, like "<binary: 256 bytes, 89 50 4e
+                          47 ...>", rather than printing the bytes. Zero
+                          shows only the size. -field and the json and yaml
+                          formats output the value itself.
+
+  -ttl-as-expiry          Show the TTL fields of table output, the fields whose
+                          key contains "ttl" or "duration" such as "max_ttl"
+                          or "lease_duration", as the UTC time they expire
+                          at, which is the current time plus the TTL. The
+                          json, yaml and other formats keep the TTL and add a
+                          "<key>_expires_at" field next to it. TTLs of zero
+                          are left alone.
+
+  -thousands-sep=sep      Group the digits of integer values in table output
+                          into thousands using the given separator, for
+                          example "," renders 1234567 as 1,234,567. Keys that
+                          look like identifiers, such as IDs, versions, and
+                          ports, are left alone.
+
+  -no-sep-keys=key        Key whose integer value is never grouped into
+                          thousands. This can be specified multiple times.
+
+  -syslog                 Also send the outcome of the command to the local
+                          syslog: the path, whether it succeeded, and the keys
+                          of the result, or the error. Values are never sent,
+                          so that secrets do not end up in the system logs.
+
+  -syslog-only            Send the outcome of the command to the local syslog,
+                          as -syslog does, instead of printing the result.
+                          Errors are still printed.
+
+  -syslog-facility=user   The syslog facility to use with -syslog, such as
+                          "user", "auth", or "local0".
+
+  -syslog-tag=vault       The tag to use for messages sent to syslog.
+
+  -typed-json             With the json format, annotate every value with its
+                          type, for example {"type": "string", "value": "x"}.
+                          Nested values are annotated recursively.
+
+  -quote-values           Quote values in table output that contain tabs,
+                          newlines, or runs of spaces, which would otherwise
+                          break the alignment of the table. Quoted values use
+                          Go string escaping, for example "a\tb".
+
+  -bool-style=true-false  How boolean values are rendered in table output, one
+                          of "true-false", "yes-no", "on-off", or "1-0". The
+                          json and yaml formats are not affected.
+
+  -avro-schema=file       With the json format, validate the data against the
+                          Avro record schema in the given file and output it
+                          in the Avro JSON encoding, in which union values are
+                          tagged with their type, such as {"string": "x"}.
+                          Fields not in the schema are dropped.
+
+  -avro-strict            With -avro-schema, fail if the data has fields that
+                          are not in the schema rather than dropping them.
+
+  -json-indent=n          Indent the json format with n spaces. Zero outputs
+                          compact JSON on a single line. By default the json
+                          format is indented with two spaces when the output
+                          is a terminal, and compact otherwise.
+
+  -json-indent-tab        Indent the json format with tabs.
+
+  -color                  Color the keys, strings, numbers, and literals of
+                          the json format with ANSI escape codes, even when
+                          the output is not a terminal or the environment
+                          disables color.
+
+  -only=section           Output only the given section of the response, one
+                          of "data", "auth", or "wrap". By default every
+                          section of the response is output.
+
+  -merge-sections         Output the response as a single object with a key
+                          for each section it has: "lease", "auth",
+                          "wrap_info", "data", and "warnings". The table
+                          format lists the fields of every section as keys
+                          such as "auth.client_token", so that each format
+                          outputs the same fields. With -only, the object
+                          holds just that section.
+
+  -fail-if-empty          Exit with a non-zero code if the response has no
+                          data, or the list has no keys. The empty result is
+                          still output.
+
+  -quiet                  Do not output the result. Combined with
+                          -fail-if-empty, this checks for a result using only
+                          the exit code.
+
+  -no-final-newline       Leave out the newline at the end of the output, in
+                          any format, for consumers that compare the output
+                          byte for byte. -field already outputs no trailing
+                          newline.
+
+  -list-format=table      How the keys of a list are laid out with the table
+                          format: "table" prints them under a header with
+                          any key metadata, "plain" prints one key per line,
+                          "tree" indents the keys by their "/" separated
+                          hierarchy, and "columns" fills the width of the
+                          terminal like ls.
+
+  -sort-by=column         Sort the rows of a list by the given column, such as
+                          "key" or one of the metadata fields the server
+                          returned for the keys. Numbers and RFC 3339
+                          timestamps are compared by value, anything else as
+                          text. This orders the json and yaml output as well.
+
+  -sort-desc              Sort the rows of a list in descending order.
+
+  -respect-sensitive-metadata
+                          Output the fields of a versioned secret that are
+                          listed, comma-separated, in the "sensitive" key of
+                          its custom metadata as REDACTED.
+
+  -reveal                 Output sensitive fields as they are, even with
+                          -respect-sensitive-metadata.
+
+  -show-age               Add the age of a versioned secret, the time since its
+                          version was created, to the output as "age". The
+                          table format renders it like "3d4h", and the json
+                          and yaml formats as a number of seconds. A warning
+                          is printed for secrets that are not versioned.
+
+  -sign-output=key        Sign the output with the given gpg key, using the
+                          local gpg-agent, and output the armored detached
+                          signature after it. If the output cannot be signed,
+                          nothing is output and the command fails.
+
+  -signature-file=path    With -sign-output, write the signature to this file
+                          rather than after the output.
+
+  -template-dir=dir       Rather than outputting the secret, render each file
+                          in this directory as a Go template with the data of
+                          the secret, such as {{.password}}, and write it to
+                          the file with the same relative path in the
+                          -output-dir. Templates that use a key the secret
+                          does not have are reported and not written.
+
+  -output-dir=dir         The directory the templates given by -template-dir
+                          are written to. It is created if needed, and the
+                          files are only readable by the user, unless
+                          -file-mode is given. Each file is replaced
+                          atomically.
+
+  -file-mode=0600         The permissions of the files written to
+                          -output-dir, in octal, such as 0640.
+
+  -file-owner=user        The user, by name or ID, that owns the files written
+                          to -output-dir. Changing the owner usually needs
+                          root.
+
+  -file-group=group       The group, by name or ID, of the files written to
+                          -output-dir.
+
+  -template=tmpl          The Go template the template format renders the
+                          response with, or @file to read it from a file. The
+                          template can use .Data, .Metadata, .LeaseID,
+                          .LeaseDuration, .Renewable, .Warnings, .Auth, and
+                          .WrapInfo; for a versioned secret .Data holds the
+                          secret and .Metadata its metadata. Control
+                          characters in the result are escaped unless
+                          -no-sanitize is given. Only valid with
+                          -format=template.
+
+  -template-missing-key=error
+                          What happens when the template given by -template
+                          uses a key the data does not have: "error" fails,
+                          "zero" renders the zero value, and "default"
+                          renders "<no value>".
+
+  -jsonpath=expr          Output the values the JSONPath expression selects
+                          from the response, one per line, rather than the
+                          response itself, for example
+                          '$.data.users[?(@.active)].name'. Strings are output
+                          as they are and other values as JSON. Selecting
+                          nothing is an error.
+
+  -jsonpath-allow-empty   Exit with a zero code, outputting nothing, when the
+                          expression given by -jsonpath selects nothing.
+
+  -field-single           Fail if the field given by -field, or the expression
+                          given by -jsonpath, matches more than one value,
+                          rather than outputting all of the matches for
+                          -jsonpath or the first one for -field. A dotted
+                          -field such as "a.b" matches more than one value
+                          when the data has both an "a.b" key and an "a"
+                          object with a "b" key. Matching nothing is still
+                          an error unless -field-default or
+                          -jsonpath-allow-empty is given.
+
+  -env-prefix=prefix      With the env format, add this prefix to the name of
+                          every variable, such as "APP_" to output APP_USER
+                          for the "user" field.
+`
+}
+
+// intPtrValue is a flag.Value for an int that is only set, to a non-nil
+// value, when the flag is given.
+type intPtrValue struct {
+	p **int
+}
+
+func (v intPtrValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.Itoa(**v.p)
+}
+
+func (v intPtrValue) Set(s string) error {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v.p = &i
+	return nil
+}
+
+// durationPtrValue is a flag.Value for a duration that is only set, to a
+// non-nil value, when the flag is given. A number without a unit is taken
+// as seconds.
+type durationPtrValue struct {
+	p **time.Duration
+}
+
+func (v durationPtrValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return (**v.p).String()
+}
+
+func (v durationPtrValue) Set(s string) error {
+	d, err := parseutil.ParseDurationSecond(s)
+	if err != nil {
+		return err
+	}
+	*v.p = &d
+	return nil
+}
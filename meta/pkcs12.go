@@ -0,0 +1,57 @@
+package meta
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// loadPKCS12 loads the client certificate and its private key from the
+// PKCS#12 bundle, such as a .p12 or .pfx file, given by -client-pkcs12.
+func loadPKCS12(path, password string) (tls.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error reading the PKCS#12 bundle: %s", err)
+	}
+
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err == pkcs12.ErrIncorrectPassword {
+		return tls.Certificate{}, fmt.Errorf(
+			"error decoding the PKCS#12 bundle %s: the password is wrong", path)
+	}
+	if err != nil && strings.Contains(err.Error(), "expected exactly two") {
+		// The decoder only supports a certificate and its key, and reports
+		// anything else, such as a bundle without a key, in these terms
+		err = errors.New("it must hold exactly one certificate and its private key")
+	}
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error decoding the PKCS#12 bundle %s: %s", path, err)
+	}
+
+	var certPEM, keyPEM []byte
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+		case "PRIVATE KEY":
+			keyPEM = append(keyPEM, pem.EncodeToMemory(block)...)
+		}
+	}
+	if len(keyPEM) == 0 {
+		return tls.Certificate{}, fmt.Errorf("the PKCS#12 bundle %s has no private key", path)
+	}
+	if len(certPEM) == 0 {
+		return tls.Certificate{}, fmt.Errorf("the PKCS#12 bundle %s has no certificate", path)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error loading the PKCS#12 bundle %s: %s", path, err)
+	}
+	return cert, nil
+}
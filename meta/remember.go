@@ -0,0 +1,96 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
+)
+
+// rememberedState is the contents of the state file in which -remember
+// keeps the settings of the last command that reached the server.
+type rememberedState struct {
+	Address   string `json:"address,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// rememberPath returns the path of the -remember state file.
+func (m *Meta) rememberPath() (string, error) {
+	if m.statePath != "" {
+		return m.statePath, nil
+	}
+
+	// NOTE: requires HOME env var to be set
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", errwrap.Wrapf("error getting user's home directory: {{err}}", err)
+	}
+	return filepath.Join(home, ".vault-state"), nil
+}
+
+// readRemembered returns the remembered state, which is empty if nothing
+// was remembered yet.
+func (m *Meta) readRemembered() (*rememberedState, error) {
+	path, err := m.rememberPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var state rememberedState
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return nil, fmt.Errorf(
+			"error parsing remembered settings in %s: %s; use -no-remember to ignore them", path, err)
+	}
+	return &state, nil
+}
+
+// writeRemembered replaces the remembered state.
+func (m *Meta) writeRemembered(state *rememberedState) error {
+	path, err := m.rememberPath()
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, contents, 0600)
+}
+
+// rememberTransport is an http.RoundTripper that remembers the state once a
+// request gets a response from the server, so that only settings that work
+// are remembered. A failure to remember them is reported as a warning.
+type rememberTransport struct {
+	state *rememberedState
+	write func(*rememberedState) error
+	ui    cli.Ui
+	once  sync.Once
+	base  http.RoundTripper
+}
+
+func (t *rememberTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.once.Do(func() {
+			if err := t.write(t.state); err != nil && t.ui != nil {
+				t.ui.Error(fmt.Sprintf("Warning: error remembering settings: %s", err))
+			}
+		})
+	}
+	return resp, err
+}
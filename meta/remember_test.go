@@ -0,0 +1,224 @@
+package meta
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestClient_remember(t *testing.T) {
+	var l sync.Mutex
+	var namespace string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		namespace = r.Header.Get("X-Vault-Namespace")
+		l.Unlock()
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state")
+
+	oldAddr := os.Getenv(api.EnvVaultAddress)
+	os.Unsetenv(api.EnvVaultAddress)
+	defer os.Setenv(api.EnvVaultAddress, oldAddr)
+
+	client := func(args ...string) *api.Client {
+		m := Meta{ClientToken: "foo", Ui: cli.NewMockUi(), statePath: statePath}
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		c, err := m.Client()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return c
+	}
+	defaultAddr := client().Address()
+
+	// requestNamespace returns the namespace a request to the server is
+	// made in
+	requestNamespace := func(c *api.Client) string {
+		c.SetAddress(server.URL)
+		if _, err := c.Logical().Delete("secret/foo"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		l.Lock()
+		defer l.Unlock()
+		return namespace
+	}
+
+	// Nothing is remembered until the server is reached
+	unreachable := "http://127.0.0.1:1"
+	c := client("-address", unreachable, "-remember")
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("expected no state file: %v", err)
+	}
+	c.Logical().Delete("secret/foo")
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("expected no state file: %v", err)
+	}
+
+	c = client("-address", server.URL, "-remember")
+	if _, err := c.Logical().Delete("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	info, err := os.Stat(statePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("bad mode: %s", info.Mode())
+	}
+
+	// The remembered address is used when none is given
+	if addr := client().Address(); addr != server.URL {
+		t.Fatalf("expected the remembered address %q, got %q", server.URL, addr)
+	}
+	if addr := client("-no-remember").Address(); addr != defaultAddr {
+		t.Fatalf("expected the default address %q, got %q", defaultAddr, addr)
+	}
+
+	// The namespace is remembered with the address
+	c = client("-address", server.URL, "-namespace", "team-a", "-remember")
+	if _, err := c.Logical().Delete("secret/foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err := (&Meta{statePath: statePath}).readRemembered()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.Address != server.URL || state.Namespace != "team-a" {
+		t.Fatalf("bad state: %#v", state)
+	}
+	c = client()
+	if addr := c.Address(); addr != server.URL {
+		t.Fatalf("expected the remembered address %q, got %q", server.URL, addr)
+	}
+	if ns := requestNamespace(c); ns != "team-a" {
+		t.Fatalf("expected the remembered namespace, got %q", ns)
+	}
+	if ns := requestNamespace(client("-namespace", "team-b")); ns != "team-b" {
+		t.Fatalf("expected the flag namespace, got %q", ns)
+	}
+	oldNamespace := os.Getenv(api.EnvVaultNamespace)
+	os.Setenv(api.EnvVaultNamespace, "team-c")
+	if ns := requestNamespace(client()); ns != "team-c" {
+		t.Fatalf("expected the environment namespace, got %q", ns)
+	}
+	os.Setenv(api.EnvVaultNamespace, oldNamespace)
+	for _, args := range [][]string{{"-no-remember"}, {"-address", unreachable}} {
+		if ns := requestNamespace(client(args...)); ns != "" {
+			t.Fatalf("%v: expected no namespace, got %q", args, ns)
+		}
+	}
+
+	// Explicit flags and the environment win
+	if addr := client("-address", unreachable).Address(); addr != unreachable {
+		t.Fatalf("expected the flag address, got %q", addr)
+	}
+	os.Setenv(api.EnvVaultAddress, unreachable)
+	if addr := client().Address(); addr != unreachable {
+		t.Fatalf("expected the environment address, got %q", addr)
+	}
+	os.Unsetenv(api.EnvVaultAddress)
+
+	// A corrupt state file is an error, unless it is ignored
+	if err := ioutil.WriteFile(statePath, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := Meta{ClientToken: "foo", Ui: cli.NewMockUi(), statePath: statePath}
+	if err := m.FlagSet("foo", FlagSetServer).Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Client(); err == nil {
+		t.Fatal("expected error")
+	}
+	client("-no-remember")
+}
+
+func TestClient_rememberUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	statePath := filepath.Join(dir, "state")
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"via":"socket"}}`))
+	})}
+	go server.Serve(ln)
+	defer server.Close()
+
+	oldAddr := os.Getenv(api.EnvVaultAddress)
+	os.Unsetenv(api.EnvVaultAddress)
+	defer os.Setenv(api.EnvVaultAddress, oldAddr)
+
+	read := func(m *Meta, args ...string) {
+		fs := m.FlagSet("foo", FlagSetServer)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		c, err := m.Client()
+		if err != nil {
+			t.Fatalf("%v: err: %s", args, err)
+		}
+		secret, err := c.Logical().Read("secret/foo")
+		if err != nil {
+			t.Fatalf("%v: err: %s", args, err)
+		}
+		if secret.Data["via"] != "socket" {
+			t.Fatalf("%v: bad: %#v", args, secret.Data)
+		}
+	}
+
+	// The socket is remembered, rather than the address of the requests
+	read(&Meta{ClientToken: "foo", statePath: statePath},
+		"-unix-socket", socket, "-namespace", "team-a", "-remember")
+	state, err := (&Meta{statePath: statePath}).readRemembered()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.Address != "unix://"+socket || state.Namespace != "team-a" {
+		t.Fatalf("bad state: %#v", state)
+	}
+	read(&Meta{ClientToken: "foo", statePath: statePath})
+
+	// The remembered namespace is only used with the remembered address
+	m := &Meta{ClientToken: "foo", statePath: statePath}
+	_, settings, err := m.clientConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if settings.Namespace != "team-a" {
+		t.Fatalf("expected the remembered namespace, got %q", settings.Namespace)
+	}
+	m = &Meta{ClientToken: "foo", statePath: statePath, ForceAddress: "http://127.0.0.1:1"}
+	config, settings, err := m.clientConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.Address != "http://127.0.0.1:1" || settings.Namespace != "" {
+		t.Fatalf("expected the forced address without a namespace: %q %q", config.Address, settings.Namespace)
+	}
+}
@@ -0,0 +1,108 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/command/token"
+)
+
+// hookRequest is the serialized request passed to the -request-hook program
+// on stdin.
+type hookRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// requestHookTransport is an http.RoundTripper that runs the -request-hook
+// program before each request is sent. The program is given the request on
+// stdin and must print a JSON object of headers to set on the request to
+// stdout. If the program fails, the request is not sent.
+type requestHookTransport struct {
+	program string
+	timeout time.Duration
+	base    http.RoundTripper
+}
+
+func (t *requestHookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Copy the request so that the original is not modified
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	headers, err := t.run(&hookRequest{
+		Method:  r.Method,
+		URL:     r.URL.String(),
+		Headers: r.Header,
+		Body:    string(body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	return t.base.RoundTrip(r)
+}
+
+// run executes the hook program for the request and returns the headers it
+// printed.
+func (t *requestHookTransport) run(req *hookRequest) (map[string]string, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := token.ExecScript(t.program)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error running request hook: %s", err)
+	}
+
+	doneCh := make(chan error, 1)
+	go func() { doneCh <- cmd.Wait() }()
+	select {
+	case err := <-doneCh:
+		if err != nil {
+			return nil, fmt.Errorf(
+				"request hook failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	case <-time.After(t.timeout):
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("request hook did not finish within %s", t.timeout)
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal(stdout.Bytes(), &headers); err != nil {
+		return nil, fmt.Errorf("error parsing request hook output: %s", err)
+	}
+	return headers, nil
+}
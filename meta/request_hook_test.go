@@ -0,0 +1,115 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestClient_requestHook(t *testing.T) {
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	defer os.Unsetenv("GO_WANT_HELPER_PROCESS")
+
+	var header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Hook")
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	// The hook is this test binary, which can be slow to start, so only the
+	// hook that is meant to time out gets a short timeout
+	cases := []struct {
+		Mode    string
+		Timeout string
+		Header  string
+		Err     bool
+	}{
+		{"headers", "30s", "PUT /v1/secret/foo", false},
+		{"fail", "30s", "", true},
+		{"sleep", "1s", "", true},
+	}
+
+	for _, tc := range cases {
+		header = ""
+		m := Meta{ClientToken: "foo"}
+		fs := m.FlagSet("foo", FlagSetServer)
+		args := []string{
+			"-address", ts.URL,
+			"-request-hook", requestHookPath(tc.Mode),
+			"-request-hook-timeout", tc.Timeout,
+		}
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		client, err := m.Client()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err = client.Logical().Write("secret/foo", map[string]interface{}{"value": "bar"})
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: expected error %t, got %v", tc.Mode, tc.Err, err)
+		}
+		if header != tc.Header {
+			t.Fatalf("%s: bad header: %q", tc.Mode, header)
+		}
+	}
+}
+
+func requestHookPath(mode string) string {
+	return fmt.Sprintf("%s -test.run=TestRequestHookProcess -- %s", os.Args[0], mode)
+}
+
+// This is not a real test. This is just a helper process kicked off by tests.
+func TestRequestHookProcess(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "No command\n")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "headers":
+		var req hookRequest
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+			fmt.Fprintf(os.Stderr, "Err: %s\n", err)
+			os.Exit(1)
+		}
+		if req.Body == "" {
+			fmt.Fprintf(os.Stderr, "Missing body\n")
+			os.Exit(1)
+		}
+		json.NewEncoder(os.Stdout).Encode(map[string]string{
+			"X-Hook": req.Method + " /v1/secret/foo",
+		})
+	case "fail":
+		fmt.Fprintf(os.Stderr, "denied\n")
+		os.Exit(1)
+	case "sleep":
+		time.Sleep(5 * time.Second)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %q\n", args[0])
+		os.Exit(2)
+	}
+}